@@ -0,0 +1,336 @@
+package kvstore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//KVStore is an embedded key/value store in a single file. The file is a log of puts and deletes, only the
+//keys and where their values are in the file are held in memory. It is compacted as it fills with stale records
+type KVStore interface {
+	Get(key string) ([]byte, bool, error)
+	Put(key string, value []byte) error
+	Delete(key string) error
+	//DeletePrefix deletes the keys starting with the prefix
+	DeletePrefix(prefix string) error
+	//Keys returns the keys starting with the prefix, in order
+	Keys(prefix string) []string
+	Len() int
+	//Sync writes the buffered records to the file and flushes it to disk
+	Sync() error
+	Close() error
+}
+
+var ErrClosed = errors.New("Store closed")
+
+const (
+	opPut    = byte(1)
+	opDelete = byte(2)
+	//compact once the file holds more stale records than this, and than live ones
+	compactThreshold = 1024
+)
+
+//record header: op, key length, value length, crc of the key and value
+const headerLen = 1 + 4 + 4 + 4
+
+type location struct {
+	offset int64
+	length int
+}
+
+type kvStoreImpl struct {
+	lock   sync.Mutex
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+	//where the next record goes
+	size  int64
+	index map[string]location
+	stale int
+	//written records not flushed yet, reads flush them first
+	dirty  bool
+	closed bool
+}
+
+//Open opens the store in the file, created as needed. A record partly written by a crash is dropped
+func Open(path string) (KVStore, error) {
+	s := kvStoreImpl{}
+	err := s.initKVStoreImpl(path)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *kvStoreImpl) initKVStoreImpl(path string) error {
+	s.path = path
+	s.index = make(map[string]location)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	s.file = file
+	err = s.load()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.writer = bufio.NewWriter(file)
+	return nil
+}
+
+//load rebuilds the index from the log, and truncates it after the last complete record
+func (s *kvStoreImpl) load() error {
+	reader := bufio.NewReader(s.file)
+	offset := int64(0)
+	header := make([]byte, headerLen)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			break
+		}
+		op := header[0]
+		keyLen := int(binary.BigEndian.Uint32(header[1:5]))
+		valueLen := int(binary.BigEndian.Uint32(header[5:9]))
+		body := make([]byte, keyLen+valueLen)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(header[9:13]) || (op != opPut && op != opDelete) {
+			break
+		}
+		key := string(body[:keyLen])
+		if _, found := s.index[key]; found {
+			s.stale++
+		}
+		if op == opPut {
+			s.index[key] = location{offset + int64(headerLen+keyLen), valueLen}
+		} else {
+			delete(s.index, key)
+			s.stale++
+		}
+		offset += int64(headerLen + keyLen + valueLen)
+	}
+	s.size = offset
+	if err := s.file.Truncate(offset); err != nil {
+		return err
+	}
+	_, err := s.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+func (s *kvStoreImpl) Get(key string) ([]byte, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return nil, false, ErrClosed
+	}
+	loc, found := s.index[key]
+	if !found {
+		return nil, false, nil
+	}
+	if s.dirty {
+		if err := s.writer.Flush(); err != nil {
+			return nil, false, err
+		}
+		s.dirty = false
+	}
+	value := make([]byte, loc.length)
+	_, err := s.file.ReadAt(value, loc.offset)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (s *kvStoreImpl) Put(key string, value []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if _, found := s.index[key]; found {
+		s.stale++
+	}
+	offset, err := s.append(opPut, key, value)
+	if err != nil {
+		return err
+	}
+	s.index[key] = location{offset, len(value)}
+	return s.compactIfStale()
+}
+
+func (s *kvStoreImpl) Delete(key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	return s.delete(key)
+}
+
+func (s *kvStoreImpl) delete(key string) error {
+	if _, found := s.index[key]; !found {
+		return nil
+	}
+	if _, err := s.append(opDelete, key, nil); err != nil {
+		return err
+	}
+	delete(s.index, key)
+	//the put and the delete
+	s.stale += 2
+	return s.compactIfStale()
+}
+
+func (s *kvStoreImpl) DeletePrefix(prefix string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	for _, key := range s.keys(prefix) {
+		if err := s.delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *kvStoreImpl) Keys(prefix string) []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.keys(prefix)
+}
+
+func (s *kvStoreImpl) keys(prefix string) []string {
+	keys := []string{}
+	for key := range s.index {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *kvStoreImpl) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.index)
+}
+
+func (s *kvStoreImpl) Sync() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	s.dirty = false
+	return s.file.Sync()
+}
+
+func (s *kvStoreImpl) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	err := s.writer.Flush()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+//append writes a record, and returns where its value is
+func (s *kvStoreImpl) append(op byte, key string, value []byte) (int64, error) {
+	return appendRecord(s.writer, &s.size, &s.dirty, op, key, value)
+}
+
+func appendRecord(w *bufio.Writer, size *int64, dirty *bool, op byte, key string, value []byte) (int64, error) {
+	body := make([]byte, 0, len(key)+len(value))
+	body = append(body, key...)
+	body = append(body, value...)
+	header := make([]byte, headerLen)
+	header[0] = op
+	binary.BigEndian.PutUint32(header[1:5], uint32(len(key)))
+	binary.BigEndian.PutUint32(header[5:9], uint32(len(value)))
+	binary.BigEndian.PutUint32(header[9:13], crc32.ChecksumIEEE(body))
+	if _, err := w.Write(header); err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(body); err != nil {
+		return 0, err
+	}
+	*dirty = true
+	offset := *size + int64(headerLen+len(key))
+	*size += int64(headerLen + len(body))
+	return offset, nil
+}
+
+func (s *kvStoreImpl) compactIfStale() error {
+	if s.stale < compactThreshold || s.stale < len(s.index) {
+		return nil
+	}
+	return s.compact()
+}
+
+//compact rewrites the live records to a new file, which then replaces the log
+func (s *kvStoreImpl) compact() error {
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	s.dirty = false
+	tmpPath := s.path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	index := make(map[string]location, len(s.index))
+	size := int64(0)
+	dirty := false
+	for _, key := range s.keys("") {
+		loc := s.index[key]
+		value := make([]byte, loc.length)
+		if _, err = s.file.ReadAt(value, loc.offset); err != nil {
+			break
+		}
+		offset, err2 := appendRecord(writer, &size, &dirty, opPut, key, value)
+		if err = err2; err != nil {
+			break
+		}
+		index[key] = location{offset, len(value)}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, s.path)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	s.file.Close()
+	s.file = tmp
+	s.writer = bufio.NewWriter(tmp)
+	s.index = index
+	s.size = size
+	s.stale = 0
+	return nil
+}
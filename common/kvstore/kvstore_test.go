@@ -0,0 +1,72 @@
+package kvstore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKVStore(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "kvstore")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kv")
+
+	kv, err := Open(path)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	kv.Put("a/1", []byte("one"))
+	kv.Put("a/2", []byte("two"))
+	kv.Put("b/1", []byte("three"))
+	kv.Put("a/1", []byte("uno"))
+	kv.Delete("a/2")
+	expectValue(t, kv, "a/1", "uno")
+	if _, found, _ := kv.Get("a/2"); found {
+		t.Errorf("Expected a/2 deleted")
+	}
+	kv.Close()
+
+	//a record cut short by a crash is dropped
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	f.Write([]byte{opPut, 0, 0})
+	f.Close()
+	kv, err = Open(path)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expectValue(t, kv, "a/1", "uno")
+	expectValue(t, kv, "b/1", "three")
+	kv.DeletePrefix("b/")
+	if kv.Len() != 1 {
+		t.Errorf("Expected [%d], got [%d]\n", 1, kv.Len())
+	}
+
+	//rewriting keys compacts the file
+	for i := 0; i < 10*compactThreshold; i++ {
+		kv.Put(fmt.Sprintf("c/%d", i%10), []byte(fmt.Sprint(i)))
+	}
+	expectValue(t, kv, "c/9", fmt.Sprint(10*compactThreshold-1))
+	kv.Sync()
+	info, _ := os.Stat(path)
+	if info.Size() > 100*compactThreshold {
+		t.Errorf("Expected the file compacted, got [%d] bytes\n", info.Size())
+	}
+	kv.Close()
+
+	kv, _ = Open(path)
+	defer kv.Close()
+	if keys := kv.Keys("c/"); len(keys) != 10 {
+		t.Errorf("Expected [%d], got %v\n", 10, keys)
+	}
+	expectValue(t, kv, "a/1", "uno")
+}
+
+func expectValue(t *testing.T, kv KVStore, key string, expected string) {
+	t.Helper()
+	value, found, err := kv.Get(key)
+	if err != nil || !found || string(value) != expected {
+		t.Errorf("%s: expected [%s], got [%s] %v\n", key, expected, value, err)
+	}
+}
@@ -0,0 +1,390 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//Registry holds counters and histograms by name, and collectors computing gauges when gathered. The
+//metrics of several sessions can share a registry, they tell apart by their labels
+type Registry interface {
+	//Counter returns the counters of the name, created on first use
+	Counter(name string, help string, labelNames ...string) CounterVec
+	//Histogram returns the histograms of the name, created on first use with the upper bounds of its buckets
+	Histogram(name string, help string, buckets []float64, labelNames ...string) HistogramVec
+	Register(collector Collector)
+	Unregister(collector Collector)
+	//Gather returns the current values of the metrics, by name
+	Gather() []Family
+	//WritePrometheus writes the metrics in the Prometheus text format
+	WritePrometheus(w io.Writer) error
+}
+
+//CounterVec is the counters of a name, one per set of label values
+type CounterVec interface {
+	With(labelValues ...string) Counter
+}
+
+//Counter only goes up
+type Counter interface {
+	Inc()
+	Add(v float64)
+	Value() float64
+}
+
+//HistogramVec is the histograms of a name, one per set of label values
+type HistogramVec interface {
+	With(labelValues ...string) Histogram
+}
+
+//Histogram counts the values observed per bucket
+type Histogram interface {
+	Observe(v float64)
+}
+
+//Collector computes metrics when gathered, such as gauges
+type Collector interface {
+	Collect() []Family
+}
+
+//Metric types
+const (
+	CounterType   = "counter"
+	GaugeType     = "gauge"
+	HistogramType = "histogram"
+)
+
+//Family is the samples of a metric
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+//Sample is a value of a metric, histograms have _bucket, _sum and _count samples
+type Sample struct {
+	Name   string
+	Labels []Label
+	Value  float64
+}
+
+//Label is a label of a sample
+type Label struct {
+	Name  string
+	Value string
+}
+
+//DefaultBuckets are upper bounds in seconds, for durations
+var DefaultBuckets = []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+//NewRegistry returns an empty registry
+func NewRegistry() Registry {
+	r := registryImpl{}
+	r.initRegistryImpl()
+	return &r
+}
+
+type registryImpl struct {
+	lock       sync.Mutex
+	counters   map[string]*counterVecImpl
+	histograms map[string]*histogramVecImpl
+	collectors []Collector
+}
+
+func (r *registryImpl) initRegistryImpl() {
+	r.counters = make(map[string]*counterVecImpl)
+	r.histograms = make(map[string]*histogramVecImpl)
+}
+
+func (r *registryImpl) Counter(name string, help string, labelNames ...string) CounterVec {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	cv := r.counters[name]
+	if cv == nil {
+		cv = &counterVecImpl{vec: newVec(name, help, labelNames)}
+		r.counters[name] = cv
+	}
+	return cv
+}
+
+func (r *registryImpl) Histogram(name string, help string, buckets []float64, labelNames ...string) HistogramVec {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	hv := r.histograms[name]
+	if hv == nil {
+		if len(buckets) == 0 {
+			buckets = DefaultBuckets
+		}
+		hv = &histogramVecImpl{vec: newVec(name, help, labelNames), buckets: append([]float64{}, buckets...)}
+		sort.Float64s(hv.buckets)
+		r.histograms[name] = hv
+	}
+	return hv
+}
+
+func (r *registryImpl) Register(collector Collector) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.collectors = append(r.collectors, collector)
+}
+
+func (r *registryImpl) Unregister(collector Collector) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i, c := range r.collectors {
+		if c == collector {
+			r.collectors = append(r.collectors[:i], r.collectors[i+1:]...)
+			return
+		}
+	}
+}
+
+func (r *registryImpl) Gather() []Family {
+	r.lock.Lock()
+	families := map[string]*Family{}
+	for _, cv := range r.counters {
+		families[cv.name] = cv.collect()
+	}
+	for _, hv := range r.histograms {
+		families[hv.name] = hv.collect()
+	}
+	collectors := append([]Collector{}, r.collectors...)
+	r.lock.Unlock()
+
+	//the collectors of several sessions add to the same families
+	for _, collector := range collectors {
+		for _, f := range collector.Collect() {
+			if existing := families[f.Name]; existing != nil {
+				existing.Samples = append(existing.Samples, f.Samples...)
+			} else {
+				family := f
+				families[f.Name] = &family
+			}
+		}
+	}
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	gathered := make([]Family, len(names))
+	for i, name := range names {
+		gathered[i] = *families[name]
+	}
+	return gathered
+}
+
+func (r *registryImpl) WritePrometheus(w io.Writer) error {
+	return WritePrometheus(w, r.Gather())
+}
+
+//WritePrometheus writes the families in the Prometheus text format
+func WritePrometheus(w io.Writer, families []Family) error {
+	var b strings.Builder
+	for _, f := range families {
+		if len(f.Samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.Name, escapeHelp(f.Help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.Name, f.Type)
+		for _, s := range f.Samples {
+			b.WriteString(s.Name)
+			if len(s.Labels) > 0 {
+				b.WriteString("{")
+				for i, l := range s.Labels {
+					if i > 0 {
+						b.WriteString(",")
+					}
+					fmt.Fprintf(&b, "%s=\"%s\"", l.Name, escapeLabel(l.Value))
+				}
+				b.WriteString("}")
+			}
+			b.WriteString(" " + formatValue(s.Value) + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+//Handler serves the metrics of the registry in the Prometheus text format
+func Handler(r Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WritePrometheus(w)
+	})
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+//vec keeps a metric per set of label values, in the order they were first used
+type vec struct {
+	lock       sync.Mutex
+	name       string
+	help       string
+	labelNames []string
+	keys       []string
+	values     map[string][]string
+}
+
+func newVec(name string, help string, labelNames []string) vec {
+	return vec{name: name, help: help, labelNames: labelNames, values: make(map[string][]string)}
+}
+
+//key returns the key of the label values, and tells if they are new
+func (v *vec) key(labelValues []string) (string, bool) {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("Metric [%s] has labels %v, got values %v", v.name, v.labelNames, labelValues))
+	}
+	key := strings.Join(labelValues, "\x00")
+	if _, found := v.values[key]; found {
+		return key, false
+	}
+	v.keys = append(v.keys, key)
+	v.values[key] = append([]string{}, labelValues...)
+	return key, true
+}
+
+func (v *vec) labels(key string, extra ...Label) []Label {
+	labels := make([]Label, 0, len(v.labelNames)+len(extra))
+	for i, name := range v.labelNames {
+		labels = append(labels, Label{name, v.values[key][i]})
+	}
+	return append(labels, extra...)
+}
+
+type counterVecImpl struct {
+	vec
+	counters map[string]*counterImpl
+}
+
+func (cv *counterVecImpl) With(labelValues ...string) Counter {
+	cv.lock.Lock()
+	defer cv.lock.Unlock()
+	key, isNew := cv.key(labelValues)
+	if isNew {
+		if cv.counters == nil {
+			cv.counters = make(map[string]*counterImpl)
+		}
+		cv.counters[key] = &counterImpl{}
+	}
+	return cv.counters[key]
+}
+
+func (cv *counterVecImpl) collect() *Family {
+	cv.lock.Lock()
+	defer cv.lock.Unlock()
+	f := &Family{Name: cv.name, Help: cv.help, Type: CounterType}
+	for _, key := range cv.keys {
+		f.Samples = append(f.Samples, Sample{cv.name, cv.labels(key), cv.counters[key].Value()})
+	}
+	return f
+}
+
+type counterImpl struct {
+	lock  sync.Mutex
+	value float64
+}
+
+func (c *counterImpl) Inc() {
+	c.Add(1)
+}
+
+func (c *counterImpl) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	c.lock.Lock()
+	c.value += v
+	c.lock.Unlock()
+}
+
+func (c *counterImpl) Value() float64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.value
+}
+
+type histogramVecImpl struct {
+	vec
+	buckets    []float64
+	histograms map[string]*histogramImpl
+}
+
+func (hv *histogramVecImpl) With(labelValues ...string) Histogram {
+	hv.lock.Lock()
+	defer hv.lock.Unlock()
+	key, isNew := hv.key(labelValues)
+	if isNew {
+		if hv.histograms == nil {
+			hv.histograms = make(map[string]*histogramImpl)
+		}
+		hv.histograms[key] = &histogramImpl{buckets: hv.buckets, counts: make([]uint64, len(hv.buckets))}
+	}
+	return hv.histograms[key]
+}
+
+func (hv *histogramVecImpl) collect() *Family {
+	hv.lock.Lock()
+	defer hv.lock.Unlock()
+	f := &Family{Name: hv.name, Help: hv.help, Type: HistogramType}
+	for _, key := range hv.keys {
+		h := hv.histograms[key]
+		h.lock.Lock()
+		//the buckets are cumulative
+		cumulative := uint64(0)
+		for i, bound := range h.buckets {
+			cumulative += h.counts[i]
+			f.Samples = append(f.Samples, Sample{hv.name + "_bucket", hv.labels(key, Label{"le", formatValue(bound)}), float64(cumulative)})
+		}
+		f.Samples = append(f.Samples, Sample{hv.name + "_bucket", hv.labels(key, Label{"le", "+Inf"}), float64(h.count)})
+		f.Samples = append(f.Samples, Sample{hv.name + "_sum", hv.labels(key), h.sum})
+		f.Samples = append(f.Samples, Sample{hv.name + "_count", hv.labels(key), float64(h.count)})
+		h.lock.Unlock()
+	}
+	return f
+}
+
+type histogramImpl struct {
+	lock    sync.Mutex
+	buckets []float64
+	//the values per bucket, not cumulative
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogramImpl) Observe(v float64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	i := sort.SearchFloat64s(h.buckets, v)
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type gauge struct {
+	value float64
+}
+
+func (g *gauge) Collect() []Family {
+	return []Family{{Name: "g", Help: "A gauge", Type: GaugeType,
+		Samples: []Sample{{Name: "g", Labels: []Label{{"l", `a"b`}}, Value: g.value}}}}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("c_total", "A counter", "l")
+	c.With("x").Inc()
+	c.With("x").Add(2)
+	c.With("x").Add(-1)
+	if r.Counter("c_total", "A counter", "l").With("x").Value() != 3 {
+		t.Errorf("Expected the counter at 3")
+	}
+	h := r.Histogram("h_seconds", "A histogram", []float64{1, 0.5})
+	h.With().Observe(0.5)
+	h.With().Observe(0.7)
+	h.With().Observe(2)
+	g := &gauge{value: 1.5}
+	r.Register(g)
+
+	buf := bytes.Buffer{}
+	r.WritePrometheus(&buf)
+	expected := `# HELP c_total A counter
+# TYPE c_total counter
+c_total{l="x"} 3
+# HELP g A gauge
+# TYPE g gauge
+g{l="a\"b"} 1.5
+# HELP h_seconds A histogram
+# TYPE h_seconds histogram
+h_seconds_bucket{le="0.5"} 1
+h_seconds_bucket{le="1"} 2
+h_seconds_bucket{le="+Inf"} 3
+h_seconds_sum 3.2
+h_seconds_count 3
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected exposition\n%s", buf.String())
+	}
+
+	r.Unregister(g)
+	w := httptest.NewRecorder()
+	Handler(r).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") || strings.Contains(w.Body.String(), "# TYPE g") {
+		t.Errorf("Unexpected response %v\n%s", w.Header(), w.Body.String())
+	}
+}
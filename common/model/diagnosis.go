@@ -0,0 +1,57 @@
+package model
+
+//Diagnosis tells why a rule did or did not fire for some tuples, see RuleSession.Diagnose
+type Diagnosis struct {
+	Rule string `json:"rule"`
+	//Tuples are the keys of the asserted tuples bound to the rule's identifiers
+	Tuples map[TupleType]string `json:"tuples"`
+	//NotAsserted are the keys given of tuples not asserted
+	NotAsserted []string `json:"notAsserted,omitempty"`
+	//Unmatched are the identifiers of the rule no asserted tuple given binds
+	Unmatched []TupleType `json:"unmatched,omitempty"`
+	//Conditions are the conditions of the rule's nodes, in the order of the join path
+	Conditions []ConditionDiagnosis `json:"conditions"`
+	//Activation tells if the tuples activated the rule, and how the activation ended
+	Activation ActivationStatus `json:"activation"`
+	//CancelReason tells why a cancelled activation did not fire
+	CancelReason string `json:"cancelReason,omitempty"`
+}
+
+//ConditionDiagnosis is a condition evaluated on the tuples of a diagnosis
+type ConditionDiagnosis struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	//NodeID is the id of the node evaluating the condition in the network's graph
+	NodeID string `json:"nodeId"`
+	//Evaluated is false if an identifier of the condition is unmatched, the condition is then not
+	//evaluated, as for the negated identifiers of a condition group
+	Evaluated bool   `json:"evaluated"`
+	Passed    bool   `json:"passed"`
+	Error     string `json:"error,omitempty"`
+	//Values are the values of the properties the condition reads, see ConditionExplanation
+	Values map[TupleType]map[string]interface{} `json:"values,omitempty"`
+}
+
+//ActivationStatus is what became of the activation of a rule for some tuples
+type ActivationStatus string
+
+const (
+	//ActivationNone is for tuples that did not activate the rule, or whose activation is forgotten
+	ActivationNone ActivationStatus = "none"
+	//ActivationPending is for an activation on the agenda, such as one of an agenda group without the focus
+	ActivationPending ActivationStatus = "pending"
+	ActivationFired   ActivationStatus = "fired"
+	//ActivationCancelled is for an activation removed from the agenda without firing, see Diagnosis.CancelReason
+	ActivationCancelled ActivationStatus = "cancelled"
+)
+
+//Reasons an activation is cancelled
+const (
+	//CancelActivationGroup is for an activation of an activation group another activation fired in
+	CancelActivationGroup = "activation group"
+	//CancelTupleChanged is for an activation one of whose tuples an action modified, retracted or deleted
+	CancelTupleChanged = "tuple changed"
+	//CancelNoMatch is for an activation whose tuples no longer match the rule, or are retracted
+	CancelNoMatch     = "no match"
+	CancelRuleRemoved = "rule removed"
+)
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"github.com/project-flogo/core/data"
+)
+
+//ExpiredTupleType is the type of the tuples asserted as tuples expire, for rules to match. Its key is
+//the key of the expired tuple, "tupleType" its type and "tuple" the tuple itself. They are retracted
+//once the RTC of the expiry is over
+const ExpiredTupleType TupleType = "tupleExpired"
+
+func init() {
+	RegisterTupleDescriptorsFromTds([]TupleDescriptor{{
+		Name:         string(ExpiredTupleType),
+		TTLInSeconds: 0,
+		Props: []TuplePropertyDescriptor{
+			{Name: "key", PropType: data.TypeString, KeyIndex: 0},
+			{Name: "tupleType", PropType: data.TypeString, KeyIndex: -1},
+			{Name: "tuple", PropType: data.TypeAny, KeyIndex: -1},
+		},
+	}})
+}
+
+//NewExpiredTuple returns the tuple telling the tuple expired
+func NewExpiredTuple(tuple Tuple) (Tuple, error) {
+	return NewTuple(ExpiredTupleType, map[string]interface{}{
+		"key":       tuple.GetKey().String(),
+		"tupleType": string(tuple.GetTupleType()),
+		"tuple":     tuple,
+	})
+}
+
+//SetTTL overrides the TTL of the tuple's type, see TupleDescriptor.TTLInSeconds. 0 retracts the tuple once
+//its RTC is over, a negative ttl never does
+func SetTTL(tuple Tuple, ttl time.Duration) {
+	if t, ok := tuple.(*tupleImpl); ok {
+		t.ttl = ttl
+		t.hasTTL = true
+	}
+}
+
+//GetTTL returns how long the tuple stays asserted, negative for ever
+func GetTTL(tuple Tuple) time.Duration {
+	if t, ok := tuple.(*tupleImpl); ok && t.hasTTL {
+		return t.ttl
+	}
+	td := tuple.GetTupleDescriptor()
+	if td == nil || td.TTLInSeconds < 0 {
+		return -1
+	}
+	return time.Duration(td.TTLInSeconds) * time.Second
+}
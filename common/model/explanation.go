@@ -0,0 +1,61 @@
+package model
+
+import "context"
+
+//Explanation tells why an activation fired: the nodes of the rete network that matched its tuples,
+//the conditions they evaluated and the values these read, see RuleSession.SetExplain
+type Explanation struct {
+	RtcID int64  `json:"rtcId"`
+	Rule  string `json:"rule"`
+	//Tuples are the keys of the activation's tuples, by identifier
+	Tuples map[TupleType]string `json:"tuples"`
+	//Path is the join path, the class nodes of the tuples first and the rule node last
+	Path []ExplanationNode `json:"path"`
+}
+
+//ExplanationNode is a node of the join path. The conditions of a filter or join node passed, those
+//of a group node are combined per its GroupType
+type ExplanationNode struct {
+	//ID is the id of the node in the network's graph
+	ID string `json:"id"`
+	//Type is class, filter, join, group, accumulate or rule
+	Type        string      `json:"type"`
+	Identifiers []TupleType `json:"identifiers,omitempty"`
+	//Inputs are the ids of the nodes passing their tuples to this one, the left one first
+	Inputs     []string               `json:"inputs,omitempty"`
+	GroupType  ConditionGroupType     `json:"groupType,omitempty"`
+	Conditions []ConditionExplanation `json:"conditions,omitempty"`
+}
+
+//ConditionExplanation is a condition of the join path with the values it read
+type ConditionExplanation struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	//Values are the values of the properties the condition reads, by identifier and property.
+	//Identifiers not bound to a tuple of the activation, such as negated ones, have none
+	Values map[TupleType]map[string]interface{} `json:"values,omitempty"`
+}
+
+//PropertyCondition is implemented by conditions that tell the properties they read, by identifier.
+//The explanation of other conditions has the values of all the properties the rule depends on
+type PropertyCondition interface {
+	Condition
+	GetProperties() map[TupleType][]string
+}
+
+type explanationKeyType struct{}
+
+//WithExplanation returns the context of the action firing for the explained activation
+func WithExplanation(ctx context.Context, explanation *Explanation) context.Context {
+	return context.WithValue(ctx, explanationKeyType{}, explanation)
+}
+
+//GetExplanation returns the explanation of the activation whose action is running, nil if the
+//session does not explain them
+func GetExplanation(ctx context.Context) *Explanation {
+	if ctx == nil {
+		return nil
+	}
+	explanation, _ := ctx.Value(explanationKeyType{}).(*Explanation)
+	return explanation
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+)
+
+//JobOperation is what a scheduled job does
+type JobOperation string
+
+const (
+	//AssertJob asserts a tuple of the job's values, a cron job retracts the one of its last run first
+	AssertJob JobOperation = "assert"
+	//RetractJob retracts the asserted tuple of the job's key values
+	RetractJob JobOperation = "retract"
+	//ModifyJob sets the other values of the job on the asserted tuple of its key values
+	ModifyJob JobOperation = "modify"
+)
+
+//Job is an operation on a tuple scheduled for later, see RuleSession.ScheduleJob
+type Job struct {
+	//Key identifies the job, a job replaces the one of the same key
+	Key       string                 `json:"key"`
+	Operation JobOperation           `json:"operation"`
+	TupleType TupleType              `json:"tupleType"`
+	Values    map[string]interface{} `json:"values"`
+	//At is when the job runs next, the next time of its cron if zero
+	At time.Time `json:"at"`
+	//Cron optionally repeats the job, cron fields such as "0 9 * * 1-5" or an interval such as "@every 10m".
+	//A recurring assert skips its runs while the tuple it asserted last is still asserted
+	Cron string `json:"cron,omitempty"`
+}
+
+//JobStore keeps the scheduled jobs across restarts
+type JobStore interface {
+	Save(job Job) error
+	Delete(key string) error
+	LoadAll() ([]Job, error)
+}
@@ -0,0 +1,80 @@
+package model
+
+import "context"
+
+//RuleSessionListener observes the work of a session, see RuleSession.AddListener. The callbacks run
+//in the RTC while the session is locked, they must not assert, retract or wait on the session
+type RuleSessionListener interface {
+	OnRtcStart(ctx context.Context, rs RuleSession, event RtcEvent)
+	//OnRtcEnd is called after the RtcTransactionHandler
+	OnRtcEnd(ctx context.Context, rs RuleSession, event RtcEvent)
+
+	OnTupleAsserted(ctx context.Context, rs RuleSession, event TupleEvent)
+	//OnTupleRetracted is called for the deleted tuples too, see TupleEvent.Deleted
+	OnTupleRetracted(ctx context.Context, rs RuleSession, event TupleEvent)
+	OnTupleModified(ctx context.Context, rs RuleSession, event TupleEvent)
+	//OnTupleExpired is called once the TTL or window of the tuple is over, before it is deleted
+	OnTupleExpired(ctx context.Context, rs RuleSession, event TupleEvent)
+
+	OnActivationCreated(ctx context.Context, rs RuleSession, event ActivationEvent)
+	//OnActivationCancelled is called for the activations removed from the agenda without firing
+	OnActivationCancelled(ctx context.Context, rs RuleSession, event ActivationEvent)
+	OnBeforeAction(ctx context.Context, rs RuleSession, event ActivationEvent)
+	OnAfterAction(ctx context.Context, rs RuleSession, event ActivationEvent)
+}
+
+//RtcEvent is the start or end of an RTC
+type RtcEvent struct {
+	//RtcID tells the RTCs of a process apart, 0 outside of an RTC
+	RtcID int64
+}
+
+//TupleEvent is a change of a tuple
+type TupleEvent struct {
+	RtcID int64
+	Tuple Tuple
+	//RuleName is the rule whose action made the change, empty outside of actions
+	RuleName string
+	//ModifiedProps are the properties set, of a modified tuple
+	ModifiedProps map[string]bool
+	//Deleted tells if a retracted tuple is deleted too
+	Deleted bool
+}
+
+//ActivationEvent is a change of an activation, a rule matching tuples
+type ActivationEvent struct {
+	RtcID    int64
+	RuleName string
+	Tuples   map[TupleType]Tuple
+}
+
+//BaseRuleSessionListener does nothing, embed it to implement only some of the callbacks
+type BaseRuleSessionListener struct{}
+
+func (BaseRuleSessionListener) OnRtcStart(ctx context.Context, rs RuleSession, event RtcEvent) {}
+
+func (BaseRuleSessionListener) OnRtcEnd(ctx context.Context, rs RuleSession, event RtcEvent) {}
+
+func (BaseRuleSessionListener) OnTupleAsserted(ctx context.Context, rs RuleSession, event TupleEvent) {
+}
+
+func (BaseRuleSessionListener) OnTupleRetracted(ctx context.Context, rs RuleSession, event TupleEvent) {
+}
+
+func (BaseRuleSessionListener) OnTupleModified(ctx context.Context, rs RuleSession, event TupleEvent) {
+}
+
+func (BaseRuleSessionListener) OnTupleExpired(ctx context.Context, rs RuleSession, event TupleEvent) {
+}
+
+func (BaseRuleSessionListener) OnActivationCreated(ctx context.Context, rs RuleSession, event ActivationEvent) {
+}
+
+func (BaseRuleSessionListener) OnActivationCancelled(ctx context.Context, rs RuleSession, event ActivationEvent) {
+}
+
+func (BaseRuleSessionListener) OnBeforeAction(ctx context.Context, rs RuleSession, event ActivationEvent) {
+}
+
+func (BaseRuleSessionListener) OnAfterAction(ctx context.Context, rs RuleSession, event ActivationEvent) {
+}
@@ -0,0 +1,70 @@
+package model
+
+import "context"
+
+//Query finds the asserted tuples, or combinations of tuples, satisfying its conditions. Its identifiers
+//and conditions are declared as those of a rule, see RuleSession.RunQuery
+type Query interface {
+	GetName() string
+	GetIdentifiers() []TupleType
+	//GetIdentifierType returns the tuple type of an identifier, see Rule.GetIdentifierType
+	GetIdentifierType(idr TupleType) TupleType
+	GetConditions() []Condition
+	//GetSelfMatch tells if a tuple may be bound to more than one identifier of the query at once
+	GetSelfMatch() bool
+	//GetDeps returns the properties the conditions read, by tuple type, see Rule.GetDeps
+	GetDeps() map[TupleType]map[string]bool
+	String() string
+}
+
+//MutableQuery interface has methods to add identifiers and conditions
+type MutableQuery interface {
+	Query
+	//AddIdentifier binds the identifier to the query without a condition, to find all the tuples of a type,
+	//"alias:tupletype" declares an alias
+	AddIdentifier(idr string) error
+	AddCondition(conditionName string, idrs []string, cFn ConditionEvaluator, ctx RuleContext) error
+	//AddExprCondition adds an expression condition, $param[<name>] in the expression is the value of
+	//a parameter of the query, e.g; "$.order.amount > $param[minAmount]"
+	AddExprCondition(conditionName string, cExpr string, ctx RuleContext) error
+	SetSelfMatch(selfMatch bool)
+}
+
+//QueryResult is a combination of tuples satisfying a query, by identifier
+type QueryResult map[TupleType]Tuple
+
+//QueryContext is the context the conditions of a query are evaluated with when it runs, in place of
+//their own, which is Context
+type QueryContext struct {
+	Context RuleContext
+	Params  map[string]interface{}
+}
+
+//GetQueryParams returns the parameters of the query running, for condition functions, nil if the
+//condition is evaluated for a rule
+func GetQueryParams(ctx RuleContext) map[string]interface{} {
+	if qctx, ok := ctx.(QueryContext); ok {
+		return qctx.Params
+	}
+	return nil
+}
+
+//LiveQueryListener is told how the results of a live query changed, at the end of each RTC changing
+//them, see RuleSession.AddLiveQuery. As those of a RuleSessionListener, the callbacks run while the
+//session is locked, they may run queries with their ctx but must not assert, retract or wait on the session
+type LiveQueryListener interface {
+	//OnResultAdded is called for a combination of tuples entering the results
+	OnResultAdded(ctx context.Context, rs RuleSession, event LiveQueryEvent)
+	//OnResultUpdated is called for a result a tuple of which was modified, or retracted and asserted
+	//again, and that still satisfies the query
+	OnResultUpdated(ctx context.Context, rs RuleSession, event LiveQueryEvent)
+	//OnResultRemoved is called for a combination of tuples leaving the results, once modified or retracted
+	OnResultRemoved(ctx context.Context, rs RuleSession, event LiveQueryEvent)
+}
+
+//LiveQueryEvent is a result of a live query that changed in an RTC
+type LiveQueryEvent struct {
+	RtcID  int64
+	Query  string
+	Result QueryResult
+}
@@ -0,0 +1,163 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/project-flogo/core/data"
+	"github.com/project-flogo/core/data/coerce"
+)
+
+//EventTime is the interval of time the event of a tuple lasts, Start equals End for an instant
+type EventTime struct {
+	Start time.Time
+	End   time.Time
+}
+
+//TemporalOperator relates the event times of two tuples, see TemporalOperator.Eval
+type TemporalOperator string
+
+const (
+	//BeforeOperator passes when the left event ends before the right one starts. Bounds are the
+	//[min, ]max time between them, after any time by default
+	BeforeOperator TemporalOperator = "before"
+	//AfterOperator passes when the left event starts after the right one ends, bounds as BeforeOperator
+	AfterOperator TemporalOperator = "after"
+	//WithinOperator passes when the events start at most the one bound apart, in any order
+	WithinOperator TemporalOperator = "within"
+	//OverlapsOperator passes when the left event starts before the right one, and ends while it lasts
+	OverlapsOperator TemporalOperator = "overlaps"
+	//DuringOperator passes when the left event starts and ends while the right one lasts
+	DuringOperator TemporalOperator = "during"
+)
+
+//GetEventTime returns when the event of the tuple happened: from its descriptor's timestamp and
+//duration properties if any, else the time it was asserted
+func GetEventTime(tuple Tuple) EventTime {
+	td := tuple.GetTupleDescriptor()
+	var start time.Time
+	if td.TimestampProp != "" {
+		start, _ = ToTime(tuple.GetMap()[td.TimestampProp])
+	} else if t, ok := tuple.(*tupleImpl); ok {
+		start = t.assertedAt
+	}
+	var duration time.Duration
+	if td.DurationProp != "" {
+		duration, _ = ToDuration(tuple.GetMap()[td.DurationProp])
+	}
+	return EventTime{start, start.Add(duration)}
+}
+
+//GetAssertionTime returns the time the tuple was first asserted, zero if it was not
+func GetAssertionTime(tuple Tuple) time.Time {
+	if t, ok := tuple.(*tupleImpl); ok {
+		return t.assertedAt
+	}
+	return time.Time{}
+}
+
+//SetAssertionTime records the time the tuple is first asserted, also the value of its timestamp
+//property if it is not set
+func SetAssertionTime(tuple Tuple, at time.Time) {
+	t, ok := tuple.(*tupleImpl)
+	if !ok || !t.assertedAt.IsZero() {
+		return
+	}
+	t.assertedAt = at
+	if prop := t.td.GetProperty(t.td.TimestampProp); prop != nil && t.tuples[prop.Name] == nil {
+		if prop.PropType == data.TypeString {
+			t.tuples[prop.Name] = at.Format(time.RFC3339Nano)
+		} else {
+			t.tuples[prop.Name], _ = coerce.ToType(at.UnixNano()/int64(time.Millisecond), prop.PropType)
+		}
+	}
+}
+
+//ToTime converts an EventTime (its start), a time.Time, a number of milliseconds since the epoch
+//or an RFC 3339 string
+func ToTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case EventTime:
+		return v.Start, nil
+	case time.Time:
+		return v, nil
+	case string:
+		return time.Parse(time.RFC3339Nano, v)
+	case nil:
+		return time.Time{}, fmt.Errorf("Cannot convert nil to a time")
+	}
+	millis, err := coerce.ToInt64(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, millis*int64(time.Millisecond)), nil
+}
+
+//ToDuration converts a time.Duration, a number of milliseconds or a string such as "10m"
+func ToDuration(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		return time.ParseDuration(v)
+	case nil:
+		return 0, fmt.Errorf("Cannot convert nil to a duration")
+	}
+	millis, err := coerce.ToInt64(value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(millis) * time.Millisecond, nil
+}
+
+//ValidateBounds checks that the operator is known and takes that many bounds
+func (op TemporalOperator) ValidateBounds(bounds []time.Duration) error {
+	switch op {
+	case BeforeOperator, AfterOperator:
+		if len(bounds) > 2 || len(bounds) == 2 && bounds[0] > bounds[1] {
+			return fmt.Errorf("Operator [%s] takes an optional [min, ]max bound", op)
+		}
+	case WithinOperator:
+		if len(bounds) != 1 {
+			return fmt.Errorf("Operator [%s] takes one bound", op)
+		}
+	case OverlapsOperator, DuringOperator:
+		if len(bounds) != 0 {
+			return fmt.Errorf("Operator [%s] takes no bounds", op)
+		}
+	default:
+		return fmt.Errorf("Unknown temporal operator [%s]", op)
+	}
+	return nil
+}
+
+//Eval tells if the left event relates to the right one, see the operators
+func (op TemporalOperator) Eval(left EventTime, right EventTime, bounds ...time.Duration) (bool, error) {
+	if err := op.ValidateBounds(bounds); err != nil {
+		return false, err
+	}
+	switch op {
+	case BeforeOperator:
+		return inBounds(right.Start.Sub(left.End), bounds), nil
+	case AfterOperator:
+		return inBounds(left.Start.Sub(right.End), bounds), nil
+	case WithinOperator:
+		d := left.Start.Sub(right.Start)
+		return -bounds[0] <= d && d <= bounds[0], nil
+	case OverlapsOperator:
+		return left.Start.Before(right.Start) && right.Start.Before(left.End) && left.End.Before(right.End), nil
+	}
+	//DuringOperator
+	return right.Start.Before(left.Start) && left.End.Before(right.End), nil
+}
+
+//inBounds tells if d is in (0, +inf), (0, max] or [min, max]
+func inBounds(d time.Duration, bounds []time.Duration) bool {
+	switch len(bounds) {
+	case 0:
+		return d > 0
+	case 1:
+		return d > 0 && d <= bounds[0]
+	}
+	return bounds[0] <= d && d <= bounds[1]
+}
@@ -46,6 +46,11 @@ type tupleImpl struct {
 	tuples    map[string]interface{}
 	key       TupleKey
 	td        *TupleDescriptor
+	//when first asserted, see SetAssertionTime
+	assertedAt time.Time
+	//overrides the TTL of the type when set, see SetTTL
+	ttl    time.Duration
+	hasTTL bool
 }
 
 func NewTuple(tupleType TupleType, values map[string]interface{}) (mtuple MutableTuple, err error) {
@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"fmt"
 
@@ -24,7 +25,34 @@ type TupleDescriptor struct {
 	Name         string                    `json:"name"`
 	TTLInSeconds int                       `json:"ttl"`
 	Props        []TuplePropertyDescriptor `json:"properties"`
-	keyProps     []string
+	//the property holding the time of the event, see GetEventTime
+	TimestampProp string `json:"timestamp,omitempty"`
+	//the property holding how long the event lasts
+	DurationProp string `json:"duration,omitempty"`
+	//keeps only the latest tuples of the type asserted
+	Window   *WindowDescriptor `json:"window,omitempty"`
+	keyProps []string
+}
+
+//WindowType is how a window lets go of its tuples
+type WindowType string
+
+const (
+	//SlidingWindow deletes its oldest tuple beyond its length, and each tuple once its time is over
+	SlidingWindow WindowType = "sliding"
+	//TumblingWindow deletes all its tuples when full, and at the end of each period of its time
+	TumblingWindow WindowType = "tumbling"
+)
+
+//WindowDescriptor bounds the number of tuples of a type asserted, or how long they stay by their event
+//time, see GetEventTime. The tuples leaving the window are deleted
+type WindowDescriptor struct {
+	Type   WindowType `json:"type,omitempty"`
+	Length int        `json:"length,omitempty"`
+	//a duration such as "5m"
+	Time string `json:"time,omitempty"`
+	//optional property, each of its values has a window of its own
+	GroupBy string `json:"groupBy,omitempty"`
 }
 
 // TuplePropertyDescriptor defines the actual property, its type, key index
@@ -101,6 +129,22 @@ func (td *TupleDescriptor) UnmarshalJSON(b []byte) error {
 		td.TTLInSeconds = int(ttl.(float64))
 	}
 
+	if timestamp, ok := val["timestamp"]; ok {
+		td.TimestampProp, _ = timestamp.(string)
+	}
+	if duration, ok := val["duration"]; ok {
+		td.DurationProp, _ = duration.(string)
+	}
+
+	if window, ok := val["window"]; ok {
+		jsonWindow, _ := json.Marshal(window)
+		td.Window = &WindowDescriptor{}
+		err := json.Unmarshal(jsonWindow, td.Window)
+		if err != nil {
+			return fmt.Errorf("Invalid window for type [%s]: %s", nm, err)
+		}
+	}
+
 	jsonProps := val["properties"].([]interface{})
 
 	idxProp := make(map[int]string)
@@ -148,6 +192,15 @@ func (td *TupleDescriptor) UnmarshalJSON(b []byte) error {
 		}
 	}
 
+	for _, prop := range []string{td.TimestampProp, td.DurationProp} {
+		if prop != "" && td.GetProperty(prop) == nil {
+			return fmt.Errorf("Property [%s] not found for type [%s]", prop, nm)
+		}
+	}
+	if td.Window != nil {
+		return td.Window.validate(td)
+	}
+
 	return nil
 }
 
@@ -183,3 +236,31 @@ func (td *TupleDescriptor) GetKeyProps() []string {
 	}
 	return td.keyProps
 }
+
+//GetDuration returns the time of the window, 0 for none
+func (wd *WindowDescriptor) GetDuration() time.Duration {
+	duration, _ := time.ParseDuration(wd.Time)
+	return duration
+}
+
+func (wd *WindowDescriptor) validate(td *TupleDescriptor) error {
+	if wd.Type == "" {
+		wd.Type = SlidingWindow
+	}
+	if wd.Type != SlidingWindow && wd.Type != TumblingWindow {
+		return fmt.Errorf("Unknown window type [%s] for type [%s]", wd.Type, td.Name)
+	}
+	if wd.Time != "" {
+		duration, err := time.ParseDuration(wd.Time)
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("Invalid window time [%s] for type [%s]", wd.Time, td.Name)
+		}
+	}
+	if wd.Length < 0 || wd.Length == 0 && wd.Time == "" {
+		return fmt.Errorf("Window of type [%s] needs a length or a time", td.Name)
+	}
+	if wd.GroupBy != "" && td.GetProperty(wd.GroupBy) == nil {
+		return fmt.Errorf("Property [%s] not found for type [%s]", wd.GroupBy, td.Name)
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package model
 
 import (
 	"context"
+	"io"
+	"time"
 )
 
 // RuleContext associated with every rule
@@ -17,6 +19,22 @@ type Rule interface {
 	GetPriority() int
 	GetDeps() map[TupleType]map[string]bool
 	GetContext() RuleContext
+	GetConditionGroups() []ConditionGroup
+	GetAccumulates() []Accumulate
+	//GetIdentifierType returns the tuple type of an identifier, identifiers are either tuple types
+	//or aliases declared as "alias:tupletype", e.g; "o1:order", to bind the same type more than once
+	GetIdentifierType(idr TupleType) TupleType
+	//GetSelfMatch tells if a tuple may be bound to more than one identifier of the rule at once
+	GetSelfMatch() bool
+	//GetAgendaGroup is the agenda group of the rule, the rule fires only while its group has the focus
+	GetAgendaGroup() string
+	//GetActivationGroup is the activation group of the rule, "" for none. Once a rule of the group
+	//fires, the other pending activations of the group are cancelled
+	GetActivationGroup() string
+	//GetNoLoop tells if the rule's own action is kept from activating it again
+	GetNoLoop() bool
+	//GetLockOnActive tells if the rule is kept from being activated by actions while its agenda group has the focus
+	GetLockOnActive() bool
 }
 
 //MutableRule interface has methods to add conditions and actions
@@ -27,7 +45,99 @@ type MutableRule interface {
 	SetPriority(priority int)
 	SetContext(ctx RuleContext)
 	AddExprCondition(conditionName string, cExpr string, ctx RuleContext) error
-	AddIdrsToRule(idrs []TupleType)
+	//AddEquiJoinCondition adds a condition that passes when leftProp equals rightProp, e.g; "order.id", "item.orderId"
+	AddEquiJoinCondition(conditionName string, leftProp string, rightProp string, ctx RuleContext) error
+	//AddTemporalCondition adds a condition that passes when the events of the left and right identifiers relate
+	//by the operator, e.g; "t2", AfterOperator, "t1", 10*time.Minute. See GetEventTime
+	AddTemporalCondition(conditionName string, left string, operator TemporalOperator, right string, ctx RuleContext, bounds ...time.Duration) error
+	AddIdrsToRule(idrs []TupleType) error
+	//AddNotGroup adds a group of conditions that passes when no combination of idrs satisfies all its conditions
+	AddNotGroup(groupName string, idrs []string) (MutableConditionGroup, error)
+	//AddExistsGroup adds a group of conditions that passes once when some combinations of idrs satisfy all its conditions
+	AddExistsGroup(groupName string, idrs []string) (MutableConditionGroup, error)
+	//AddAccumulate binds the identifier resultIdr to the aggregate fn of over, e.g; "order.amount", or just "order"
+	//for count and collect, for each value of groupBy, e.g; "order.customerId", or "" to aggregate all. See Accumulate
+	AddAccumulate(resultIdr string, fn AccumulateFunction, over string, groupBy string) (MutableAccumulate, error)
+	SetSelfMatch(selfMatch bool)
+	SetAgendaGroup(agendaGroup string)
+	SetActivationGroup(activationGroup string)
+	SetNoLoop(noLoop bool)
+	SetLockOnActive(lockOnActive bool)
+}
+
+//MainAgendaGroup is the agenda group of rules that set none, it has the focus when no other group does
+const MainAgendaGroup = "MAIN"
+
+//ConditionGroupType is how a condition group quantifies its identifiers
+type ConditionGroupType string
+
+const (
+	//NotGroup passes when there are no tuples for the group's identifiers satisfying all its conditions
+	NotGroup ConditionGroupType = "not"
+	//ExistsGroup passes once, no matter how many tuples for the group's identifiers satisfy all its conditions
+	ExistsGroup ConditionGroupType = "exists"
+)
+
+//ConditionGroup is a set of conditions over identifiers that are quantified by the group instead of being
+//bound by the rule. Its conditions can also refer to the rule's identifiers, but the group's identifiers
+//are never passed to the rule's action
+type ConditionGroup interface {
+	GetName() string
+	GetGroupType() ConditionGroupType
+	GetRule() Rule
+	GetIdentifiers() []TupleType
+	GetConditions() []Condition
+	String() string
+}
+
+//MutableConditionGroup interface has methods to add conditions to a group
+type MutableConditionGroup interface {
+	ConditionGroup
+	AddCondition(conditionName string, idrs []string, cFn ConditionEvaluator, ctx RuleContext) (err error)
+	AddExprCondition(conditionName string, cExpr string, ctx RuleContext) error
+	AddEquiJoinCondition(conditionName string, leftProp string, rightProp string, ctx RuleContext) error
+	AddTemporalCondition(conditionName string, left string, operator TemporalOperator, right string, ctx RuleContext, bounds ...time.Duration) error
+}
+
+//AccumulateFunction is how an accumulate aggregates its tuples
+type AccumulateFunction string
+
+const (
+	//CountFunction counts the tuples, the result value is an int
+	CountFunction AccumulateFunction = "count"
+	//SumFunction adds up the property of the tuples, the result value is a float64
+	SumFunction AccumulateFunction = "sum"
+	//MinFunction is the smallest property of the tuples, the result value is a float64
+	MinFunction AccumulateFunction = "min"
+	//MaxFunction is the largest property of the tuples, the result value is a float64
+	MaxFunction AccumulateFunction = "max"
+	//AvgFunction is the average property of the tuples, the result value is a float64
+	AvgFunction AccumulateFunction = "avg"
+	//CollectFunction collects the tuples, the result value is a []interface{} of Tuple
+	CollectFunction AccumulateFunction = "collect"
+)
+
+//Accumulate aggregates the tuples of an identifier satisfying its conditions. The result is bound to the rule as a
+//tuple of type GetName() with the properties "key", the groupBy value, "value", the aggregate and "count", the number
+//of tuples aggregated. There is one result tuple per groupBy value with tuples, and none if there are no tuples.
+//Results are kept up to date as tuples are asserted, modified and retracted. The type is registered as the rule is
+//added to a session
+type Accumulate interface {
+	GetName() string
+	GetFunction() AccumulateFunction
+	GetRule() Rule
+	GetIdentifier() TupleType
+	GetProperty() string
+	GetGroupBy() string
+	GetConditions() []Condition
+	String() string
+}
+
+//MutableAccumulate interface has methods to add conditions filtering the tuples to aggregate
+type MutableAccumulate interface {
+	Accumulate
+	AddCondition(conditionName string, idrs []string, cFn ConditionEvaluator, ctx RuleContext) (err error)
+	AddExprCondition(conditionName string, cExpr string, ctx RuleContext) error
 }
 
 //Condition interface to maintain/get various condition properties
@@ -40,6 +150,21 @@ type Condition interface {
 	Evaluate(string, string, map[TupleType]Tuple, RuleContext) (bool, error)
 }
 
+//EquiJoin declares that the property LeftProp of identifier Left equals the property RightProp of identifier Right
+type EquiJoin struct {
+	Left      TupleType
+	LeftProp  string
+	Right     TupleType
+	RightProp string
+}
+
+//EquiJoinCondition is implemented by conditions that are (in part) equalities between properties of two identifiers.
+//The rete network uses these to hash index its join tables instead of scanning them
+type EquiJoinCondition interface {
+	Condition
+	GetEquiJoins() []EquiJoin
+}
+
 // RuleSession to maintain rules and assert tuples against those rules
 type RuleSession interface {
 	GetName() string
@@ -49,11 +174,35 @@ type RuleSession interface {
 	GetRules() []Rule
 
 	Assert(ctx context.Context, tuple Tuple) (err error)
-	Retract(ctx context.Context, tuple Tuple)
+	//AssertWithTTL asserts a tuple expiring once the ttl has passed, instead of per the TTL of its type
+	AssertWithTTL(ctx context.Context, tuple Tuple, ttl time.Duration) (err error)
+	//Retract fails, without retracting, if the write-ahead log of the session cannot log it
+	Retract(ctx context.Context, tuple Tuple) (err error)
+	//LogicalAssert asserts a tuple from an action, justified by the activation firing. The tuple is
+	//retracted, and deleted, once none of the activations that logically asserted it match any more
+	LogicalAssert(ctx context.Context, tuple Tuple) (err error)
 
+	//ScheduleAssert schedules a job asserting the tuple, its key is the key as a string
 	ScheduleAssert(ctx context.Context, delayInMillis uint64, key interface{}, tuple Tuple)
 	CancelScheduledAssert(ctx context.Context, key interface{})
+	//ScheduleJob runs the job at its time, and again at the next times of its cron if any
+	ScheduleJob(ctx context.Context, job Job) (err error)
+	CancelScheduledJob(ctx context.Context, key string)
+	//GetScheduledJobs returns the pending jobs by time
+	GetScheduledJobs() []Job
+	//SetJobStore keeps the scheduled jobs in the store, and schedules those it holds, so that they
+	//survive a restart
+	SetJobStore(store JobStore) (err error)
+
+	//Snapshot writes the asserted tuples, their descriptors, remaining TTLs, pending activations and
+	//scheduled jobs as versioned JSON
+	Snapshot(w io.Writer) (err error)
+	//Restore asserts the tuples of a snapshot into the session, after its rules are added and before
+	//any tuple is asserted, and schedules its jobs. Actions fire only if runActions, else only the
+	//activations pending at the snapshot stay on the agenda
+	Restore(r io.Reader, runActions bool) (err error)
 
+	//Unregister closes the session, its tuples no longer expire
 	Unregister()
 
 	//Optional, called before asserting a tuple but after adding all rules
@@ -68,14 +217,96 @@ type RuleSession interface {
 	//return the asserted tuple, nil if not found
 	GetAssertedTuple(key TupleKey) Tuple
 
-	//Retract, and remove
-	Delete(ctx context.Context, tuple Tuple)
+	//Retract, and remove. Fails as Retract does
+	Delete(ctx context.Context, tuple Tuple) (err error)
 
 	//RtcTransactionHandler
 	RegisterRtcTransactionHandler(txnHandler RtcTransactionHandler, handlerCtx interface{})
+	//AddListener calls the listener as the session asserts, retracts, modifies and expires tuples, and
+	//creates, cancels and fires activations, in the RTCs starting after
+	AddListener(listener RuleSessionListener)
+	RemoveListener(listener RuleSessionListener)
 
 	//replay existing tuples into a rule
 	ReplayTuplesForRule(ruleName string) (err error)
+
+	//SetConflictResolver sets the strategy ordering the rules ready to fire, by default rules fire by priority
+	//and the most recently activated first
+	SetConflictResolver(resolver ConflictResolver)
+	GetConflictResolver() ConflictResolver
+
+	//SetFocus pushes the agenda group onto the focus stack, its rules fire until it has no more activations,
+	//then the focus goes back to the group below it. From an action, pass its ctx, they fire after it.
+	//Otherwise its pending activations fire before SetFocus returns
+	SetFocus(ctx context.Context, agendaGroup string)
+	//GetFocus returns the agenda group having the focus
+	GetFocus(ctx context.Context) string
+
+	//SetRefraction keeps an activation from firing again in the same RTC, unless a property its
+	//rule depends on changed since it fired. Off by default
+	SetRefraction(refraction bool)
+	GetRefraction() bool
+
+	//SetExplain has each activation firing record why, its action gets the explanation from its context
+	//with GetExplanation, and the RtcTxn of the RTC has them all. Off by default
+	SetExplain(explain bool)
+	GetExplain() bool
+	//Diagnose tells why the rule did not fire for the tuples of the keys: which of them are not asserted,
+	//which identifiers of the rule they leave unmatched, which conditions pass or fail, and whether their
+	//activation is pending, fired or was cancelled. Not to be called from an action
+	Diagnose(ruleName string, keys []TupleKey) (diagnosis Diagnosis, err error)
+
+	//AddQuery adds a query to run with RunQuery, before asserting the tuples of the types of its identifiers
+	//that no rule binds
+	AddQuery(query Query) (err error)
+	DeleteQuery(queryName string)
+	GetQueries() []Query
+	//RunQuery returns the combinations of asserted tuples satisfying the query, the params are those of
+	//its $param[<name>]s, see GetQueryParams. The tuples are those asserted between RTCs, or, from an
+	//action, those of its RTC before the asserts and retracts of the action are done
+	RunQuery(ctx context.Context, queryName string, params map[string]interface{}) (results []QueryResult, err error)
+	//AddLiveQuery builds the query into the rete network with the params, the listener is told of its results
+	//as they are added, updated and removed by the RTCs, and of its results among the tuples asserted already
+	//as added. Not to be called from an action
+	AddLiveQuery(ctx context.Context, query Query, params map[string]interface{}, listener LiveQueryListener) (err error)
+	RemoveLiveQuery(queryName string)
+
+	//SetClock sets the clock timing the session: tuple TTLs, windows, scheduled asserts and event times.
+	//A realtime clock by default. It fails once the session started, or has tuples or jobs timed by the clock
+	SetClock(clock Clock) (err error)
+	GetClock() Clock
+}
+
+//Clock tells the time, and runs functions once some time has passed
+type Clock interface {
+	Now() time.Time
+	//AfterFunc runs f in its own goroutine once d has passed
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+//Timer is a function scheduled by a Clock
+type Timer interface {
+	//Stop keeps the function from running, false if it already ran or was stopped
+	Stop() bool
+}
+
+//Activation is a rule ready to fire for some tuples, see ConflictResolver
+type Activation interface {
+	GetRule() Rule
+	GetTuples() map[TupleType]Tuple
+	//GetRecency tells when the activation was created, the higher the more recent
+	GetRecency() int
+	//GetTupleRecency tells when the tuple bound to the identifier was last asserted or modified
+	GetTupleRecency(idr TupleType) int
+	//GetTupleRecencies are the recencies of all the activation's tuples, the most recent first
+	GetTupleRecencies() []int
+}
+
+//ConflictResolver decides the order in which activations fire
+type ConflictResolver interface {
+	GetName() string
+	//Less tells if activation a fires before activation b
+	Less(a Activation, b Activation) bool
 }
 
 //ConditionEvaluator is a function pointer for handling condition evaluations on the server side
@@ -99,6 +330,10 @@ type RtcTxn interface {
 	GetRtcAdded() map[string]map[string]Tuple
 	GetRtcModified() map[string]map[string]RtcModified
 	GetRtcDeleted() map[string]map[string]Tuple
+	//the tuples retracted without being deleted
+	GetRtcRetracted() map[string]map[string]Tuple
+	//why the activations of the RTC fired, in order, if the session explains them
+	GetExplanations() []Explanation
 }
 
 type RtcModified interface {
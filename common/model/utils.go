@@ -1,5 +1,11 @@
 package model
 
+import (
+	"strconv"
+
+	"github.com/project-flogo/core/data/coerce"
+)
+
 //IdentifiersToString Take a slice of Identifiers and return a string representation
 func IdentifiersToString(identifiers []TupleType) string {
 	str := ""
@@ -18,3 +24,17 @@ func Contains(identifiers []TupleType, toCheck TupleType) (bool, int) {
 	}
 	return false, -1
 }
+
+// ValueKey returns a canonical string for a property value, numbers of different types having the same key.
+// Used to compare and hash values of equi-join properties, see EquiJoin
+func ValueKey(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		f, _ := coerce.ToFloat64(v)
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	str, _ := coerce.ToString(val)
+	return str
+}
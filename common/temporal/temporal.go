@@ -0,0 +1,64 @@
+package temporal
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/project-flogo/core/data"
+	"github.com/project-flogo/core/data/expression/function"
+	"github.com/project-flogo/rules/common/model"
+)
+
+//The temporal operators as expression functions, e.g; temporal.after($event[t2], $event[t1], "10m").
+//Events are model.EventTime, times or numbers of milliseconds since the epoch, bounds are durations
+//or numbers of milliseconds
+func init() {
+	for _, op := range []model.TemporalOperator{model.BeforeOperator, model.AfterOperator, model.WithinOperator,
+		model.OverlapsOperator, model.DuringOperator} {
+		function.Register(&fnOperator{op})
+	}
+}
+
+type fnOperator struct {
+	op model.TemporalOperator
+}
+
+func (fn *fnOperator) Name() string {
+	return string(fn.op)
+}
+
+func (fn *fnOperator) Sig() (paramTypes []data.Type, isVariadic bool) {
+	return []data.Type{data.TypeAny}, true
+}
+
+func (fn *fnOperator) Eval(params ...interface{}) (interface{}, error) {
+	if len(params) < 2 {
+		return false, fmt.Errorf("%s function needs two events", fn.op)
+	}
+	left, err := ToEventTime(params[0])
+	if err != nil {
+		return false, err
+	}
+	right, err := ToEventTime(params[1])
+	if err != nil {
+		return false, err
+	}
+	bounds := []time.Duration{}
+	for _, param := range params[2:] {
+		bound, err := model.ToDuration(param)
+		if err != nil {
+			return false, err
+		}
+		bounds = append(bounds, bound)
+	}
+	return fn.op.Eval(left, right, bounds...)
+}
+
+//ToEventTime converts a model.EventTime, else an instant, see model.ToTime
+func ToEventTime(value interface{}) (model.EventTime, error) {
+	if eventTime, ok := value.(model.EventTime); ok {
+		return eventTime, nil
+	}
+	t, err := model.ToTime(value)
+	return model.EventTime{Start: t, End: t}, err
+}
@@ -15,19 +15,51 @@ type RuleActionDescriptor struct {
 	Name       string               `json:"name"`
 	IOMetadata *metadata.IOMetadata `json:"metadata"`
 	Rules      []*RuleDescriptor    `json:"rules"`
+	//ConflictResolver is the name of a built-in or registered model.ConflictResolver
+	ConflictResolver string `json:"conflictResolver,omitempty"`
+	Refraction       bool   `json:"refraction,omitempty"`
+	//Explain has the activations firing record why, see model.Explanation
+	Explain bool `json:"explain,omitempty"`
 }
 
 type RuleSessionDescriptor struct {
-	Rules []*RuleDescriptor `json:"rules"`
+	Rules            []*RuleDescriptor `json:"rules"`
+	ConflictResolver string            `json:"conflictResolver,omitempty"`
+	Refraction       bool              `json:"refraction,omitempty"`
+	Explain          bool              `json:"explain,omitempty"`
 }
 
 // RuleDescriptor defines a rule
 type RuleDescriptor struct {
-	Name        string
-	Conditions  []*ConditionDescriptor
-	ActionFunc  model.ActionFunction
-	Priority    int
-	Identifiers []string
+	Name            string
+	Conditions      []*ConditionDescriptor
+	ActionFunc      model.ActionFunction
+	Priority        int
+	Identifiers     []string
+	Groups          []*ConditionGroupDescriptor
+	Accumulates     []*AccumulateDescriptor
+	SelfMatch       bool
+	AgendaGroup     string
+	ActivationGroup string
+	NoLoop          bool
+	LockOnActive    bool
+}
+
+// ConditionGroupDescriptor defines a condition group in a rule, see model.ConditionGroupType
+type ConditionGroupDescriptor struct {
+	Name        string                 `json:"name"`
+	Type        string                 `json:"type"`
+	Identifiers []string               `json:"identifiers"`
+	Conditions  []*ConditionDescriptor `json:"conditions"`
+}
+
+// AccumulateDescriptor defines an accumulate in a rule, see model.Accumulate
+type AccumulateDescriptor struct {
+	Name       string                 `json:"name"`
+	Function   string                 `json:"function"`
+	Over       string                 `json:"over"`
+	GroupBy    string                 `json:"groupBy"`
+	Conditions []*ConditionDescriptor `json:"conditions"`
 }
 
 // ConditionDescriptor defines a condition in a rule
@@ -40,11 +72,18 @@ type ConditionDescriptor struct {
 
 func (c *RuleDescriptor) UnmarshalJSON(d []byte) error {
 	ser := &struct {
-		Name         string                 `json:"name"`
-		Conditions   []*ConditionDescriptor `json:"conditions"`
-		ActionFuncId string                 `json:"actionFunction"`
-		Priority     int                    `json:"priority"`
-		Identifiers  []string               `json:"identifiers"`
+		Name            string                      `json:"name"`
+		Conditions      []*ConditionDescriptor      `json:"conditions"`
+		ActionFuncId    string                      `json:"actionFunction"`
+		Priority        int                         `json:"priority"`
+		Identifiers     []string                    `json:"identifiers"`
+		Groups          []*ConditionGroupDescriptor `json:"groups"`
+		Accumulates     []*AccumulateDescriptor     `json:"accumulates"`
+		SelfMatch       bool                        `json:"selfMatch"`
+		AgendaGroup     string                      `json:"agendaGroup"`
+		ActivationGroup string                      `json:"activationGroup"`
+		NoLoop          bool                        `json:"noLoop"`
+		LockOnActive    bool                        `json:"lockOnActive"`
 	}{}
 
 	if err := json.Unmarshal(d, ser); err != nil {
@@ -56,6 +95,13 @@ func (c *RuleDescriptor) UnmarshalJSON(d []byte) error {
 	c.ActionFunc = GetActionFunction(ser.ActionFuncId)
 	c.Priority = ser.Priority
 	c.Identifiers = ser.Identifiers
+	c.Groups = ser.Groups
+	c.Accumulates = ser.Accumulates
+	c.SelfMatch = ser.SelfMatch
+	c.AgendaGroup = ser.AgendaGroup
+	c.ActivationGroup = ser.ActivationGroup
+	c.NoLoop = ser.NoLoop
+	c.LockOnActive = ser.LockOnActive
 
 	return nil
 }
@@ -82,6 +128,35 @@ func (c *RuleDescriptor) MarshalJSON() ([]byte, error) {
 	buffer.Truncate(buffer.Len() - 1)
 	buffer.WriteString("],")
 
+	if c.Groups != nil {
+		jsonGroups, err := json.Marshal(c.Groups)
+		if err == nil {
+			buffer.WriteString("\"groups\":" + string(jsonGroups) + ",")
+		}
+	}
+	if c.Accumulates != nil {
+		jsonAccumulates, err := json.Marshal(c.Accumulates)
+		if err == nil {
+			buffer.WriteString("\"accumulates\":" + string(jsonAccumulates) + ",")
+		}
+	}
+
+	if c.SelfMatch {
+		buffer.WriteString("\"selfMatch\":true,")
+	}
+	if c.AgendaGroup != "" {
+		buffer.WriteString("\"agendaGroup\":\"" + c.AgendaGroup + "\",")
+	}
+	if c.ActivationGroup != "" {
+		buffer.WriteString("\"activationGroup\":\"" + c.ActivationGroup + "\",")
+	}
+	if c.NoLoop {
+		buffer.WriteString("\"noLoop\":true,")
+	}
+	if c.LockOnActive {
+		buffer.WriteString("\"lockOnActive\":true,")
+	}
+
 	actionFunctionID := GetActionFunctionID(c.ActionFunc)
 	buffer.WriteString("\"actionFunction\":\"" + actionFunctionID + "\",")
 	buffer.WriteString("\"priority\":" + strconv.Itoa(c.Priority) + "}")
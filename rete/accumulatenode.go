@@ -0,0 +1,237 @@
+package rete
+
+import (
+	"context"
+
+	"github.com/project-flogo/core/data/coerce"
+	"github.com/project-flogo/rules/common/model"
+)
+
+//accumulateNode aggregates the handles asserted into it and propagates the results as handles of
+//the accumulate's result type to the rule's links of that class node, see model.Accumulate
+type accumulateNode interface {
+	node
+}
+
+type accumulateNodeImpl struct {
+	nodeImpl
+	accumulate   model.Accumulate
+	classNodeVar classNode
+
+	table     joinTable
+	groups    map[string]*accumulateGroup
+	rowGroups map[joinTableRow]*accumulateGroup
+}
+
+//accumulateGroup holds the rows for a groupBy value and the current result
+type accumulateGroup struct {
+	key  string
+	rows []joinTableRow
+	//the property value of a row when it was added, the tuple may have changed since
+	values map[joinTableRow]float64
+
+	sum float64
+	min float64
+	max float64
+	//min and max need a scan of the values after removing an extreme
+	extremesValid bool
+
+	handle reteHandle
+}
+
+func newAccumulateNode(nw Network, rule model.Rule, accumulate model.Accumulate, classNodeVar classNode) accumulateNode {
+	an := accumulateNodeImpl{}
+	an.initAccumulateNodeImpl(nw, rule, accumulate, classNodeVar)
+	return &an
+}
+
+func (an *accumulateNodeImpl) initAccumulateNodeImpl(nw Network, rule model.Rule, accumulate model.Accumulate, classNodeVar classNode) {
+	identifiers := []model.TupleType{accumulate.GetIdentifier()}
+	an.initNodeImpl(nw, rule, identifiers)
+	an.accumulate = accumulate
+	an.classNodeVar = classNodeVar
+	an.table = newJoinTable(nw, rule, identifiers)
+	an.table.setListener(an)
+	an.groups = make(map[string]*accumulateGroup)
+	an.rowGroups = make(map[joinTableRow]*accumulateGroup)
+}
+
+func (an *accumulateNodeImpl) String() string {
+	return "\t[AccumulateNode(" + an.nodeImpl.String() + ") class(" + an.classNodeVar.getName() + ")\n" +
+		"\t\tAccumulate           = " + an.accumulate.String() + "]\n"
+}
+
+func (an *accumulateNodeImpl) assertObjects(ctx context.Context, handles []reteHandle, isRight bool) {
+	row := newJoinTableRow(handles)
+	an.table.addRow(row)
+
+	tuple := handles[0].getTuple()
+	key := ""
+	if an.accumulate.GetGroupBy() != "" {
+		key = model.ValueKey(tuple.GetMap()[an.accumulate.GetGroupBy()])
+	}
+	g, found := an.groups[key]
+	if !found {
+		g = &accumulateGroup{key: key, values: make(map[joinTableRow]float64), extremesValid: true}
+		an.groups[key] = g
+	}
+	an.rowGroups[row] = g
+	g.rows = append(g.rows, row)
+	if an.accumulate.GetProperty() != "" {
+		val, err := coerce.ToFloat64(tuple.GetMap()[an.accumulate.GetProperty()])
+		if err == nil && tuple.GetMap()[an.accumulate.GetProperty()] != nil {
+			g.addValue(row, val)
+		}
+	}
+	an.update(ctx, g)
+}
+
+func (an *accumulateNodeImpl) retractObjects(ctx context.Context, handles []reteHandle, isRight bool) {
+	row := an.table.findRow(handles)
+	if row == nil {
+		return
+	}
+	an.table.removeRow(row)
+	an.removed(ctx, row)
+}
+
+//rowRetracted implements joinTableListener, called when the tuple of the row got retracted or modified
+func (an *accumulateNodeImpl) rowRetracted(ctx context.Context, jt joinTable, row joinTableRow) {
+	an.removed(ctx, row)
+}
+
+func (an *accumulateNodeImpl) removed(ctx context.Context, row joinTableRow) {
+	g, found := an.rowGroups[row]
+	if !found {
+		return
+	}
+	delete(an.rowGroups, row)
+	for i, r := range g.rows {
+		if r == row {
+			g.rows = append(g.rows[:i], g.rows[i+1:]...)
+			break
+		}
+	}
+	g.removeValue(row)
+	an.update(ctx, g)
+}
+
+//update withdraws the group's previous result and propagates its new one, if it still has rows
+func (an *accumulateNodeImpl) update(ctx context.Context, g *accumulateGroup) {
+	if g.handle != nil {
+		g.handle.removeJoinTableRowRefs(ctx, nil)
+		getReteCtx(ctx).getConflictResolver().removeAgendaItemsFor(ctx, g.handle)
+		g.handle = nil
+	}
+	if len(g.rows) == 0 {
+		delete(an.groups, g.key)
+		return
+	}
+
+	values := map[string]interface{}{
+		"key":   g.key,
+		"count": len(g.rows),
+	}
+	numValues := len(g.values)
+	switch an.accumulate.GetFunction() {
+	case model.CountFunction:
+		values["value"] = len(g.rows)
+	case model.SumFunction:
+		values["value"] = g.sum
+	case model.AvgFunction:
+		if numValues > 0 {
+			values["value"] = g.sum / float64(numValues)
+		}
+	case model.MinFunction:
+		if numValues > 0 {
+			values["value"] = g.getMin()
+		}
+	case model.MaxFunction:
+		if numValues > 0 {
+			values["value"] = g.getMax()
+		}
+	case model.CollectFunction:
+		tuples := make([]interface{}, len(g.rows))
+		for i, r := range g.rows {
+			tuples[i] = r.getHandles()[0].getTuple()
+		}
+		values["value"] = tuples
+	}
+	tuple, err := model.NewTuple(model.TupleType(an.accumulate.GetName()), values)
+	if err != nil {
+		return
+	}
+	h := handleImpl{}
+	h.initHandleImpl()
+	h.setTuple(tuple)
+	h.setRecency(getReteCtx(ctx).getNetwork().incrementAndGetRecency())
+	g.handle = &h
+
+	//result handles are not asserted, so only this rule sees them
+	handles := []reteHandle{g.handle}
+	for e := an.classNodeVar.getClassNodeLinks().Front(); e != nil; e = e.Next() {
+		classNodeLinkVar := e.Value.(classNodeLink)
+		if classNodeLinkVar.getRule().GetName() == an.rule.GetName() {
+			classNodeLinkVar.propagateObjects(ctx, handles)
+		}
+	}
+}
+
+func (g *accumulateGroup) addValue(row joinTableRow, val float64) {
+	if len(g.values) == 0 {
+		g.min, g.max = val, val
+		g.extremesValid = true
+	} else if g.extremesValid {
+		if val < g.min {
+			g.min = val
+		}
+		if val > g.max {
+			g.max = val
+		}
+	}
+	g.values[row] = val
+	g.sum += val
+}
+
+func (g *accumulateGroup) removeValue(row joinTableRow) {
+	val, found := g.values[row]
+	if !found {
+		return
+	}
+	delete(g.values, row)
+	g.sum -= val
+	if len(g.values) == 0 {
+		g.sum = 0
+		g.extremesValid = true
+	} else if val == g.min || val == g.max {
+		g.extremesValid = false
+	}
+}
+
+func (g *accumulateGroup) scanExtremes() {
+	first := true
+	for _, val := range g.values {
+		if first || val < g.min {
+			g.min = val
+		}
+		if first || val > g.max {
+			g.max = val
+		}
+		first = false
+	}
+	g.extremesValid = true
+}
+
+func (g *accumulateGroup) getMin() float64 {
+	if !g.extremesValid {
+		g.scanExtremes()
+	}
+	return g.min
+}
+
+func (g *accumulateGroup) getMax() float64 {
+	if !g.extremesValid {
+		g.scanExtremes()
+	}
+	return g.max
+}
@@ -1,28 +1,65 @@
 package rete
 
 import (
+	"sort"
+
 	"github.com/project-flogo/rules/common/model"
 )
 
+//agendaItem is the model.Activation of a rule on the agenda
 type agendaItem interface {
-	getRule() model.Rule
-	getTuples() map[model.TupleType]model.Tuple
+	model.Activation
+	getHandles() []reteHandle
 }
 
 type agendaItemImpl struct {
 	rule     model.Rule
 	tupleMap map[model.TupleType]model.Tuple
+	handles  []reteHandle
+	recency  int
+
+	//the handles' recencies when activated, by identifier
+	tupleRecencies map[model.TupleType]int
 }
 
-func newAgendaItem(rule model.Rule, tupleMap map[model.TupleType]model.Tuple) agendaItem {
-	ai := agendaItemImpl{rule, tupleMap}
+func newAgendaItem(rule model.Rule, tupleMap map[model.TupleType]model.Tuple, handles []reteHandle, recency int) agendaItem {
+	ai := agendaItemImpl{rule, tupleMap, append([]reteHandle{}, handles...), recency, make(map[model.TupleType]int)}
+	for idr, tuple := range tupleMap {
+		for _, h := range handles {
+			if h.getTuple() == tuple {
+				ai.tupleRecencies[idr] = h.getRecency()
+				break
+			}
+		}
+	}
 	return &ai
 }
 
-func (ai *agendaItemImpl) getRule() model.Rule {
+func (ai *agendaItemImpl) GetRule() model.Rule {
 	return ai.rule
 }
 
-func (ai *agendaItemImpl) getTuples() map[model.TupleType]model.Tuple {
+func (ai *agendaItemImpl) GetTuples() map[model.TupleType]model.Tuple {
 	return ai.tupleMap
 }
+
+func (ai *agendaItemImpl) GetRecency() int {
+	return ai.recency
+}
+
+func (ai *agendaItemImpl) GetTupleRecency(idr model.TupleType) int {
+	return ai.tupleRecencies[idr]
+}
+
+func (ai *agendaItemImpl) GetTupleRecencies() []int {
+	recencies := make([]int, 0, len(ai.tupleRecencies))
+	for _, recency := range ai.tupleRecencies {
+		recencies = append(recencies, recency)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(recencies)))
+	return recencies
+}
+
+func (ai *agendaItemImpl) getHandles() []reteHandle {
+	return ai.handles
+}
@@ -76,11 +76,15 @@ func (cn *classNodeImpl) String() string {
 
 func (cn *classNodeImpl) assert(ctx context.Context, tuple model.Tuple, changedProps map[string]bool, forRule string) {
 	handle := getOrCreateHandle(ctx, tuple)
+	if forRule == "" {
+		handle.setRecency(getReteCtx(ctx).getNetwork().incrementAndGetRecency())
+	}
 	handles := make([]reteHandle, 1)
 	handles[0] = handle
-	propagate := false
 	for e := cn.getClassNodeLinks().Front(); e != nil; e = e.Next() {
 		classNodeLinkVar := e.Value.(classNodeLink)
+		//per link, a modification reaches only the rules depending on a changed property
+		propagate := false
 		if forRule != "" {
 			if classNodeLinkVar.getRule().GetName() != forRule {
 				continue
@@ -103,8 +107,5 @@ func (cn *classNodeImpl) assert(ctx context.Context, tuple model.Tuple, changedP
 		if propagate {
 			classNodeLinkVar.propagateObjects(ctx, handles)
 		}
-		if forRule != "" {
-			break
-		}
 	}
 }
@@ -3,64 +3,186 @@ package rete
 import (
 	"container/list"
 	"context"
+	"time"
 
 	"github.com/project-flogo/rules/common/model"
 )
 
 type conflictRes interface {
-	addAgendaItem(rule model.Rule, tupleMap map[model.TupleType]model.Tuple)
+	addAgendaItem(ctx context.Context, rule model.Rule, tupleMap map[model.TupleType]model.Tuple, handles []reteHandle)
+	removeAgendaItem(ctx context.Context, rule model.Rule, handles []reteHandle)
+	removeAgendaItemsFor(ctx context.Context, handle reteHandle)
 	resolveConflict(ctx context.Context)
 	deleteAgendaFor(ctx context.Context, tuple model.Tuple, changeProps map[string]bool)
+	deleteAgendaForRule(rule model.Rule)
+	setFocus(agendaGroup string)
+	getFocus() string
+	//getFocusStack returns the agenda groups pushed by setFocus, the focused one last
+	getFocusStack() []string
+	//getOutcome tells how the latest activation of the key ended, fired or cancelled and why, see
+	//activationKey
+	getOutcome(key string) (model.ActivationStatus, string)
 }
 
+//maxOutcomes is how many activations the agenda remembers the end of
+const maxOutcomes = 1024
+
+//conflictResImpl is the agenda of a network, activations of agenda groups without the focus stay
+//on it across RTCs until their group gets the focus
 type conflictResImpl struct {
-	agendaList list.List
+	nw Network
+	//agenda groups pushed by setFocus, model.MainAgendaGroup is always below them
+	focusStack []string
+	//how the latest activations ended by activationKey, the keys in the order they ended
+	outcomes    map[string]activationOutcome
+	outcomeKeys []string
+}
+
+type activationOutcome struct {
+	status model.ActivationStatus
+	reason string
 }
 
-func newConflictRes() conflictRes {
+func newConflictRes(nw Network) conflictRes {
 	cr := conflictResImpl{}
-	cr.initCR()
+	cr.initCR(nw)
 	return &cr
 }
 
-func (cr *conflictResImpl) initCR() {
-	cr.agendaList = list.List{}
+func (cr *conflictResImpl) initCR(nw Network) {
+	cr.nw = nw
+	cr.focusStack = []string{}
+	cr.outcomes = make(map[string]activationOutcome)
 }
 
-func (cr *conflictResImpl) addAgendaItem(rule model.Rule, tupleMap map[model.TupleType]model.Tuple) {
-	item := newAgendaItem(rule, tupleMap)
-	v := rule.GetPriority()
-	found := false
-	for e := cr.agendaList.Front(); e != nil; e = e.Next() {
-		curr := e.Value.(agendaItem)
-		if v <= curr.getRule().GetPriority() {
-			cr.agendaList.InsertBefore(item, e)
-			found = true
+func (cr *conflictResImpl) addAgendaItem(ctx context.Context, rule model.Rule, tupleMap map[model.TupleType]model.Tuple, handles []reteHandle) {
+	item := newAgendaItem(rule, tupleMap, handles, cr.nw.incrementAndGetRecency())
+	var mark *list.Element
+	for e := cr.nw.getStore().getAgenda().Front(); e != nil; e = e.Next() {
+		if cr.firesBefore(item, e.Value.(agendaItem)) {
+			mark = e
 			break
 		}
 	}
-	if !found {
-		cr.agendaList.PushBack(item)
+	cr.nw.getStore().insertAgendaItem(item, mark)
+	cr.nw.getMetrics().activationCreated(rule)
+	cr.nw.getListeners().activationCreated(ctx, item)
+}
+
+//firesBefore tells if the item goes before curr in the agenda, by default lower priorities first and
+//newer items before older ones of the same priority
+func (cr *conflictResImpl) firesBefore(item agendaItem, curr agendaItem) bool {
+	resolver := cr.nw.GetConflictResolver()
+	if resolver == nil {
+		return item.GetRule().GetPriority() <= curr.GetRule().GetPriority()
 	}
+	return resolver.Less(item, curr)
 }
 
-func (cr *conflictResImpl) resolveConflict(ctx context.Context) {
-	var item agendaItem
-
-	front := cr.agendaList.Front()
-	for front != nil {
-		val := cr.agendaList.Remove(front)
-		if val != nil {
-			item = val.(agendaItem)
-			actionTuples := item.getTuples()
-			actionFn := item.getRule().GetActionFn()
-			if actionFn != nil {
-				reteCtxV := getReteCtx(ctx)
-				actionFn(ctx, reteCtxV.getRuleSession(), item.getRule().GetName(), actionTuples, item.getRule().GetContext())
+func (cr *conflictResImpl) setFocus(agendaGroup string) {
+	if agendaGroup == "" || agendaGroup == cr.getFocus() {
+		return
+	}
+	cr.focusStack = append(cr.focusStack, agendaGroup)
+}
+
+func (cr *conflictResImpl) getFocus() string {
+	if len(cr.focusStack) == 0 {
+		return model.MainAgendaGroup
+	}
+	return cr.focusStack[len(cr.focusStack)-1]
+}
+
+func (cr *conflictResImpl) getFocusStack() []string {
+	return append([]string{}, cr.focusStack...)
+}
+
+//nextAgendaItem removes the first activation of the focused agenda group, the focus goes back to the
+//group below once it has none
+func (cr *conflictResImpl) nextAgendaItem() agendaItem {
+	for {
+		focus := cr.getFocus()
+		for e := cr.nw.getStore().getAgenda().Front(); e != nil; e = e.Next() {
+			item := e.Value.(agendaItem)
+			if item.GetRule().GetAgendaGroup() == focus {
+				cr.nw.getStore().removeAgendaItem(e)
+				return item
 			}
 		}
+		if len(cr.focusStack) == 0 {
+			return nil
+		}
+		cr.focusStack = cr.focusStack[:len(cr.focusStack)-1]
+	}
+}
+
+//cancel removes the activation from the agenda without firing it, for the reason, see model.CancelNoMatch
+func (cr *conflictResImpl) cancel(ctx context.Context, e *list.Element, reason string) {
+	cr.nw.getStore().removeAgendaItem(e)
+	cr.setOutcome(e.Value.(agendaItem), model.ActivationCancelled, reason)
+	cr.nw.getMetrics().activationCancelled(e.Value.(agendaItem).GetRule())
+	cr.nw.getListeners().activationCancelled(ctx, e.Value.(agendaItem))
+}
 
+//cancelActivationGroup removes the pending activations of the activation group
+func (cr *conflictResImpl) cancelActivationGroup(ctx context.Context, activationGroup string) {
+	for e := cr.nw.getStore().getAgenda().Front(); e != nil; {
+		next := e.Next()
+		if e.Value.(agendaItem).GetRule().GetActivationGroup() == activationGroup {
+			cr.cancel(ctx, e, model.CancelActivationGroup)
+		}
+		e = next
+	}
+}
+
+//removeAgendaItem removes the pending activation of the rule for exactly these handles
+func (cr *conflictResImpl) removeAgendaItem(ctx context.Context, rule model.Rule, handles []reteHandle) {
+	for e := cr.nw.getStore().getAgenda().Front(); e != nil; e = e.Next() {
+		item := e.Value.(agendaItem)
+		if item.GetRule() == rule && sameHandles(item.getHandles(), handles) {
+			cr.cancel(ctx, e, model.CancelNoMatch)
+			break
+		}
+	}
+}
+
+//removeAgendaItemsFor removes all pending activations holding the handle
+func (cr *conflictResImpl) removeAgendaItemsFor(ctx context.Context, handle reteHandle) {
+	for e := cr.nw.getStore().getAgenda().Front(); e != nil; {
+		next := e.Next()
+		for _, h := range e.Value.(agendaItem).getHandles() {
+			if h == handle {
+				cr.cancel(ctx, e, model.CancelNoMatch)
+				break
+			}
+		}
+		e = next
+	}
+}
+
+func (cr *conflictResImpl) resolveConflict(ctx context.Context) {
+	for item := cr.nextAgendaItem(); item != nil; item = cr.nextAgendaItem() {
+		if activationGroup := item.GetRule().GetActivationGroup(); activationGroup != "" {
+			cr.cancelActivationGroup(ctx, activationGroup)
+		}
 		reteCtxV := getReteCtx(ctx)
+		reteCtxV.setFiring(item)
+
+		actionTuples := item.GetTuples()
+		actionFn := item.GetRule().GetActionFn()
+		actionCtx := ctx
+		if cr.nw.GetExplain() {
+			explanation := cr.nw.explainActivation(ctx, item)
+			reteCtxV.addExplanation(*explanation)
+			actionCtx = model.WithExplanation(ctx, explanation)
+		}
+		cr.setOutcome(item, model.ActivationFired, "")
+		cr.nw.getListeners().beforeAction(ctx, item)
+		start := time.Now()
+		if actionFn != nil {
+			actionFn(actionCtx, reteCtxV.getRuleSession(), item.GetRule().GetName(), actionTuples, item.GetRule().GetContext())
+		}
+		cr.nw.getMetrics().activationFired(item.GetRule(), start)
 
 		reteCtxV.addRuleModifiedToOpsList()
 
@@ -77,8 +199,9 @@ func (cr *conflictResImpl) resolveConflict(ctx context.Context) {
 				opsFront = reteCtxV.getOpsList().Front()
 			}
 		}
-
-		front = cr.agendaList.Front()
+		//after the changes the action made
+		cr.nw.getListeners().afterAction(ctx, item)
+		reteCtxV.setFiring(nil)
 	}
 
 	reteCtxV := getReteCtx(ctx)
@@ -91,16 +214,16 @@ func (cr *conflictResImpl) deleteAgendaFor(ctx context.Context, modifiedTuple mo
 
 	hdlModified := getOrCreateHandle(ctx, modifiedTuple)
 
-	for e := cr.agendaList.Front(); e != nil; {
+	for e := cr.nw.getStore().getAgenda().Front(); e != nil; {
 		item := e.Value.(agendaItem)
 		next := e.Next()
-		for _, tuple := range item.getTuples() {
+		for _, tuple := range item.GetTuples() {
 			hdl := getOrCreateHandle(ctx, tuple)
 			if hdl == hdlModified { //this agendaitem has the modified tuple, remove the agenda item!
 				toRemove := true
 				//check if the rule depends on this change prop
 				if changeProps != nil {
-					if depProps, found := item.getRule().GetDeps()[tuple.GetTupleType()]; found {
+					if depProps, found := item.GetRule().GetDeps()[tuple.GetTupleType()]; found {
 						if len(depProps) > 0 {
 							for prop := range depProps {
 								if _, fnd := changeProps[prop]; fnd {
@@ -116,7 +239,7 @@ func (cr *conflictResImpl) deleteAgendaFor(ctx context.Context, modifiedTuple mo
 					}
 				}
 				if toRemove {
-					cr.agendaList.Remove(e)
+					cr.cancel(ctx, e, model.CancelTupleChanged)
 					break
 				}
 			}
@@ -125,3 +248,34 @@ func (cr *conflictResImpl) deleteAgendaFor(ctx context.Context, modifiedTuple mo
 	}
 
 }
+
+//deleteAgendaForRule removes the pending activations of a removed rule, outside of an RTC
+func (cr *conflictResImpl) deleteAgendaForRule(rule model.Rule) {
+	for e := cr.nw.getStore().getAgenda().Front(); e != nil; {
+		next := e.Next()
+		if e.Value.(agendaItem).GetRule() == rule {
+			cr.cancel(context.Background(), e, model.CancelRuleRemoved)
+		}
+		e = next
+	}
+}
+
+func (cr *conflictResImpl) setOutcome(item agendaItem, status model.ActivationStatus, reason string) {
+	key := activationKey(item.GetRule(), item.getHandles())
+	if _, found := cr.outcomes[key]; !found {
+		if len(cr.outcomeKeys) == maxOutcomes {
+			delete(cr.outcomes, cr.outcomeKeys[0])
+			cr.outcomeKeys = cr.outcomeKeys[1:]
+		}
+		cr.outcomeKeys = append(cr.outcomeKeys, key)
+	}
+	cr.outcomes[key] = activationOutcome{status, reason}
+}
+
+func (cr *conflictResImpl) getOutcome(key string) (model.ActivationStatus, string) {
+	outcome, found := cr.outcomes[key]
+	if !found {
+		return model.ActivationNone, ""
+	}
+	return outcome.status, outcome.reason
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 
 	"fmt"
+	"reflect"
+	"sync/atomic"
 
 	"github.com/project-flogo/rules/common/model"
 )
@@ -21,10 +23,12 @@ type reteCtx interface {
 	getRtcAdded() map[string]model.Tuple
 	getRtcModified() map[string]model.RtcModified
 	getRtcDeleted() map[string]model.Tuple
+	getRtcRetracted() map[string]model.Tuple
 
 	addToRtcAdded(tuple model.Tuple)
 	addToRtcModified(tuple model.Tuple)
 	addToRtcDeleted(tuple model.Tuple)
+	addToRtcRetracted(tuple model.Tuple)
 	addRuleModifiedToOpsList()
 
 	normalize()
@@ -32,11 +36,20 @@ type reteCtx interface {
 	resetModified()
 
 	printRtcChangeList()
+
+	//canActivate applies the rule's no-loop and lock-on-active, and the network's refraction
+	canActivate(rule model.Rule, handles []reteHandle) bool
+	//setFiring sets the activation whose action and ops are executing, nil once done
+	setFiring(item agendaItem)
+	getFiring() agendaItem
+	getRtcID() int64
+	//addExplanation records why an activation fired in the RTC, see Network.SetExplain
+	addExplanation(explanation model.Explanation)
+	getExplanations() []model.Explanation
 }
 
 //store any context, may not know all keys upfront
 type reteCtxImpl struct {
-	cr      conflictRes
 	opsList *list.List
 	network Network
 	rs      model.RuleSession
@@ -50,13 +63,26 @@ type reteCtxImpl struct {
 	//deleted (which is different than simply retracted) tuples in the current RTC (
 	deleteMap map[string]model.Tuple
 
+	//retracted, not deleted, tuples in the current RTC
+	retractMap map[string]model.Tuple
+
 	//modified tuples in the current RTC
 	rtcModifyMap map[string]model.RtcModified
+
+	firing agendaItem
+	//values of the dependent properties of the tuples of activations fired in the current RTC,
+	//by activation key and tuple key, see Network.SetRefraction
+	fired map[string]map[string]map[string]interface{}
+	rtcID int64
+	//the explanations of the activations fired in the RTC, in order
+	explanations []model.Explanation
 }
 
+//rtcIDs numbers the RTCs of the process
+var rtcIDs int64
+
 func newReteCtxImpl(network Network, rs model.RuleSession) reteCtx {
 	reteCtxVal := reteCtxImpl{}
-	reteCtxVal.cr = newConflictRes()
 	reteCtxVal.opsList = list.New()
 	reteCtxVal.network = network
 	reteCtxVal.rs = rs
@@ -64,11 +90,14 @@ func newReteCtxImpl(network Network, rs model.RuleSession) reteCtx {
 	reteCtxVal.modifyMap = make(map[string]model.RtcModified)
 	reteCtxVal.rtcModifyMap = make(map[string]model.RtcModified)
 	reteCtxVal.deleteMap = make(map[string]model.Tuple)
+	reteCtxVal.retractMap = make(map[string]model.Tuple)
+	reteCtxVal.fired = make(map[string]map[string]map[string]interface{})
+	reteCtxVal.rtcID = atomic.AddInt64(&rtcIDs, 1)
 	return &reteCtxVal
 }
 
 func (rctx *reteCtxImpl) getConflictResolver() conflictRes {
-	return rctx.cr
+	return rctx.network.getConflictRes()
 }
 
 func (rctx *reteCtxImpl) getOpsList() *list.List {
@@ -79,6 +108,18 @@ func (rctx *reteCtxImpl) getNetwork() Network {
 	return rctx.network
 }
 
+func (rctx *reteCtxImpl) getRtcID() int64 {
+	return rctx.rtcID
+}
+
+func (rctx *reteCtxImpl) addExplanation(explanation model.Explanation) {
+	rctx.explanations = append(rctx.explanations, explanation)
+}
+
+func (rctx *reteCtxImpl) getExplanations() []model.Explanation {
+	return rctx.explanations
+}
+
 func (rctx *reteCtxImpl) getRuleSession() model.RuleSession {
 	return rctx.rs
 }
@@ -90,9 +131,9 @@ func (rctx *reteCtxImpl) OnValueChange(tuple model.Tuple, prop string) {
 		rtcModified := rctx.modifyMap[tuple.GetKey().String()]
 		if rtcModified == nil {
 			rtcModified = NewRtcModified(tuple)
-			(rtcModified.(*rtcModifiedImpl)).addProp(prop)
 			rctx.modifyMap[tuple.GetKey().String()] = rtcModified
 		}
+		(rtcModified.(*rtcModifiedImpl)).addProp(prop)
 	}
 }
 
@@ -105,6 +146,9 @@ func (rctx *reteCtxImpl) getRtcModified() map[string]model.RtcModified {
 func (rctx *reteCtxImpl) getRtcDeleted() map[string]model.Tuple {
 	return rctx.deleteMap
 }
+func (rctx *reteCtxImpl) getRtcRetracted() map[string]model.Tuple {
+	return rctx.retractMap
+}
 
 func (rctx *reteCtxImpl) addToRtcAdded(tuple model.Tuple) {
 	rctx.addMap[tuple.GetKey().String()] = tuple
@@ -118,6 +162,10 @@ func (rctx *reteCtxImpl) addToRtcDeleted(tuple model.Tuple) {
 	rctx.deleteMap[tuple.GetKey().String()] = tuple
 }
 
+func (rctx *reteCtxImpl) addToRtcRetracted(tuple model.Tuple) {
+	rctx.retractMap[tuple.GetKey().String()] = tuple
+}
+
 func (rctx *reteCtxImpl) addRuleModifiedToOpsList() {
 	for _, rtcModified := range rctx.modifyMap {
 		rctx.getOpsList().PushBack(newModifyEntry(rtcModified.GetTuple(), rtcModified.GetModifiedProps()))
@@ -145,6 +193,64 @@ func (rctx *reteCtxImpl) resetModified() {
 	rctx.modifyMap = make(map[string]model.RtcModified)
 }
 
+func (rctx *reteCtxImpl) setFiring(item agendaItem) {
+	rctx.firing = item
+	if item != nil && rctx.network.GetRefraction() {
+		rctx.fired[activationKey(item.GetRule(), item.getHandles())] = depValues(item.GetRule(), item.getHandles())
+	}
+}
+
+func (rctx *reteCtxImpl) getFiring() agendaItem {
+	return rctx.firing
+}
+
+func (rctx *reteCtxImpl) canActivate(rule model.Rule, handles []reteHandle) bool {
+	if rctx.firing != nil {
+		if rule.GetNoLoop() && rctx.firing.GetRule() == rule {
+			return false
+		}
+		if rule.GetLockOnActive() && rctx.network.getConflictRes().getFocus() == rule.GetAgendaGroup() {
+			return false
+		}
+	}
+	if rctx.network.GetRefraction() {
+		firedValues, found := rctx.fired[activationKey(rule, handles)]
+		if found && reflect.DeepEqual(firedValues, depValues(rule, handles)) {
+			return false
+		}
+	}
+	return true
+}
+
+//activationKey identifies an activation by its rule and tuples, the same across modifications
+func activationKey(rule model.Rule, handles []reteHandle) string {
+	return activationKeyOf(rule.GetName(), handleKeys(handles))
+}
+
+//activationKeyOf is the activationKey of the rule for the tuples of the keys, in the order of the
+//identifiers of its rule node
+func activationKeyOf(ruleName string, tupleKeys []string) string {
+	key := ruleName
+	for _, tupleKey := range tupleKeys {
+		key += "|" + tupleKey
+	}
+	return key
+}
+
+//depValues are the values of the properties of the tuples the rule depends on, by tuple key
+func depValues(rule model.Rule, handles []reteHandle) map[string]map[string]interface{} {
+	values := make(map[string]map[string]interface{})
+	for _, h := range handles {
+		tuple := h.getTuple()
+		tupleValues := make(map[string]interface{})
+		for prop := range rule.GetDeps()[tuple.GetTupleType()] {
+			tupleValues[prop] = tuple.GetMap()[prop]
+		}
+		values[tuple.GetKey().String()] = tupleValues
+	}
+	return values
+}
+
 func (rctx *reteCtxImpl) printRtcChangeList() {
 	for k := range rctx.getRtcAdded() {
 
@@ -164,6 +270,16 @@ func (rctx *reteCtxImpl) printRtcChangeList() {
 
 }
 
+//InRtc tells if the context is the one of an RTC, such as the context of an action
+func InRtc(ctx context.Context) bool {
+	return ctx != nil && getReteCtx(ctx) != nil
+}
+
+//inRtcOf tells if the context is the one of an RTC of the network, whose lock it holds
+func inRtcOf(ctx context.Context, network Network) bool {
+	return InRtc(ctx) && getReteCtx(ctx).getNetwork() == network
+}
+
 func getReteCtx(ctx context.Context) reteCtx {
 	intr := ctx.Value(reteCTXKEY)
 	if intr == nil {
@@ -0,0 +1,96 @@
+package rete
+
+import (
+	"fmt"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+func (nw *reteNetworkImpl) Diagnose(ruleName string, keys []string) (model.Diagnosis, error) {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	rule := nw.allRules[ruleName]
+	if rule == nil {
+		return model.Diagnosis{}, fmt.Errorf("Rule not found [%s]", ruleName)
+	}
+	diagnosis := model.Diagnosis{Rule: ruleName, Tuples: map[model.TupleType]string{},
+		Conditions: []model.ConditionDiagnosis{}, Activation: model.ActivationNone}
+
+	//bind the tuples to the identifiers of their type, in the order of the keys
+	asserted := []model.Tuple{}
+	for _, key := range keys {
+		if tuple := nw.GetAssertedTupleByStringKey(key); tuple != nil {
+			asserted = append(asserted, tuple)
+		} else {
+			diagnosis.NotAsserted = append(diagnosis.NotAsserted, key)
+		}
+	}
+	tuples := map[model.TupleType]model.Tuple{}
+	for _, idr := range rule.GetIdentifiers() {
+		for i, tuple := range asserted {
+			if tuple != nil && tuple.GetTupleType() == rule.GetIdentifierType(idr) {
+				tuples[idr] = tuple
+				diagnosis.Tuples[idr] = tuple.GetKey().String()
+				asserted[i] = nil
+				break
+			}
+		}
+		if tuples[idr] == nil {
+			diagnosis.Unmatched = append(diagnosis.Unmatched, idr)
+		}
+	}
+
+	var ruleNode *ruleNodeImpl
+	for e := nw.ruleNameNodesOfRule[ruleName].Front(); e != nil; e = e.Next() {
+		n, ok := e.Value.(node)
+		if !ok {
+			continue
+		}
+		if rn, ok := n.(*ruleNodeImpl); ok {
+			ruleNode = rn
+		}
+		for _, cv := range nodeConditions(n) {
+			diagnosis.Conditions = append(diagnosis.Conditions, diagnoseCondition(rule, cv, nodeID(n), tuples))
+		}
+	}
+	if ruleNode == nil {
+		return diagnosis, nil
+	}
+
+	//the activation has the tuples of the identifiers of the rule node, in their order
+	tupleKeys := []string{}
+	for _, idr := range ruleNode.getIdentifiers() {
+		if tuples[idr] == nil {
+			return diagnosis, nil
+		}
+		tupleKeys = append(tupleKeys, tuples[idr].GetKey().String())
+	}
+	key := activationKeyOf(ruleName, tupleKeys)
+	for e := nw.store.getAgenda().Front(); e != nil; e = e.Next() {
+		item := e.Value.(agendaItem)
+		if activationKey(item.GetRule(), item.getHandles()) == key {
+			diagnosis.Activation = model.ActivationPending
+			return diagnosis, nil
+		}
+	}
+	diagnosis.Activation, diagnosis.CancelReason = nw.cr.getOutcome(key)
+	return diagnosis, nil
+}
+
+//diagnoseCondition evaluates the condition on the tuples, unless one of its identifiers is unmatched
+func diagnoseCondition(rule model.Rule, cv model.Condition, nodeID string, tuples map[model.TupleType]model.Tuple) model.ConditionDiagnosis {
+	cd := model.ConditionDiagnosis{Name: cv.GetName(), Expression: cv.String(), NodeID: nodeID,
+		Values: conditionValues(rule, cv, tuples)}
+	for _, idr := range cv.GetIdentifiers() {
+		if tuples[idr] == nil {
+			return cd
+		}
+	}
+	cd.Evaluated = true
+	pass, err := cv.Evaluate(cv.GetName(), rule.GetName(), tuples, cv.GetContext())
+	cd.Passed = err == nil && pass
+	if err != nil {
+		cd.Error = err.Error()
+	}
+	return cd
+}
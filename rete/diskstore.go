@@ -0,0 +1,233 @@
+package rete
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/project-flogo/rules/common/kvstore"
+	"github.com/project-flogo/rules/common/model"
+)
+
+//NewDiskStore returns a store writing the working memory through to a kvstore file: the tuples by key,
+//the rows of each join table by their tuple keys and the activations by recency. The network works on
+//the handles, rows and activations kept in memory, those of a file opened again are rebuilt from its
+//tuples and activations, see Network.SetStore. The file makes the working memory durable, it does not
+//page it: the working memory still has to fit in memory
+func NewDiskStore(path string) (WorkingMemoryStore, error) {
+	s := diskStoreImpl{}
+	err := s.initDiskStoreImpl(path)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+//the records of the file
+type tupleRecord struct {
+	TupleType model.TupleType        `json:"type"`
+	Values    map[string]interface{} `json:"values"`
+	//the order the tuples were put in, that they are asserted again in
+	Order      int64     `json:"order"`
+	AssertedAt time.Time `json:"assertedAt"`
+	//the TTL of the tuple in milliseconds, if it expires
+	TTL *int64 `json:"ttl,omitempty"`
+}
+
+type activationRecord struct {
+	Rule string   `json:"rule"`
+	Keys []string `json:"keys"`
+}
+
+const (
+	handlePrefix     = "h/"
+	rowPrefix        = "r/"
+	activationPrefix = "a/"
+)
+
+type diskStoreImpl struct {
+	memoryStoreImpl
+	kv kvstore.KVStore
+	//the order of the next tuple put
+	order int64
+	//the keys of the records load read and not written again since, see loaded
+	stale map[string]bool
+	//the first error writing since the last sync, returned by sync or Close
+	err error
+}
+
+func (s *diskStoreImpl) initDiskStoreImpl(path string) error {
+	s.initMemoryStoreImpl()
+	kv, err := kvstore.Open(path)
+	if err != nil {
+		return err
+	}
+	s.kv = kv
+	s.stale = make(map[string]bool)
+	return nil
+}
+
+func (s *diskStoreImpl) load() (NetworkState, error) {
+	state := NetworkState{Expiries: map[string]time.Time{}}
+	records := []tupleRecord{}
+	for _, key := range s.kv.Keys(handlePrefix) {
+		record := tupleRecord{}
+		if err := s.getRecord(key, &record); err != nil {
+			return state, err
+		}
+		records = append(records, record)
+		s.stale[key] = true
+		if record.Order >= s.order {
+			s.order = record.Order + 1
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Order < records[j].Order
+	})
+	for _, record := range records {
+		tuple, err := model.NewTuple(record.TupleType, record.Values)
+		if err != nil {
+			return state, err
+		}
+		if record.TTL != nil {
+			ttl := time.Duration(*record.TTL) * time.Millisecond
+			model.SetTTL(tuple, ttl)
+			state.Expiries[tuple.GetKey().String()] = record.AssertedAt.Add(ttl)
+		}
+		state.Tuples = append(state.Tuples, tuple)
+	}
+	for _, key := range s.kv.Keys(rowPrefix) {
+		s.stale[key] = true
+	}
+	//by recency, see agendaRecordKey
+	for _, key := range s.kv.Keys(activationPrefix) {
+		record := activationRecord{}
+		if err := s.getRecord(key, &record); err != nil {
+			return state, err
+		}
+		state.Agenda = append(state.Agenda, ActivationState{record.Rule, record.Keys})
+		s.stale[key] = true
+	}
+	return state, nil
+}
+
+func (s *diskStoreImpl) loaded() error {
+	for key := range s.stale {
+		if err := s.kv.Delete(key); err != nil {
+			return err
+		}
+	}
+	s.stale = make(map[string]bool)
+	return nil
+}
+
+func (s *diskStoreImpl) putHandle(key string, handle reteHandle) {
+	s.memoryStoreImpl.putHandle(key, handle)
+	tuple := handle.getTuple()
+	record := tupleRecord{TupleType: tuple.GetTupleType(), Values: tuple.GetMap(), Order: s.order,
+		AssertedAt: model.GetAssertionTime(tuple)}
+	s.order++
+	if ttl := model.GetTTL(tuple); ttl > 0 {
+		ms := int64(ttl / time.Millisecond)
+		record.TTL = &ms
+	}
+	s.put(handlePrefix+key, record)
+}
+
+func (s *diskStoreImpl) deleteHandle(key string) {
+	s.memoryStoreImpl.deleteHandle(key)
+	s.delete(handlePrefix + key)
+}
+
+func (s *diskStoreImpl) addRow(jt joinTable, row joinTableRow) {
+	s.memoryStoreImpl.addRow(jt, row)
+	s.put(rowKey(jt, row), handleKeys(row.getHandles()))
+}
+
+func (s *diskStoreImpl) removeRow(jt joinTable, row joinTableRow) {
+	s.memoryStoreImpl.removeRow(jt, row)
+	s.delete(rowKey(jt, row))
+}
+
+func (s *diskStoreImpl) removeRows(jt joinTable) {
+	s.memoryStoreImpl.removeRows(jt)
+	s.setErr(s.kv.DeletePrefix(fmt.Sprintf("%s%d/", rowPrefix, jt.getID())))
+}
+
+func (s *diskStoreImpl) insertAgendaItem(item agendaItem, mark *list.Element) {
+	s.memoryStoreImpl.insertAgendaItem(item, mark)
+	s.put(agendaRecordKey(item), activationRecord{item.GetRule().GetName(), handleKeys(item.getHandles())})
+}
+
+func (s *diskStoreImpl) removeAgendaItem(e *list.Element) {
+	s.memoryStoreImpl.removeAgendaItem(e)
+	s.delete(agendaRecordKey(e.Value.(agendaItem)))
+}
+
+//sync flushes and fsyncs the records of the RTC
+func (s *diskStoreImpl) sync() error {
+	err := s.err
+	s.err = nil
+	if syncErr := s.kv.Sync(); err == nil {
+		err = syncErr
+	}
+	return err
+}
+
+func (s *diskStoreImpl) Close() error {
+	if err := s.kv.Close(); s.err == nil {
+		s.err = err
+	}
+	return s.err
+}
+
+func (s *diskStoreImpl) getRecord(key string, record interface{}) error {
+	value, _, err := s.kv.Get(key)
+	if err == nil {
+		err = json.Unmarshal(value, record)
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot read record [%s]: %s", key, err)
+	}
+	return nil
+}
+
+func (s *diskStoreImpl) put(key string, record interface{}) {
+	delete(s.stale, key)
+	value, err := json.Marshal(record)
+	if err == nil {
+		err = s.kv.Put(key, value)
+	}
+	s.setErr(err)
+}
+
+func (s *diskStoreImpl) delete(key string) {
+	delete(s.stale, key)
+	s.setErr(s.kv.Delete(key))
+}
+
+func (s *diskStoreImpl) setErr(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+func handleKeys(handles []reteHandle) []string {
+	keys := make([]string, len(handles))
+	for i, h := range handles {
+		keys[i] = h.getTuple().GetKey().String()
+	}
+	return keys
+}
+
+func rowKey(jt joinTable, row joinTableRow) string {
+	return fmt.Sprintf("%s%d/%s", rowPrefix, jt.getID(), strings.Join(handleKeys(row.getHandles()), "\x00"))
+}
+
+//agendaRecordKey orders the activations by recency in the file
+func agendaRecordKey(item agendaItem) string {
+	return fmt.Sprintf("%s%020d", activationPrefix, item.GetRecency())
+}
@@ -0,0 +1,166 @@
+package rete
+
+import (
+	"container/heap"
+	"context"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+//expiries deletes the tuples whose TTL is over, in batches, from a single timer
+type expiries interface {
+	//schedule deletes the tuple once the ttl has passed
+	schedule(ctx context.Context, tuple model.Tuple, ttl time.Duration)
+	//remove forgets a retracted tuple
+	remove(tuple model.Tuple)
+	//getDeadline returns when the tuple expires, if it does
+	getDeadline(tuple model.Tuple) (time.Time, bool)
+	//expire deletes the tuples whose TTL is over, and asserts the tuples telling so, see
+	//model.ExpiredTupleType. It returns the latter, to retract once the RTC is over
+	expire(ctx context.Context) []model.Tuple
+	//stop cancels the timer, the tuples no longer expire
+	stop()
+}
+
+type expiryEntry struct {
+	tuple    model.Tuple
+	deadline time.Time
+	//position in the heap
+	index int
+}
+
+//expiryHeap orders the entries by deadline
+type expiryHeap []*expiryEntry
+
+func (h expiryHeap) Len() int {
+	return len(h)
+}
+
+func (h expiryHeap) Less(i, j int) bool {
+	return h[i].deadline.Before(h[j].deadline)
+}
+
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x interface{}) {
+	entry := x.(*expiryEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
+
+type expiriesImpl struct {
+	nw      Network
+	pending expiryHeap
+	//the entries of the pending tuples, by tuple key
+	entries map[string]*expiryEntry
+	//expires the tuples at the earliest deadline
+	timer    model.Timer
+	deadline time.Time
+	stopped  bool
+}
+
+func newExpiries(nw Network) expiries {
+	e := expiriesImpl{}
+	e.initExpiriesImpl(nw)
+	return &e
+}
+
+func (e *expiriesImpl) initExpiriesImpl(nw Network) {
+	e.nw = nw
+	e.entries = make(map[string]*expiryEntry)
+}
+
+func (e *expiriesImpl) schedule(ctx context.Context, tuple model.Tuple, ttl time.Duration) {
+	if e.stopped {
+		return
+	}
+	e.remove(tuple)
+	entry := &expiryEntry{tuple: tuple, deadline: e.nw.GetClock().Now().Add(ttl)}
+	heap.Push(&e.pending, entry)
+	e.entries[tuple.GetKey().String()] = entry
+	e.reschedule(getReteCtx(ctx).getRuleSession())
+}
+
+func (e *expiriesImpl) remove(tuple model.Tuple) {
+	key := tuple.GetKey().String()
+	entry := e.entries[key]
+	if entry == nil {
+		return
+	}
+	delete(e.entries, key)
+	//the timer is left as is, it reschedules once it fires
+	heap.Remove(&e.pending, entry.index)
+}
+
+func (e *expiriesImpl) getDeadline(tuple model.Tuple) (time.Time, bool) {
+	entry := e.entries[tuple.GetKey().String()]
+	if entry == nil {
+		return time.Time{}, false
+	}
+	return entry.deadline, true
+}
+
+func (e *expiriesImpl) expire(ctx context.Context) []model.Tuple {
+	if e.stopped {
+		return nil
+	}
+	e.timer = nil
+	now := e.nw.GetClock().Now()
+	expired := []model.Tuple{}
+	for len(e.pending) > 0 && !e.pending[0].deadline.After(now) {
+		entry := heap.Pop(&e.pending).(*expiryEntry)
+		delete(e.entries, entry.tuple.GetKey().String())
+		if h := e.nw.getHandle(entry.tuple); h != nil && h.getTuple() == entry.tuple {
+			e.nw.getListeners().tupleExpired(ctx, entry.tuple)
+			newDeleteEntry(entry.tuple, DELETE, nil).execute(ctx)
+			if tuple, err := model.NewExpiredTuple(entry.tuple); err == nil {
+				newAssertEntry(tuple, nil, ADD).execute(ctx)
+				expired = append(expired, tuple)
+			}
+		}
+	}
+	e.reschedule(getReteCtx(ctx).getRuleSession())
+	return expired
+}
+
+//reschedule sets the timer to the earliest deadline, unless it is due to fire before
+func (e *expiriesImpl) reschedule(rs model.RuleSession) {
+	if e.stopped || len(e.pending) == 0 {
+		return
+	}
+	deadline := e.pending[0].deadline
+	if e.timer != nil && !deadline.Before(e.deadline) {
+		return
+	}
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	clock := e.nw.GetClock()
+	e.deadline = deadline
+	e.timer = clock.AfterFunc(deadline.Sub(clock.Now()), func() {
+		e.nw.expireTuples(rs)
+	})
+}
+
+func (e *expiriesImpl) stop() {
+	e.stopped = true
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	e.pending = nil
+	e.entries = make(map[string]*expiryEntry)
+}
@@ -0,0 +1,131 @@
+package rete
+
+import (
+	"context"
+	"sort"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+func (nw *reteNetworkImpl) SetExplain(explain bool) {
+	nw.explain = explain
+}
+
+func (nw *reteNetworkImpl) GetExplain() bool {
+	return nw.explain
+}
+
+//explainActivation returns the explanation of the activation about to fire
+func (nw *reteNetworkImpl) explainActivation(ctx context.Context, item agendaItem) *model.Explanation {
+	rule := item.GetRule()
+	tuples := item.GetTuples()
+	explanation := model.Explanation{Rule: rule.GetName(), Tuples: map[model.TupleType]string{}, Path: []model.ExplanationNode{}}
+	if reteCtxVar := getReteCtx(ctx); reteCtxVar != nil {
+		explanation.RtcID = reteCtxVar.getRtcID()
+	}
+	for idr, tuple := range tuples {
+		explanation.Tuples[idr] = tuple.GetKey().String()
+	}
+
+	//the inputs of each node, the left ones first
+	inputs := map[string][]string{}
+	rightInputs := map[string][]string{}
+	addInput := func(from string, to string, right bool) {
+		if right {
+			rightInputs[to] = append(rightInputs[to], from)
+		} else {
+			inputs[to] = append(inputs[to], from)
+		}
+	}
+	classNames := []string{}
+	listed := map[string]bool{}
+	for e := nw.ruleNameClassNodeLinksOfRule[rule.GetName()].Front(); e != nil; e = e.Next() {
+		cnl := e.Value.(classNodeLink)
+		name := cnl.getClassNode().getName()
+		if !listed[name] {
+			listed[name] = true
+			classNames = append(classNames, name)
+		}
+		addInput(classNodeID(name), nodeID(cnl.getChild()), cnl.isRightNode())
+	}
+	nodes := []node{}
+	for e := nw.ruleNameNodesOfRule[rule.GetName()].Front(); e != nil; e = e.Next() {
+		if n, ok := e.Value.(node); ok {
+			nodes = append(nodes, n)
+			if nl := nodeLinkOf(n); nl != nil && nl.getChild() != nil {
+				addInput(nodeID(n), nodeID(nl.getChild()), nl.isRightNode())
+			}
+		}
+	}
+
+	sort.Strings(classNames)
+	for _, name := range classNames {
+		explanation.Path = append(explanation.Path, model.ExplanationNode{ID: classNodeID(name), Type: ClassGraphNode,
+			Identifiers: []model.TupleType{model.TupleType(name)}})
+	}
+	for _, n := range nodes {
+		gn := graphNode(n, rule.GetName(), false)
+		en := model.ExplanationNode{ID: gn.ID, Type: gn.Type, Identifiers: gn.Identifiers, GroupType: gn.GroupType,
+			Inputs: append(inputs[gn.ID], rightInputs[gn.ID]...)}
+		for _, cv := range nodeConditions(n) {
+			en.Conditions = append(en.Conditions, model.ConditionExplanation{Name: cv.GetName(), Expression: cv.String(),
+				Values: conditionValues(rule, cv, tuples)})
+		}
+		if accumulateNode, ok := n.(*accumulateNodeImpl); ok {
+			en.Conditions = append(en.Conditions, model.ConditionExplanation{Name: accumulateNode.accumulate.GetName(),
+				Expression: accumulateNode.accumulate.String()})
+		}
+		explanation.Path = append(explanation.Path, en)
+	}
+	return &explanation
+}
+
+func nodeConditions(n node) []model.Condition {
+	switch nodeImpl := n.(type) {
+	case *filterNodeImpl:
+		if nodeImpl.conditionVar != nil {
+			return []model.Condition{nodeImpl.conditionVar}
+		}
+	case *joinNodeImpl:
+		if nodeImpl.conditionVar != nil {
+			return []model.Condition{nodeImpl.conditionVar}
+		}
+	case *groupNodeImpl:
+		return nodeImpl.conditions
+	}
+	return nil
+}
+
+//conditionValues returns the values of the properties the condition reads, of the tuples bound
+func conditionValues(rule model.Rule, cv model.Condition, tuples map[model.TupleType]model.Tuple) map[model.TupleType]map[string]interface{} {
+	props := map[model.TupleType][]string{}
+	if pc, ok := cv.(model.PropertyCondition); ok {
+		props = pc.GetProperties()
+	} else {
+		for _, idr := range cv.GetIdentifiers() {
+			for prop := range rule.GetDeps()[rule.GetIdentifierType(idr)] {
+				props[idr] = append(props[idr], prop)
+			}
+		}
+	}
+	values := map[model.TupleType]map[string]interface{}{}
+	for idr, idrProps := range props {
+		tuple := tuples[idr]
+		if tuple == nil {
+			continue
+		}
+		tupleValues := map[string]interface{}{}
+		for _, prop := range idrProps {
+			if value, found := tuple.GetMap()[prop]; found {
+				tupleValues[prop] = value
+			}
+		}
+		if len(tupleValues) > 0 {
+			values[idr] = tupleValues
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
@@ -15,7 +15,6 @@ type filterNode interface {
 type filterNodeImpl struct {
 	nodeImpl
 	conditionVar model.Condition
-	convert      []int
 }
 
 func newFilterNode(nw Network, rule model.Rule, identifiers []model.TupleType, conditionVar model.Condition) filterNode {
@@ -27,27 +26,6 @@ func newFilterNode(nw Network, rule model.Rule, identifiers []model.TupleType, c
 func (fn *filterNodeImpl) initFilterNodeImpl(nw Network, rule model.Rule, identifiers []model.TupleType, conditionVar model.Condition) {
 	fn.nodeImpl.initNodeImpl(nw, rule, identifiers)
 	fn.conditionVar = conditionVar
-	fn.setConvert()
-}
-
-func (fn *filterNodeImpl) setConvert() {
-
-	if fn.conditionVar == nil {
-		return
-	}
-	conIdrs := fn.conditionVar.GetIdentifiers()
-
-	if conIdrs != nil && len(conIdrs) == 0 {
-		for i, condIdr := range conIdrs {
-			idx := GetIndex(fn.identifiers, condIdr)
-			if idx != -1 {
-				fn.convert[i] = idx
-				// } else {
-				//TODO ERROR HANDLING
-			}
-		}
-	}
-
 }
 
 func (fn *filterNodeImpl) String() string {
@@ -64,10 +42,18 @@ func (fn *filterNodeImpl) String() string {
 		} else {
 			linkTo += "j" + strconv.Itoa(fn.nodeLinkVar.getChild().getID()) + "L"
 		}
+	case *groupNodeImpl:
+		if fn.nodeLinkVar.isRightNode() {
+			linkTo += "n" + strconv.Itoa(fn.nodeLinkVar.getChild().getID()) + "R"
+		} else {
+			linkTo += "n" + strconv.Itoa(fn.nodeLinkVar.getChild().getID()) + "L"
+		}
 	case *filterNodeImpl:
 		linkTo += "f" + strconv.Itoa(fn.nodeLinkVar.getChild().getID())
 	case *ruleNodeImpl:
 		linkTo += "r" + strconv.Itoa(fn.nodeLinkVar.getChild().getID())
+	case *accumulateNodeImpl:
+		linkTo += "a" + strconv.Itoa(fn.nodeLinkVar.getChild().getID())
 	}
 
 	return "\t[FilterNode id(" + strconv.Itoa(fn.nodeImpl.id) + ") link(" + linkTo + "):\n" +
@@ -81,20 +67,9 @@ func (fn *filterNodeImpl) assertObjects(ctx context.Context, handles []reteHandl
 		fn.nodeLinkVar.propagateObjects(ctx, handles)
 	} else {
 		//TODO: rete listeners...
-		var tuples []model.Tuple
-		// tupleMap := map[model.TupleType]model.Tuple{}
-		if fn.convert == nil {
-			tuples = copyIntoTupleArray(handles)
-		} else {
-			tuples = make([]model.Tuple, len(fn.convert))
-			for i := 0; i < len(fn.convert); i++ {
-				tuples[i] = handles[fn.convert[i]].getTuple()
-				// tupleMap[tuples[i].GetTupleType()] = tuples[i]
-			}
-		}
-		tupleMap := convertToTupleMap(tuples)
+		tupleMap := copyIntoTupleMap(fn.identifiers, handles)
 		cv := fn.conditionVar
-		toPropagate, err := cv.Evaluate(cv.GetName(), cv.GetRule().GetName(), tupleMap, cv.GetContext())
+		toPropagate, err := fn.evaluate(cv, tupleMap)
 		if err == nil {
 			if toPropagate {
 				fn.nodeLinkVar.propagateObjects(ctx, handles)
@@ -104,3 +79,8 @@ func (fn *filterNodeImpl) assertObjects(ctx context.Context, handles []reteHandl
 		}
 	}
 }
+
+//a filter node holds no state, nodes downstream ignore handles they never got
+func (fn *filterNodeImpl) retractObjects(ctx context.Context, handles []reteHandle, isRight bool) {
+	fn.nodeLinkVar.retractObjects(ctx, handles)
+}
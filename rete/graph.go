@@ -0,0 +1,264 @@
+package rete
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+//Graph is the rete network as its nodes and the links between them, see Network.GetGraph
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Links []GraphLink `json:"links"`
+}
+
+//GraphNode types
+const (
+	ClassGraphNode      = "class"
+	FilterGraphNode     = "filter"
+	JoinGraphNode       = "join"
+	GroupGraphNode      = "group"
+	AccumulateGraphNode = "accumulate"
+	RuleGraphNode       = "rule"
+	QueryGraphNode      = "query"
+)
+
+//GraphNode is a node of the network, class nodes are shared by the rules
+type GraphNode struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	//the tuple type of a class node, the rule or live query of the others
+	Name        string            `json:"name"`
+	Identifiers []model.TupleType `json:"identifiers,omitempty"`
+	//the inputs of a join or group node
+	LeftIdentifiers  []model.TupleType `json:"leftIdentifiers,omitempty"`
+	RightIdentifiers []model.TupleType `json:"rightIdentifiers,omitempty"`
+	//the conditions the node evaluates, or the accumulate it computes
+	Conditions []string `json:"conditions,omitempty"`
+	//how a group node combines its conditions
+	GroupType model.ConditionGroupType `json:"groupType,omitempty"`
+	//the rows of the node's join tables, only if asked for
+	LeftRows  *int `json:"leftRows,omitempty"`
+	RightRows *int `json:"rightRows,omitempty"`
+}
+
+//GraphLink passes the tuples of a node to the next one
+type GraphLink struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	//the identifier of the tuples a class node passes
+	Identifier model.TupleType `json:"identifier,omitempty"`
+	//whether the link goes to the right input of a join or group node
+	Right bool `json:"right,omitempty"`
+}
+
+func (nw *reteNetworkImpl) GetGraph(ctx context.Context, withRows bool) Graph {
+	//an action runs with the lock held
+	if !inRtcOf(ctx, nw) {
+		nw.assertLock.Lock()
+		defer nw.assertLock.Unlock()
+	}
+
+	g := Graph{Nodes: []GraphNode{}, Links: []GraphLink{}}
+	classNames := []string{}
+	for name := range nw.allClassNodes {
+		classNames = append(classNames, name)
+	}
+	sort.Strings(classNames)
+	for _, name := range classNames {
+		g.Nodes = append(g.Nodes, GraphNode{ID: classNodeID(name), Type: ClassGraphNode, Name: name})
+	}
+
+	ruleNames := []string{}
+	for name := range nw.allRules {
+		ruleNames = append(ruleNames, name)
+	}
+	for name := range nw.liveQueries {
+		ruleNames = append(ruleNames, name)
+	}
+	sort.Strings(ruleNames)
+	for _, ruleName := range ruleNames {
+		for e := nw.ruleNameClassNodeLinksOfRule[ruleName].Front(); e != nil; e = e.Next() {
+			cnl := e.Value.(classNodeLink)
+			g.Links = append(g.Links, GraphLink{From: classNodeID(cnl.getClassNode().getName()),
+				To: nodeID(cnl.getChild()), Identifier: cnl.GetIdentifier(), Right: cnl.isRightNode()})
+		}
+		for e := nw.ruleNameNodesOfRule[ruleName].Front(); e != nil; e = e.Next() {
+			n, ok := e.Value.(node)
+			if !ok {
+				continue
+			}
+			g.Nodes = append(g.Nodes, graphNode(n, ruleName, withRows))
+			switch nodeImpl := n.(type) {
+			case *ruleNodeImpl, *queryNodeImpl:
+			case *accumulateNodeImpl:
+				//its results are asserted as tuples of the accumulate's type
+				g.Links = append(g.Links, GraphLink{From: nodeID(n), To: classNodeID(nodeImpl.classNodeVar.getName())})
+			default:
+				nl := nodeLinkOf(n)
+				if nl != nil && nl.getChild() != nil {
+					g.Links = append(g.Links, GraphLink{From: nodeID(n), To: nodeID(nl.getChild()), Right: nl.isRightNode()})
+				}
+			}
+		}
+	}
+	return g
+}
+
+func graphNode(n node, ruleName string, withRows bool) GraphNode {
+	gn := GraphNode{ID: nodeID(n), Name: ruleName, Identifiers: n.getIdentifiers()}
+	var left, right joinTable
+	switch nodeImpl := n.(type) {
+	case *filterNodeImpl:
+		gn.Type = FilterGraphNode
+		gn.Conditions = conditionStrings(nodeImpl.conditionVar)
+	case *joinNodeImpl:
+		gn.Type = JoinGraphNode
+		gn.LeftIdentifiers = nodeImpl.leftIdrs
+		gn.RightIdentifiers = nodeImpl.rightIdrs
+		gn.Conditions = conditionStrings(nodeImpl.conditionVar)
+		left, right = nodeImpl.leftTable, nodeImpl.rightTable
+	case *groupNodeImpl:
+		gn.Type = GroupGraphNode
+		gn.LeftIdentifiers = nodeImpl.leftIdrs
+		gn.RightIdentifiers = nodeImpl.rightIdrs
+		gn.GroupType = nodeImpl.groupType
+		gn.Conditions = conditionStrings(nodeImpl.conditions...)
+		left, right = nodeImpl.leftTable, nodeImpl.rightTable
+	case *accumulateNodeImpl:
+		gn.Type = AccumulateGraphNode
+		gn.Conditions = []string{nodeImpl.accumulate.String()}
+		left = nodeImpl.table
+	case *ruleNodeImpl:
+		gn.Type = RuleGraphNode
+	case *queryNodeImpl:
+		gn.Type = QueryGraphNode
+	}
+	if withRows && left != nil {
+		leftRows := left.len()
+		gn.LeftRows = &leftRows
+	}
+	if withRows && right != nil {
+		rightRows := right.len()
+		gn.RightRows = &rightRows
+	}
+	return gn
+}
+
+func nodeLinkOf(n node) nodeLink {
+	switch nodeImpl := n.(type) {
+	case *filterNodeImpl:
+		return nodeImpl.nodeLinkVar
+	case *joinNodeImpl:
+		return nodeImpl.nodeLinkVar
+	case *groupNodeImpl:
+		return nodeImpl.nodeLinkVar
+	}
+	return nil
+}
+
+func conditionStrings(conditions ...model.Condition) []string {
+	strs := []string{}
+	for _, conditionVar := range conditions {
+		if conditionVar != nil {
+			strs = append(strs, conditionVar.String())
+		}
+	}
+	return strs
+}
+
+func classNodeID(name string) string {
+	return "class:" + name
+}
+
+func nodeID(n node) string {
+	return "n" + strconv.Itoa(n.getID())
+}
+
+//WriteJSON writes the graph as indented JSON
+func (g Graph) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(g)
+}
+
+//WriteDOT writes the graph in the Graphviz DOT language, a cluster of nodes per rule
+func (g Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph rete {\n\trankdir=TB;\n")
+	clusters := map[string][]GraphNode{}
+	ruleNames := []string{}
+	for _, n := range g.Nodes {
+		if n.Type == ClassGraphNode {
+			fmt.Fprintf(&b, "\t%s [shape=box, style=filled, fillcolor=lightgrey, label=%s];\n", strconv.Quote(n.ID), strconv.Quote(n.Name))
+			continue
+		}
+		if clusters[n.Name] == nil {
+			ruleNames = append(ruleNames, n.Name)
+		}
+		clusters[n.Name] = append(clusters[n.Name], n)
+	}
+	for i, ruleName := range ruleNames {
+		fmt.Fprintf(&b, "\tsubgraph cluster_%d {\n\t\tlabel=%s;\n", i, strconv.Quote(ruleName))
+		for _, n := range clusters[ruleName] {
+			shape := "ellipse"
+			if n.Type == RuleGraphNode {
+				shape = "doubleoctagon"
+			} else if n.Type == QueryGraphNode {
+				shape = "octagon"
+			}
+			fmt.Fprintf(&b, "\t\t%s [shape=%s, label=%s];\n", strconv.Quote(n.ID), shape, strconv.Quote(dotLabel(n)))
+		}
+		b.WriteString("\t}\n")
+	}
+	for _, l := range g.Links {
+		label := string(l.Identifier)
+		if l.Right {
+			label = strings.TrimSpace(label + " R")
+		}
+		if label == "" {
+			fmt.Fprintf(&b, "\t%s -> %s;\n", strconv.Quote(l.From), strconv.Quote(l.To))
+		} else {
+			fmt.Fprintf(&b, "\t%s -> %s [label=%s];\n", strconv.Quote(l.From), strconv.Quote(l.To), strconv.Quote(label))
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotLabel(n GraphNode) string {
+	label := n.Type + " " + n.ID
+	if n.GroupType != "" {
+		label += " " + string(n.GroupType)
+	}
+	if len(n.LeftIdentifiers) > 0 || len(n.RightIdentifiers) > 0 {
+		label += "\n" + joinIdentifiers(n.LeftIdentifiers) + " | " + joinIdentifiers(n.RightIdentifiers)
+	} else if len(n.Identifiers) > 0 {
+		label += "\n" + joinIdentifiers(n.Identifiers)
+	}
+	for _, cond := range n.Conditions {
+		label += "\n" + cond
+	}
+	if n.LeftRows != nil {
+		label += "\nrows: " + strconv.Itoa(*n.LeftRows)
+		if n.RightRows != nil {
+			label += " | " + strconv.Itoa(*n.RightRows)
+		}
+	}
+	return label
+}
+
+func joinIdentifiers(idrs []model.TupleType) string {
+	strs := make([]string, len(idrs))
+	for i, idr := range idrs {
+		strs[i] = string(idr)
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,191 @@
+package rete
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+//groupNode propagates the left handles while the right handles matching them satisfy the group's
+//quantifier; none for model.NotGroup, at least one for model.ExistsGroup
+type groupNode interface {
+	node
+}
+
+type groupNodeImpl struct {
+	nodeImpl
+	groupType  model.ConditionGroupType
+	conditions []model.Condition
+
+	leftIdrs  []model.TupleType
+	rightIdrs []model.TupleType
+	//the left identifiers followed by the right ones, binding the handles the conditions see
+	joinedIdrs []model.TupleType
+
+	leftTable  joinTable
+	rightTable joinTable
+
+	//right rows matching a left row and vice versa
+	leftMatches  map[joinTableRow]map[joinTableRow]bool
+	rightMatches map[joinTableRow]map[joinTableRow]bool
+}
+
+func newGroupNode(nw Network, rule model.Rule, groupType model.ConditionGroupType, leftIdrs []model.TupleType, rightIdrs []model.TupleType, conditions []model.Condition) groupNode {
+	nn := groupNodeImpl{}
+	nn.initGroupNodeImpl(nw, rule, groupType, leftIdrs, rightIdrs, conditions)
+	return &nn
+}
+
+func (nn *groupNodeImpl) initGroupNodeImpl(nw Network, rule model.Rule, groupType model.ConditionGroupType, leftIdrs []model.TupleType, rightIdrs []model.TupleType, conditions []model.Condition) {
+	nn.initNodeImpl(nw, rule, leftIdrs)
+	nn.groupType = groupType
+	nn.leftIdrs = leftIdrs
+	nn.rightIdrs = rightIdrs
+	nn.joinedIdrs = append(append([]model.TupleType{}, leftIdrs...), rightIdrs...)
+	nn.conditions = conditions
+	nn.leftTable = newJoinTable(nw, rule, leftIdrs)
+	nn.rightTable = newJoinTable(nw, rule, rightIdrs)
+	nn.leftTable.setListener(nn)
+	nn.rightTable.setListener(nn)
+	nn.leftMatches = make(map[joinTableRow]map[joinTableRow]bool)
+	nn.rightMatches = make(map[joinTableRow]map[joinTableRow]bool)
+
+	leftIdxs, leftProps, rightIdxs, rightProps := getEquiJoinIndexes(conditions, leftIdrs, rightIdrs)
+	if len(leftIdxs) > 0 {
+		nn.leftTable.setIndex(leftIdxs, leftProps)
+		nn.rightTable.setIndex(rightIdxs, rightProps)
+	}
+}
+
+func (nn *groupNodeImpl) String() string {
+	linkTo := ""
+	switch nn.nodeLinkVar.getChild().(type) {
+	case *joinNodeImpl, *groupNodeImpl:
+		if nn.nodeLinkVar.isRightNode() {
+			linkTo += strconv.Itoa(nn.nodeLinkVar.getChild().getID()) + "R"
+		} else {
+			linkTo += strconv.Itoa(nn.nodeLinkVar.getChild().getID()) + "L"
+		}
+	default:
+		linkTo += strconv.Itoa(nn.nodeLinkVar.getChild().getID())
+	}
+
+	conditionsStr := ""
+	for _, conditionVar := range nn.conditions {
+		conditionsStr += conditionVar.String() + " "
+	}
+	return "\t[GroupNode(" + nn.nodeImpl.String() + ") type(" + string(nn.groupType) + ") link(" + linkTo + ")\n" +
+		"\t\tLeft model.TupleType      = " + model.IdentifiersToString(nn.leftIdrs) + ";\n" +
+		"\t\tRight model.TupleType     = " + model.IdentifiersToString(nn.rightIdrs) + ";\n" +
+		"\t\tConditions           = " + conditionsStr + "]\n"
+}
+
+func (nn *groupNodeImpl) assertObjects(ctx context.Context, handles []reteHandle, isRight bool) {
+	row := newJoinTableRow(handles)
+	if isRight {
+		nn.rightTable.addRow(row)
+		nn.rightMatches[row] = make(map[joinTableRow]bool)
+		for leftRow := range nn.leftTable.getRowsForKey(nn.rightTable.getIndexKey(handles)) {
+			if nn.matches(leftRow.getHandles(), handles) {
+				nn.addMatch(leftRow, row)
+				if len(nn.leftMatches[leftRow]) == 1 {
+					//first match
+					nn.changed(ctx, leftRow)
+				}
+			}
+		}
+	} else {
+		nn.leftTable.addRow(row)
+		nn.leftMatches[row] = make(map[joinTableRow]bool)
+		for rightRow := range nn.rightTable.getRowsForKey(nn.leftTable.getIndexKey(handles)) {
+			if nn.matches(handles, rightRow.getHandles()) {
+				nn.addMatch(row, rightRow)
+			}
+		}
+		if nn.passes(row) {
+			nn.nodeLinkVar.propagateObjects(ctx, handles)
+		}
+	}
+}
+
+func (nn *groupNodeImpl) retractObjects(ctx context.Context, handles []reteHandle, isRight bool) {
+	table := nn.leftTable
+	if isRight {
+		table = nn.rightTable
+	}
+	row := table.findRow(handles)
+	if row == nil {
+		return
+	}
+	table.removeRow(row)
+	nn.removed(ctx, row, isRight, true)
+}
+
+//rowRetracted implements joinTableListener, called when a tuple of the row got retracted or modified
+func (nn *groupNodeImpl) rowRetracted(ctx context.Context, jt joinTable, row joinTableRow) {
+	//rows downstream of a left row hold its handles as well and are removed along with it
+	nn.removed(ctx, row, jt == nn.rightTable, false)
+}
+
+func (nn *groupNodeImpl) removed(ctx context.Context, row joinTableRow, isRight bool, propagate bool) {
+	if isRight {
+		for leftRow := range nn.rightMatches[row] {
+			delete(nn.leftMatches[leftRow], row)
+			if len(nn.leftMatches[leftRow]) == 0 {
+				//last match gone
+				nn.changed(ctx, leftRow)
+			}
+		}
+		delete(nn.rightMatches, row)
+	} else {
+		passed := nn.passes(row)
+		for rightRow := range nn.leftMatches[row] {
+			delete(nn.rightMatches[rightRow], row)
+		}
+		delete(nn.leftMatches, row)
+		if propagate && passed {
+			nn.nodeLinkVar.retractObjects(ctx, row.getHandles())
+		}
+	}
+}
+
+//passes tells if the left row satisfies the group's quantifier given its current matches
+func (nn *groupNodeImpl) passes(leftRow joinTableRow) bool {
+	if nn.groupType == model.ExistsGroup {
+		return len(nn.leftMatches[leftRow]) > 0
+	}
+	return len(nn.leftMatches[leftRow]) == 0
+}
+
+//changed propagates or withdraws the left row after its matches went from none to some or back
+func (nn *groupNodeImpl) changed(ctx context.Context, leftRow joinTableRow) {
+	if nn.passes(leftRow) {
+		nn.nodeLinkVar.propagateObjects(ctx, leftRow.getHandles())
+	} else {
+		nn.nodeLinkVar.retractObjects(ctx, leftRow.getHandles())
+	}
+}
+
+func (nn *groupNodeImpl) addMatch(leftRow joinTableRow, rightRow joinTableRow) {
+	nn.leftMatches[leftRow][rightRow] = true
+	nn.rightMatches[rightRow][leftRow] = true
+}
+
+func (nn *groupNodeImpl) matches(leftHandles []reteHandle, rightHandles []reteHandle) bool {
+	handles := appendHandles(leftHandles, rightHandles)
+	if !nn.rule.GetSelfMatch() && hasSelfMatch(handles) {
+		return false
+	}
+	if len(nn.conditions) == 0 {
+		return true
+	}
+	tupleMap := copyIntoTupleMap(nn.joinedIdrs, handles)
+	for _, cv := range nn.conditions {
+		pass, err := nn.evaluate(cv, tupleMap)
+		if err != nil || !pass {
+			return false
+		}
+	}
+	return true
+}
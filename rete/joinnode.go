@@ -44,6 +44,7 @@ func (jn *joinNodeImpl) initjoinNodeImplVar(nw Network, rule model.Rule, leftIdr
 	jn.leftTable = newJoinTable(nw, rule, leftIdrs)
 	jn.rightTable = newJoinTable(nw, rule, rightIdrs)
 	jn.setJoinIdentifiers()
+	jn.setEquiJoins()
 }
 
 func (jn *joinNodeImpl) GetLeftIdentifiers() []model.TupleType {
@@ -111,6 +112,46 @@ func (jn *joinNodeImpl) setJoinIdentifiers() {
 	// }
 }
 
+//setEquiJoins indexes both join tables on the equi-join properties of the join condition, if any,
+//so that an assert only looks up the matching rows of the opposite table
+func (jn *joinNodeImpl) setEquiJoins() {
+	if jn.conditionVar == nil {
+		return
+	}
+	conditions := []model.Condition{jn.conditionVar}
+	leftIdxs, leftProps, rightIdxs, rightProps := getEquiJoinIndexes(conditions, jn.leftIdrs, jn.rightIdrs)
+	if len(leftIdxs) > 0 {
+		jn.leftTable.setIndex(leftIdxs, leftProps)
+		jn.rightTable.setIndex(rightIdxs, rightProps)
+	}
+}
+
+//getEquiJoinIndexes returns the index positions and properties of the conditions' equi-joins
+//that have one identifier on the left and the other on the right
+func getEquiJoinIndexes(conditions []model.Condition, leftIdrs []model.TupleType, rightIdrs []model.TupleType) (leftIdxs []int, leftProps []string, rightIdxs []int, rightProps []string) {
+	equiJoins := []model.EquiJoin{}
+	for _, conditionVar := range conditions {
+		if equiJoinCondition, ok := conditionVar.(model.EquiJoinCondition); ok {
+			equiJoins = append(equiJoins, equiJoinCondition.GetEquiJoins()...)
+		}
+	}
+	for _, equiJoin := range equiJoins {
+		left, leftProp := GetIndex(leftIdrs, equiJoin.Left), equiJoin.LeftProp
+		right, rightProp := GetIndex(rightIdrs, equiJoin.Right), equiJoin.RightProp
+		if left == -1 || right == -1 {
+			left, leftProp = GetIndex(leftIdrs, equiJoin.Right), equiJoin.RightProp
+			right, rightProp = GetIndex(rightIdrs, equiJoin.Left), equiJoin.LeftProp
+		}
+		if left != -1 && right != -1 {
+			leftIdxs = append(leftIdxs, left)
+			leftProps = append(leftProps, leftProp)
+			rightIdxs = append(rightIdxs, right)
+			rightProps = append(rightProps, rightProp)
+		}
+	}
+	return
+}
+
 //String Stringer.String interface
 func (jn *joinNodeImpl) String() string {
 
@@ -136,6 +177,11 @@ func (jn *joinNodeImpl) String() string {
 		linkTo += strconv.Itoa(jn.nodeLinkVar.getChild().getID())
 	}
 
+	indexStr := "nil"
+	if jn.leftTable.isIndexed() {
+		indexStr = "hash"
+	}
+
 	joinConditionStr := "nil"
 	joinConditionIdrsStr := "nil"
 	if jn.conditionVar != nil {
@@ -149,6 +195,7 @@ func (jn *joinNodeImpl) String() string {
 		"\t\tCondition model.TupleType = " + joinConditionIdrsStr + ";\n" +
 		"\t\tJoin Left Index      = " + joinIdsForLeftStr + ";\n" +
 		"\t\tJoin Right Index     = " + joinIdsForRightStr + ";\n" +
+		"\t\tJoin Table Index     = " + indexStr + ";\n" +
 		"\t\tCondition            = " + joinConditionStr + "]\n"
 }
 
@@ -171,19 +218,22 @@ func (jn *joinNodeImpl) assertFromRight(ctx context.Context, handles []reteHandl
 	tupleTableRow := newJoinTableRow(handles)
 	jn.rightTable.addRow(tupleTableRow)
 	//TODO: rete listeners etc.
-	for tupleTableRowLeft := range jn.leftTable.getMap() {
+	for tupleTableRowLeft := range jn.leftTable.getRowsForKey(jn.rightTable.getIndexKey(handles)) {
 		success := jn.joinLeftObjects(tupleTableRowLeft.getHandles(), joinedHandles)
 		if !success {
 			//TODO: handle it
 			continue
 		}
+		if !jn.rule.GetSelfMatch() && hasSelfMatch(joinedHandles) {
+			continue
+		}
 		toPropagate := false
 		if jn.conditionVar == nil {
 			toPropagate = true
 		} else {
-			tupleMap := copyIntoTupleMap(joinedHandles)
+			tupleMap := copyIntoTupleMap(jn.identifiers, joinedHandles)
 			cv := jn.conditionVar
-			toPropagate, _ = cv.Evaluate(cv.GetName(), cv.GetRule().GetName(), tupleMap, cv.GetContext())
+			toPropagate, _ = jn.evaluate(cv, tupleMap)
 			// if err != nil {
 			// 	//todo handling error
 			// }
@@ -225,19 +275,22 @@ func (jn *joinNodeImpl) assertFromLeft(ctx context.Context, handles []reteHandle
 	tupleTableRow := newJoinTableRow(handles)
 	jn.leftTable.addRow(tupleTableRow)
 	//TODO: rete listeners etc.
-	for tupleTableRowRight := range jn.rightTable.getMap() {
+	for tupleTableRowRight := range jn.rightTable.getRowsForKey(jn.leftTable.getIndexKey(handles)) {
 		success := jn.joinRightObjects(tupleTableRowRight.getHandles(), joinedHandles)
 		if !success {
 			//TODO: handle it
 			continue
 		}
+		if !jn.rule.GetSelfMatch() && hasSelfMatch(joinedHandles) {
+			continue
+		}
 		toPropagate := false
 		if jn.conditionVar == nil {
 			toPropagate = true
 		} else {
-			tupleMap := copyIntoTupleMap(joinedHandles)
+			tupleMap := copyIntoTupleMap(jn.identifiers, joinedHandles)
 			cv := jn.conditionVar
-			toPropagate, _ = cv.Evaluate(cv.GetName(), cv.GetRule().GetName(), tupleMap, cv.GetContext())
+			toPropagate, _ = jn.evaluate(cv, tupleMap)
 			// if err != nil {
 			// 	//todo handling error
 			// }
@@ -247,3 +300,27 @@ func (jn *joinNodeImpl) assertFromLeft(ctx context.Context, handles []reteHandle
 		}
 	}
 }
+
+func (jn *joinNodeImpl) retractObjects(ctx context.Context, handles []reteHandle, isRight bool) {
+	table, otherTable := jn.leftTable, jn.rightTable
+	joinObjects, joinOtherObjects := jn.joinLeftObjects, jn.joinRightObjects
+	if isRight {
+		table, otherTable = jn.rightTable, jn.leftTable
+		joinObjects, joinOtherObjects = jn.joinRightObjects, jn.joinLeftObjects
+	}
+	row := table.findRow(handles)
+	if row == nil {
+		return
+	}
+	//the row's tuples may have been modified since it was indexed, probe with the key it was indexed with
+	key := table.getRowKey(row)
+	table.removeRow(row)
+
+	joinedHandles := make([]reteHandle, jn.totalIdrLen)
+	joinObjects(handles, joinedHandles)
+	for otherRow := range otherTable.getRowsForKey(key) {
+		if joinOtherObjects(otherRow.getHandles(), joinedHandles) {
+			jn.nodeLinkVar.retractObjects(ctx, joinedHandles)
+		}
+	}
+}
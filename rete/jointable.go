@@ -1,6 +1,10 @@
 package rete
 
-import "github.com/project-flogo/rules/common/model"
+import (
+	"context"
+
+	"github.com/project-flogo/rules/common/model"
+)
 
 type joinTable interface {
 	addRow(row joinTableRow) //list of Tuples
@@ -9,13 +13,45 @@ type joinTable interface {
 	getMap() map[joinTableRow]joinTableRow
 	removeRow(row joinTableRow)
 	getRule() model.Rule
+	//clear forgets the rows, the table's rule got removed
+	clear()
+
+	//find the row holding exactly these handles, nil if none
+	findRow(handles []reteHandle) joinTableRow
+	//remove a row because one of its tuples got retracted or modified, and notify the listener
+	retractRow(ctx context.Context, row joinTableRow)
+	setListener(listener joinTableListener)
+
+	//hash index on equi-join properties, see joinNodeImpl.setEquiJoins
+	setIndex(idrIdxs []int, props []string)
+	isIndexed() bool
+	getIndexKey(handles []reteHandle) string
+	//getRowKey returns the index key the row was added with, its tuples may have changed since
+	getRowKey(row joinTableRow) string
+	getRowsForKey(key string) map[joinTableRow]joinTableRow
+}
+
+//joinTableListener is notified of rows retracted from a join table other than by its own node,
+//see handleImpl.removeJoinTableRowRefs
+type joinTableListener interface {
+	rowRetracted(ctx context.Context, jt joinTable, row joinTableRow)
 }
 
 type joinTableImpl struct {
-	id    int
-	table map[joinTableRow]joinTableRow
-	idr   []model.TupleType
-	rule  model.Rule
+	id int
+	//the rows are in the network's store
+	nw       Network
+	idr      []model.TupleType
+	rule     model.Rule
+	listener joinTableListener
+
+	//index position of the identifier in a row's handles and the property of that identifier to hash on
+	idxIdrs  []int
+	idxProps []string
+	//rows bucketed by the values of the index properties
+	buckets map[string]map[joinTableRow]joinTableRow
+	//the key a row was bucketed with, its tuples may have changed since
+	rowKeys map[joinTableRow]string
 }
 
 func newJoinTable(nw Network, rule model.Rule, identifiers []model.TupleType) joinTable {
@@ -26,8 +62,8 @@ func newJoinTable(nw Network, rule model.Rule, identifiers []model.TupleType) jo
 
 func (jt *joinTableImpl) initJoinTableImpl(nw Network, rule model.Rule, identifiers []model.TupleType) {
 	jt.id = nw.incrementAndGetId()
+	jt.nw = nw
 	jt.idr = identifiers
-	jt.table = map[joinTableRow]joinTableRow{}
 	jt.rule = rule
 }
 
@@ -36,25 +72,124 @@ func (jt *joinTableImpl) getID() int {
 }
 
 func (jt *joinTableImpl) addRow(row joinTableRow) {
-	jt.table[row] = row
+	jt.nw.getStore().addRow(jt, row)
 	for i := 0; i < len(row.getHandles()); i++ {
 		handle := row.getHandles()[i]
 		handle.addJoinTableRowRef(row, jt)
 	}
+	if jt.isIndexed() {
+		key := jt.getIndexKey(row.getHandles())
+		bucket, found := jt.buckets[key]
+		if !found {
+			bucket = map[joinTableRow]joinTableRow{}
+			jt.buckets[key] = bucket
+		}
+		bucket[row] = row
+		jt.rowKeys[row] = key
+	}
 }
 
 func (jt *joinTableImpl) removeRow(row joinTableRow) {
-	delete(jt.table, row)
+	if _, found := jt.getMap()[row]; !found {
+		return
+	}
+	jt.nw.getStore().removeRow(jt, row)
+	for _, handle := range row.getHandles() {
+		handle.removeJoinTableRowRef(row, jt)
+	}
+	if jt.isIndexed() {
+		key, found := jt.rowKeys[row]
+		if found {
+			delete(jt.rowKeys, row)
+			bucket := jt.buckets[key]
+			delete(bucket, row)
+			if len(bucket) == 0 {
+				delete(jt.buckets, key)
+			}
+		}
+	}
+}
+
+func (jt *joinTableImpl) findRow(handles []reteHandle) joinTableRow {
+	if len(handles) == 0 {
+		return nil
+	}
+	rows := handles[0].getJoinTableRows(jt)
+	if rows == nil {
+		return nil
+	}
+	for e := rows.Front(); e != nil; e = e.Next() {
+		row := e.Value.(joinTableRow)
+		if sameHandles(row.getHandles(), handles) {
+			return row
+		}
+	}
+	return nil
+}
+
+func (jt *joinTableImpl) retractRow(ctx context.Context, row joinTableRow) {
+	if _, found := jt.getMap()[row]; !found {
+		return
+	}
+	jt.removeRow(row)
+	if jt.listener != nil {
+		jt.listener.rowRetracted(ctx, jt, row)
+	}
+}
+
+func (jt *joinTableImpl) setListener(listener joinTableListener) {
+	jt.listener = listener
 }
 
 func (jt *joinTableImpl) len() int {
-	return len(jt.table)
+	return len(jt.getMap())
 }
 
 func (jt *joinTableImpl) getMap() map[joinTableRow]joinTableRow {
-	return jt.table
+	return jt.nw.getStore().getRows(jt)
+}
+
+func (jt *joinTableImpl) clear() {
+	jt.nw.getStore().removeRows(jt)
 }
 
 func (jt *joinTableImpl) getRule() model.Rule {
 	return jt.rule
 }
+
+func (jt *joinTableImpl) setIndex(idrIdxs []int, props []string) {
+	jt.idxIdrs = idrIdxs
+	jt.idxProps = props
+	jt.buckets = make(map[string]map[joinTableRow]joinTableRow)
+	jt.rowKeys = make(map[joinTableRow]string)
+}
+
+func (jt *joinTableImpl) isIndexed() bool {
+	return jt.idxIdrs != nil
+}
+
+//getIndexKey computes the index key for handles ordered as per this table's identifiers
+func (jt *joinTableImpl) getIndexKey(handles []reteHandle) string {
+	key := ""
+	for i, idrIdx := range jt.idxIdrs {
+		if i > 0 {
+			key += "|"
+		}
+		key += model.ValueKey(handles[idrIdx].getTuple().GetMap()[jt.idxProps[i]])
+	}
+	return key
+}
+
+func (jt *joinTableImpl) getRowKey(row joinTableRow) string {
+	if key, found := jt.rowKeys[row]; found {
+		return key
+	}
+	return jt.getIndexKey(row.getHandles())
+}
+
+func (jt *joinTableImpl) getRowsForKey(key string) map[joinTableRow]joinTableRow {
+	if !jt.isIndexed() {
+		return jt.getMap()
+	}
+	return jt.buckets[key]
+}
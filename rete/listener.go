@@ -0,0 +1,158 @@
+package rete
+
+import (
+	"context"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+//listeners are the listeners of a network, replaced rather than changed so that an RTC keeps
+//calling those it started with. Its methods do nothing outside of an RTC
+type listeners []model.RuleSessionListener
+
+func (nw *reteNetworkImpl) AddListener(listener model.RuleSessionListener) {
+	nw.listenerLock.Lock()
+	defer nw.listenerLock.Unlock()
+	nw.listeners = append(append(listeners{}, nw.listeners...), listener)
+}
+
+func (nw *reteNetworkImpl) RemoveListener(listener model.RuleSessionListener) {
+	nw.listenerLock.Lock()
+	defer nw.listenerLock.Unlock()
+	for i, l := range nw.listeners {
+		if l == listener {
+			nw.listeners = append(append(listeners{}, nw.listeners[:i]...), nw.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+func (nw *reteNetworkImpl) getListeners() listeners {
+	nw.listenerLock.Lock()
+	defer nw.listenerLock.Unlock()
+	return nw.listeners
+}
+
+//startRtc tells the listeners an RTC starts, and returns the time it does
+func (nw *reteNetworkImpl) startRtc(ctx context.Context) time.Time {
+	if ls := nw.getListeners(); len(ls) > 0 {
+		reteCtxVar := getReteCtx(ctx)
+		for _, l := range ls {
+			l.OnRtcStart(ctx, reteCtxVar.getRuleSession(), model.RtcEvent{RtcID: reteCtxVar.getRtcID()})
+		}
+	}
+	return time.Now()
+}
+
+//endRtc syncs the store, and tells the live queries, the listeners and the metrics the RTC started at
+//start is over
+func (nw *reteNetworkImpl) endRtc(ctx context.Context, start time.Time) {
+	if err := nw.store.sync(); err != nil && nw.storeErr == nil {
+		nw.storeErr = err
+	}
+	nw.flushLiveQueries(ctx)
+	nw.metrics.rtcDone(start)
+	if ls := nw.getListeners(); len(ls) > 0 {
+		reteCtxVar := getReteCtx(ctx)
+		for _, l := range ls {
+			l.OnRtcEnd(ctx, reteCtxVar.getRuleSession(), model.RtcEvent{RtcID: reteCtxVar.getRtcID()})
+		}
+	}
+}
+
+func (ls listeners) tupleAsserted(ctx context.Context, tuple model.Tuple) {
+	rs, event := ls.tupleEvent(ctx, tuple)
+	for _, l := range ls {
+		l.OnTupleAsserted(ctx, rs, event)
+	}
+}
+
+func (ls listeners) tupleRetracted(ctx context.Context, tuple model.Tuple, deleted bool) {
+	rs, event := ls.tupleEvent(ctx, tuple)
+	event.Deleted = deleted
+	for _, l := range ls {
+		l.OnTupleRetracted(ctx, rs, event)
+	}
+}
+
+func (ls listeners) tupleModified(ctx context.Context, tuple model.Tuple, modifiedProps map[string]bool) {
+	rs, event := ls.tupleEvent(ctx, tuple)
+	event.ModifiedProps = modifiedProps
+	for _, l := range ls {
+		l.OnTupleModified(ctx, rs, event)
+	}
+}
+
+func (ls listeners) tupleExpired(ctx context.Context, tuple model.Tuple) {
+	rs, event := ls.tupleEvent(ctx, tuple)
+	for _, l := range ls {
+		l.OnTupleExpired(ctx, rs, event)
+	}
+}
+
+func (ls listeners) activationCreated(ctx context.Context, item agendaItem) {
+	rs, event := ls.activationEvent(ctx, item)
+	for _, l := range ls {
+		l.OnActivationCreated(ctx, rs, event)
+	}
+}
+
+func (ls listeners) activationCancelled(ctx context.Context, item agendaItem) {
+	rs, event := ls.activationEvent(ctx, item)
+	for _, l := range ls {
+		l.OnActivationCancelled(ctx, rs, event)
+	}
+}
+
+func (ls listeners) beforeAction(ctx context.Context, item agendaItem) {
+	rs, event := ls.activationEvent(ctx, item)
+	for _, l := range ls {
+		l.OnBeforeAction(ctx, rs, event)
+	}
+}
+
+func (ls listeners) afterAction(ctx context.Context, item agendaItem) {
+	rs, event := ls.activationEvent(ctx, item)
+	for _, l := range ls {
+		l.OnAfterAction(ctx, rs, event)
+	}
+}
+
+//tupleEvent returns the session and the event of the tuple, the listeners are dropped if not in an RTC
+func (ls *listeners) tupleEvent(ctx context.Context, tuple model.Tuple) (model.RuleSession, model.TupleEvent) {
+	reteCtxVar := ls.reteCtx(ctx)
+	if reteCtxVar == nil {
+		return nil, model.TupleEvent{}
+	}
+	event := model.TupleEvent{RtcID: reteCtxVar.getRtcID(), Tuple: tuple}
+	if firing := reteCtxVar.getFiring(); firing != nil {
+		event.RuleName = firing.GetRule().GetName()
+	}
+	return reteCtxVar.getRuleSession(), event
+}
+
+//activationEvent returns the session and the event of the activation, the listeners are dropped if
+//not in an RTC
+func (ls *listeners) activationEvent(ctx context.Context, item agendaItem) (model.RuleSession, model.ActivationEvent) {
+	reteCtxVar := ls.reteCtx(ctx)
+	if reteCtxVar == nil {
+		return nil, model.ActivationEvent{}
+	}
+	return reteCtxVar.getRuleSession(), model.ActivationEvent{RtcID: reteCtxVar.getRtcID(),
+		RuleName: item.GetRule().GetName(), Tuples: item.GetTuples()}
+}
+
+func (ls *listeners) reteCtx(ctx context.Context) reteCtx {
+	if len(*ls) == 0 {
+		return nil
+	}
+	var reteCtxVar reteCtx
+	if ctx != nil {
+		reteCtxVar = getReteCtx(ctx)
+	}
+	if reteCtxVar == nil {
+		*ls = nil
+	}
+	return reteCtxVar
+}
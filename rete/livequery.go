@@ -0,0 +1,249 @@
+package rete
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+//queryRule is the rule a live query is built into the network as, it has no action and is never activated
+type queryRule struct {
+	model.Query
+	//params are those of the query's $param[<name>]s, see model.QueryContext
+	params map[string]interface{}
+}
+
+func (qr *queryRule) GetActionFn() model.ActionFunction {
+	return nil
+}
+
+func (qr *queryRule) GetPriority() int {
+	return 0
+}
+
+func (qr *queryRule) GetContext() model.RuleContext {
+	return nil
+}
+
+func (qr *queryRule) GetConditionGroups() []model.ConditionGroup {
+	return nil
+}
+
+func (qr *queryRule) GetAccumulates() []model.Accumulate {
+	return nil
+}
+
+func (qr *queryRule) GetAgendaGroup() string {
+	return model.MainAgendaGroup
+}
+
+func (qr *queryRule) GetActivationGroup() string {
+	return ""
+}
+
+func (qr *queryRule) GetNoLoop() bool {
+	return false
+}
+
+func (qr *queryRule) GetLockOnActive() bool {
+	return false
+}
+
+//queryNode the leaf node of the network of a live query, in place of a rule node. It keeps the results
+//of the query, and tells its listener how they changed at the end of each RTC
+type queryNode interface {
+	node
+	//tupleRetracted withdraws the results of the tuple, unless only properties the query does not depend on changed
+	tupleRetracted(tuple model.Tuple, changedProps map[string]bool)
+	//flush tells the listener of the results changed in the RTC
+	flush(ctx context.Context)
+}
+
+type queryNodeImpl struct {
+	nodeImpl
+	rule     *queryRule
+	listener model.LiveQueryListener
+	//the results by the keys of their tuples, see resultKey
+	results map[string]model.QueryResult
+	//the keys of the results of each tuple, by tuple key
+	resultsOfTuple map[string]map[string]bool
+	//the results changed in the RTC as they were at its start, nil for those not in the results then
+	changed map[string]model.QueryResult
+	//the keys of the results changed, in the order they first changed
+	changedKeys []string
+}
+
+func newQueryNode(nw Network, rule *queryRule, identifiers []model.TupleType, listener model.LiveQueryListener) queryNode {
+	qn := queryNodeImpl{}
+	qn.initQueryNodeImpl(nw, rule, identifiers, listener)
+	return &qn
+}
+
+func (qn *queryNodeImpl) initQueryNodeImpl(nw Network, rule *queryRule, identifiers []model.TupleType, listener model.LiveQueryListener) {
+	qn.nodeImpl.initNodeImpl(nw, rule, identifiers)
+	qn.rule = rule
+	qn.listener = listener
+	qn.results = make(map[string]model.QueryResult)
+	qn.resultsOfTuple = make(map[string]map[string]bool)
+	qn.changed = make(map[string]model.QueryResult)
+}
+
+func (qn *queryNodeImpl) String() string {
+	return "\t[QueryNode id(" + strconv.Itoa(qn.id) + "): \n" +
+		"\t\tIdentifier           = " + model.IdentifiersToString(qn.identifiers) + " ;\n" +
+		"\t\tQuery                = " + qn.rule.GetName() + "]\n"
+}
+
+func (qn *queryNodeImpl) assertObjects(ctx context.Context, handles []reteHandle, isRight bool) {
+	key := resultKey(handles)
+	qn.change(key)
+	qn.results[key] = copyIntoTupleMap(qn.identifiers, handles)
+	for _, h := range handles {
+		tupleKey := h.getTuple().GetKey().String()
+		if qn.resultsOfTuple[tupleKey] == nil {
+			qn.resultsOfTuple[tupleKey] = map[string]bool{}
+		}
+		qn.resultsOfTuple[tupleKey][key] = true
+	}
+}
+
+func (qn *queryNodeImpl) retractObjects(ctx context.Context, handles []reteHandle, isRight bool) {
+	qn.remove(resultKey(handles))
+}
+
+func (qn *queryNodeImpl) tupleRetracted(tuple model.Tuple, changedProps map[string]bool) {
+	withdraw := changedProps == nil
+	for prop := range changedProps {
+		if qn.rule.GetDeps()[tuple.GetTupleType()][prop] {
+			withdraw = true
+			break
+		}
+	}
+	for key := range qn.resultsOfTuple[tuple.GetKey().String()] {
+		if withdraw {
+			qn.remove(key)
+		} else {
+			qn.change(key)
+		}
+	}
+}
+
+func (qn *queryNodeImpl) remove(key string) {
+	result, found := qn.results[key]
+	if !found {
+		return
+	}
+	qn.change(key)
+	delete(qn.results, key)
+	for _, tuple := range result {
+		tupleKey := tuple.GetKey().String()
+		delete(qn.resultsOfTuple[tupleKey], key)
+		if len(qn.resultsOfTuple[tupleKey]) == 0 {
+			delete(qn.resultsOfTuple, tupleKey)
+		}
+	}
+}
+
+//change remembers the result as it was at the start of the RTC, before it first changes in it
+func (qn *queryNodeImpl) change(key string) {
+	if _, found := qn.changed[key]; !found {
+		qn.changed[key] = qn.results[key]
+		qn.changedKeys = append(qn.changedKeys, key)
+	}
+}
+
+func (qn *queryNodeImpl) flush(ctx context.Context) {
+	changed, changedKeys := qn.changed, qn.changedKeys
+	qn.changed = make(map[string]model.QueryResult)
+	qn.changedKeys = nil
+
+	reteCtxVar := getReteCtx(ctx)
+	rs := reteCtxVar.getRuleSession()
+	for _, key := range changedKeys {
+		before, after := changed[key], qn.results[key]
+		event := model.LiveQueryEvent{RtcID: reteCtxVar.getRtcID(), Query: qn.rule.GetName(), Result: after}
+		switch {
+		case before == nil && after != nil:
+			qn.listener.OnResultAdded(ctx, rs, event)
+		case before != nil && after != nil:
+			qn.listener.OnResultUpdated(ctx, rs, event)
+		case before != nil:
+			event.Result = before
+			qn.listener.OnResultRemoved(ctx, rs, event)
+		}
+	}
+}
+
+//resultKey identifies a result by the keys of its tuples, in the order of the identifiers of the query node
+func resultKey(handles []reteHandle) string {
+	return strings.Join(handleKeys(handles), "|")
+}
+
+func (nw *reteNetworkImpl) AddLiveQuery(ctx context.Context, rs model.RuleSession, query model.Query,
+	params map[string]interface{}, listener model.LiveQueryListener) error {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+
+	if nw.liveQueries[query.GetName()] != nil {
+		return fmt.Errorf("Live query already exists [%s]", query.GetName())
+	}
+	if nw.allRules[query.GetName()] != nil {
+		return fmt.Errorf("Rule already exists [%s]", query.GetName())
+	}
+	if len(query.GetIdentifiers()) == 0 {
+		return fmt.Errorf("Query has no identifiers [%s]", query.GetName())
+	}
+
+	rule := &queryRule{Query: query, params: params}
+	nodesOfRule := list.New()
+	classNodeLinksOfRule := list.New()
+	lastNode := nw.buildSubNetwork(rule, rule.GetIdentifiers(), rule.GetConditions(), nodesOfRule, classNodeLinksOfRule)
+	queryNode := newQueryNode(nw, rule, lastNode.getIdentifiers(), listener)
+	newNodeLink(nw, lastNode, queryNode, false)
+	nodesOfRule.PushBack(queryNode)
+	nw.addNodesOfRule(rule, nodesOfRule, classNodeLinksOfRule)
+	nw.liveQueries[query.GetName()] = queryNode
+
+	//match the asserted tuples, the listener gets the results as added
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, _ = newReteCtx(ctx, nw, rs)
+	types := identifierTypes(rule, rule.GetIdentifiers())
+	for _, h := range nw.store.getHandles() {
+		tuple := h.getTuple()
+		if ContainedByFirst(types, []model.TupleType{tuple.GetTupleType()}) {
+			getClassNode(nw, tuple.GetTupleType()).assert(ctx, tuple, nil, query.GetName())
+		}
+	}
+	queryNode.flush(ctx)
+	return nil
+}
+
+func (nw *reteNetworkImpl) RemoveLiveQuery(queryName string) {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	if nw.liveQueries[queryName] == nil {
+		return
+	}
+	delete(nw.liveQueries, queryName)
+	nw.removeNodesOfRule(queryName)
+}
+
+//liveQueriesRetracted withdraws the results of the tuple from the live queries
+func (nw *reteNetworkImpl) liveQueriesRetracted(tuple model.Tuple, changedProps map[string]bool) {
+	for _, queryNode := range nw.liveQueries {
+		queryNode.tupleRetracted(tuple, changedProps)
+	}
+}
+
+//flushLiveQueries tells the listeners of the live queries of the results changed in the RTC
+func (nw *reteNetworkImpl) flushLiveQueries(ctx context.Context) {
+	for _, queryNode := range nw.liveQueries {
+		queryNode.flush(ctx)
+	}
+}
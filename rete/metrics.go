@@ -0,0 +1,152 @@
+package rete
+
+import (
+	"sort"
+	"time"
+
+	"github.com/project-flogo/rules/common/metrics"
+	"github.com/project-flogo/rules/common/model"
+)
+
+//networkMetrics counts the work of a network, its methods do nothing on a nil receiver so that
+//the network only pays for the metrics once they are enabled
+type networkMetrics struct {
+	nw       *reteNetworkImpl
+	registry metrics.Registry
+	session  string
+
+	activationsCreated   metrics.CounterVec
+	activationsCancelled metrics.CounterVec
+	activationsFired     metrics.CounterVec
+	evaluations          metrics.CounterVec
+	passes               metrics.CounterVec
+	actionDuration       metrics.HistogramVec
+	rtcDuration          metrics.HistogramVec
+}
+
+func newNetworkMetrics(nw *reteNetworkImpl, registry metrics.Registry, session string) *networkMetrics {
+	m := networkMetrics{nw: nw, registry: registry, session: session}
+	m.activationsCreated = registry.Counter("rules_activations_created_total",
+		"Activations put on the agenda", "session", "rule")
+	m.activationsCancelled = registry.Counter("rules_activations_cancelled_total",
+		"Activations removed from the agenda without firing", "session", "rule")
+	m.activationsFired = registry.Counter("rules_activations_fired_total",
+		"Activations whose action ran", "session", "rule")
+	m.evaluations = registry.Counter("rules_condition_evaluations_total",
+		"Evaluations of a condition", "session", "rule", "condition")
+	m.passes = registry.Counter("rules_condition_passes_total",
+		"Evaluations of a condition that passed", "session", "rule", "condition")
+	m.actionDuration = registry.Histogram("rules_action_duration_seconds",
+		"Time taken by the actions of a rule", nil, "session", "rule")
+	m.rtcDuration = registry.Histogram("rules_rtc_duration_seconds",
+		"Time taken by the RTCs of a session", nil, "session")
+	registry.Register(&m)
+	return &m
+}
+
+func (nw *reteNetworkImpl) SetMetrics(registry metrics.Registry, session string) {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	if nw.metrics != nil {
+		nw.metrics.registry.Unregister(nw.metrics)
+		nw.metrics = nil
+	}
+	if registry != nil {
+		nw.metrics = newNetworkMetrics(nw, registry, session)
+	}
+}
+
+func (nw *reteNetworkImpl) getMetrics() *networkMetrics {
+	return nw.metrics
+}
+
+func (m *networkMetrics) activationCreated(rule model.Rule) {
+	if m != nil {
+		m.activationsCreated.With(m.session, rule.GetName()).Inc()
+	}
+}
+
+func (m *networkMetrics) activationCancelled(rule model.Rule) {
+	if m != nil {
+		m.activationsCancelled.With(m.session, rule.GetName()).Inc()
+	}
+}
+
+//activationFired counts the activation and the time its action took since start
+func (m *networkMetrics) activationFired(rule model.Rule, start time.Time) {
+	if m != nil {
+		m.activationsFired.With(m.session, rule.GetName()).Inc()
+		m.actionDuration.With(m.session, rule.GetName()).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (m *networkMetrics) conditionEvaluated(cv model.Condition, pass bool) {
+	if m != nil {
+		m.evaluations.With(m.session, cv.GetRule().GetName(), cv.GetName()).Inc()
+		if pass {
+			m.passes.With(m.session, cv.GetRule().GetName(), cv.GetName()).Inc()
+		}
+	}
+}
+
+//rtcDone observes the time the RTC took since start, deferred by the methods running an RTC
+func (m *networkMetrics) rtcDone(start time.Time) {
+	if m != nil {
+		m.rtcDuration.With(m.session).Observe(time.Since(start).Seconds())
+	}
+}
+
+//Collect returns the gauges of the working memory, it locks the network so it must not be
+//called from an action
+func (m *networkMetrics) Collect() []metrics.Family {
+	nw := m.nw
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+
+	rows := metrics.Family{Name: "rules_join_table_rows", Help: "Rows in the join tables of a node", Type: metrics.GaugeType}
+	ruleNames := []string{}
+	for name := range nw.allRules {
+		ruleNames = append(ruleNames, name)
+	}
+	sort.Strings(ruleNames)
+	for _, ruleName := range ruleNames {
+		for e := nw.ruleNameNodesOfRule[ruleName].Front(); e != nil; e = e.Next() {
+			var left, right joinTable
+			switch nodeImpl := e.Value.(type) {
+			case *joinNodeImpl:
+				left, right = nodeImpl.leftTable, nodeImpl.rightTable
+			case *groupNodeImpl:
+				left, right = nodeImpl.leftTable, nodeImpl.rightTable
+			default:
+				continue
+			}
+			id := nodeID(e.Value.(node))
+			rows.Samples = append(rows.Samples, m.sample(rows.Name, float64(left.len()), "rule", ruleName, "node", id, "side", "left"),
+				m.sample(rows.Name, float64(right.len()), "rule", ruleName, "node", id, "side", "right"))
+		}
+	}
+
+	asserted := metrics.Family{Name: "rules_asserted_tuples", Help: "Tuples asserted in the working memory", Type: metrics.GaugeType}
+	counts := map[string]int{}
+	for _, h := range nw.store.getHandles() {
+		counts[string(h.getTuple().GetTupleType())]++
+	}
+	types := []string{}
+	for tupleType := range counts {
+		types = append(types, tupleType)
+	}
+	sort.Strings(types)
+	for _, tupleType := range types {
+		asserted.Samples = append(asserted.Samples, m.sample(asserted.Name, float64(counts[tupleType]), "type", tupleType))
+	}
+	return []metrics.Family{rows, asserted}
+}
+
+//sample returns a sample labelled by the session and the name value pairs
+func (m *networkMetrics) sample(name string, value float64, labels ...string) metrics.Sample {
+	s := metrics.Sample{Name: name, Value: value, Labels: []metrics.Label{{Name: "session", Value: m.session}}}
+	for i := 0; i+1 < len(labels); i += 2 {
+		s.Labels = append(s.Labels, metrics.Label{Name: labels[i], Value: labels[i+1]})
+	}
+	return s
+}
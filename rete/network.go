@@ -4,8 +4,8 @@ import (
 	"context"
 	"fmt"
 	"math"
-	"time"
 
+	"github.com/project-flogo/rules/common/metrics"
 	"github.com/project-flogo/rules/common/model"
 
 	"container/list"
@@ -30,7 +30,7 @@ type Network interface {
 	//changedProps are the properties that changed in a previous action
 	Assert(ctx context.Context, rs model.RuleSession, tuple model.Tuple, changedProps map[string]bool, mode RtcOprn)
 	//mode can be one of retract, modify, delete
-	Retract(ctx context.Context, tuple model.Tuple, changedProps map[string]bool, mode RtcOprn)
+	Retract(ctx context.Context, rs model.RuleSession, tuple model.Tuple, changedProps map[string]bool, mode RtcOprn)
 
 	retractInternal(ctx context.Context, tuple model.Tuple, changedProps map[string]bool, mode RtcOprn)
 
@@ -39,11 +39,80 @@ type Network interface {
 	getHandle(tuple model.Tuple) reteHandle
 
 	incrementAndGetId() int
+	//GetAssertedTuple may be called from actions, and outside RTCs as they run, see WorkingMemoryStore.getHandle
 	GetAssertedTuple(key model.TupleKey) model.Tuple
 	GetAssertedTupleByStringKey(key string) model.Tuple
 	//RtcTransactionHandler
 	RegisterRtcTransactionHandler(txnHandler model.RtcTransactionHandler, txnContext interface{})
 	ReplayTuplesForRule(ruleName string, rs model.RuleSession) (err error)
+	//the resolver orders the agenda of each RTC, nil orders by priority and recency
+	SetConflictResolver(resolver model.ConflictResolver)
+	GetConflictResolver() model.ConflictResolver
+	//the clock times the TTLs, windows and event times, it cannot change once tuples are asserted
+	SetClock(clock model.Clock) error
+	GetClock() model.Clock
+	incrementAndGetRecency() int
+	//SetFocus pushes the agenda group onto the focus stack, see model.RuleSession
+	SetFocus(ctx context.Context, rs model.RuleSession, agendaGroup string)
+	GetFocus(ctx context.Context) string
+	getConflictRes() conflictRes
+	//SetRefraction keeps an activation from firing again in an RTC unless a property its rule
+	//depends on changed since
+	SetRefraction(refraction bool)
+	GetRefraction() bool
+	//SetExplain has each activation firing record why, see model.Explanation
+	SetExplain(explain bool)
+	GetExplain() bool
+	explainActivation(ctx context.Context, item agendaItem) *model.Explanation
+	//Diagnose evaluates the conditions of the rule on the asserted tuples of the keys, and tells what
+	//became of their activation, see model.Diagnosis
+	Diagnose(ruleName string, keys []string) (model.Diagnosis, error)
+	//LogicalAssert asserts the tuple justified by the activation firing, see model.RuleSession
+	LogicalAssert(ctx context.Context, rs model.RuleSession, tuple model.Tuple) error
+	getTms() truthMaintenance
+	//expireWindow deletes the tuples of the type's window whose time is over, in an RTC of its own
+	expireWindow(rs model.RuleSession, tupleType model.TupleType)
+	//expireTuples deletes the tuples whose TTL is over, in an RTC of its own
+	expireTuples(rs model.RuleSession)
+	//Stop cancels the timers of the network, its tuples no longer expire
+	Stop()
+	//SetStore replaces the store of the working memory, before any tuple is asserted and after the rules
+	//are added. The tuples a previous network left in the store are asserted again as Restore does,
+	//without running actions
+	SetStore(rs model.RuleSession, store WorkingMemoryStore) error
+	getStore() WorkingMemoryStore
+	//StoreErr returns and clears the first error the store got making the changes of the RTCs durable
+	//since it was last called
+	StoreErr() error
+	//Modify sets values of an asserted tuple in an RTC of its own, as an action would
+	Modify(ctx context.Context, rs model.RuleSession, tuple model.MutableTuple, values map[string]interface{}) error
+	//GetGraph returns the nodes and links of the network, with the rows of its join tables if withRows,
+	//under the lock of the network unless ctx is the one of an action of the network
+	GetGraph(ctx context.Context, withRows bool) Graph
+	//Snapshot returns the state of the network between RTCs
+	Snapshot() NetworkState
+	//Restore asserts the tuples of the state into the empty network in an RTC, with the expiries, focus
+	//stack and pending activations of the state. Actions fire only if runActions, else the
+	//activations not pending in the state are dropped, they fired before the snapshot
+	Restore(ctx context.Context, rs model.RuleSession, state NetworkState, runActions bool) error
+	//SetMetrics counts the work of the network in the registry, labelled by the session, nil stops
+	SetMetrics(registry metrics.Registry, session string)
+	getMetrics() *networkMetrics
+	//AddListener calls the listener in the RTCs starting after, see model.RuleSessionListener
+	AddListener(listener model.RuleSessionListener)
+	RemoveListener(listener model.RuleSessionListener)
+	getListeners() listeners
+	//AddQuery adds a query to run with RunQuery
+	AddQuery(query model.Query) error
+	RemoveQuery(queryName string) model.Query
+	GetQueries() []model.Query
+	//RunQuery returns the combinations of asserted tuples satisfying the query with the params, under
+	//the lock of the network unless ctx is the one of an action of the network
+	RunQuery(ctx context.Context, queryName string, params map[string]interface{}) ([]model.QueryResult, error)
+	//AddLiveQuery builds the query into the network, ending in a query node that tells the listener how its
+	//results change, see model.LiveQueryListener
+	AddLiveQuery(ctx context.Context, rs model.RuleSession, query model.Query, params map[string]interface{}, listener model.LiveQueryListener) error
+	RemoveLiveQuery(queryName string)
 }
 
 type reteNetworkImpl struct {
@@ -59,14 +128,38 @@ type reteNetworkImpl struct {
 	//Holds the Rule name as key and a pointer to a slice of NodeLinks as value
 	ruleNameClassNodeLinksOfRule map[string]*list.List //*list.List of ClassNodeLink
 
-	allHandles map[string]reteHandle
+	//the handles of the asserted tuples, the join table rows and the agenda
+	store WorkingMemoryStore
+	//the first error syncing the store at the end of an RTC, see StoreErr
+	storeErr error
 
 	currentId int
+	recency   int
+
+	resolver model.ConflictResolver
+	clock    model.Clock
+	//the agenda, shared by the RTCs
+	cr         conflictRes
+	refraction bool
+	explain    bool
+	tms        truthMaintenance
+	windows    windows
+	expiries   expiries
 
 	assertLock sync.Mutex
 	//crudLock   sync.Mutex
 	txnHandler model.RtcTransactionHandler
 	txnContext interface{}
+	//nil unless enabled by SetMetrics
+	metrics *networkMetrics
+
+	listenerLock sync.Mutex
+	listeners    listeners
+
+	//the queries by name, see RunQuery
+	queries map[string]model.Query
+	//the terminal nodes of the live queries by name, see AddLiveQuery
+	liveQueries map[string]queryNode
 }
 
 //NewReteNetwork ... creates a new rete network
@@ -81,7 +174,13 @@ func (nw *reteNetworkImpl) initReteNetwork() {
 	nw.allClassNodes = make(map[string]classNode)
 	nw.ruleNameNodesOfRule = make(map[string]*list.List)
 	nw.ruleNameClassNodeLinksOfRule = make(map[string]*list.List)
-	nw.allHandles = make(map[string]reteHandle)
+	nw.store = NewMemoryStore()
+	nw.cr = newConflictRes(nw)
+	nw.tms = newTms(nw)
+	nw.windows = newWindows(nw)
+	nw.expiries = newExpiries(nw)
+	nw.queries = make(map[string]model.Query)
+	nw.liveQueries = make(map[string]queryNode)
 }
 
 func (nw *reteNetworkImpl) AddRule(rule model.Rule) (err error) {
@@ -91,17 +190,72 @@ func (nw *reteNetworkImpl) AddRule(rule model.Rule) (err error) {
 	if nw.allRules[rule.GetName()] != nil {
 		return fmt.Errorf("Rule already exists.." + rule.GetName())
 	}
-	conditionSet := list.New()
-	conditionSetNoIdr := list.New()
-	nodeSet := list.New()
+	if nw.liveQueries[rule.GetName()] != nil {
+		return fmt.Errorf("Live query already exists [%s]", rule.GetName())
+	}
+	err = validateConditionGroups(rule)
+	if err != nil {
+		return err
+	}
+	err = validateAccumulates(rule)
+	if err != nil {
+		return err
+	}
 
 	nodesOfRule := list.New()
 	classNodeLinksOfRule := list.New()
 
-	conditions := rule.GetConditions()
+	lastNode := nw.buildSubNetwork(rule, rule.GetIdentifiers(), rule.GetConditions(), nodesOfRule, classNodeLinksOfRule)
+	for _, group := range rule.GetConditionGroups() {
+		lastNode = nw.buildConditionGroup(rule, group, lastNode, nodesOfRule, classNodeLinksOfRule)
+	}
+	for _, accumulate := range rule.GetAccumulates() {
+		nw.buildAccumulate(rule, accumulate, nodesOfRule, classNodeLinksOfRule)
+	}
+
+	//Yoohoo! We have a Rule!!
+	ruleNode := newRuleNode(nw, rule, lastNode.getIdentifiers())
+	newNodeLink(nw, lastNode, ruleNode, false)
+	nodesOfRule.PushBack(ruleNode)
+
+	nw.addNodesOfRule(rule, nodesOfRule, classNodeLinksOfRule)
+
+	//Add the rule to the network
+	nw.allRules[rule.GetName()] = rule
+
+	return nil
+}
+
+//addNodesOfRule optimizes the nodes built for the rule and keeps them by its name
+func (nw *reteNetworkImpl) addNodesOfRule(rule model.Rule, nodesOfRule *list.List, classNodeLinksOfRule *list.List) {
+	cntxt := make([]interface{}, 2)
+	cntxt[0] = nw
+	cntxt[1] = nodesOfRule
+
+	for _, classNode := range nw.allClassNodes {
+		optimizeNetwork(classNode, cntxt)
+	}
+	// nw.optimizeNetwork(nodesOfRule)
+
+	nw.setClassNodeAndLinkJoinTables(nodesOfRule, classNodeLinksOfRule)
+
+	//Add RuleNodes
+	nw.ruleNameNodesOfRule[rule.GetName()] = nodesOfRule
+
+	//Add NodeLinks
+	nw.ruleNameClassNodeLinksOfRule[rule.GetName()] = classNodeLinksOfRule
+}
+
+//buildSubNetwork builds the nodes evaluating conditions over idrs, returns the last node holding all the idrs
+func (nw *reteNetworkImpl) buildSubNetwork(rule model.Rule, idrs []model.TupleType, conditions []model.Condition,
+	nodesOfRule *list.List, classNodeLinksOfRule *list.List) node {
+	conditionSet := list.New()
+	conditionSetNoIdr := list.New()
+	nodeSet := list.New()
+
 	noIdrConditionCnt := 0
 	if len(conditions) == 0 {
-		identifierVar := pickIdentifier(rule.GetIdentifiers())
+		identifierVar := pickIdentifier(idrs)
 		nw.createClassFilterNode(rule, nodesOfRule, classNodeLinksOfRule, identifierVar, nil, nodeSet)
 	} else {
 		for i := 0; i < len(conditions); i++ {
@@ -117,33 +271,85 @@ func (nw *reteNetworkImpl) AddRule(rule model.Rule) (err error) {
 			}
 		}
 	}
-	if len(rule.GetConditions()) != 0 && noIdrConditionCnt == len(rule.GetConditions()) {
-		idr := pickIdentifier(rule.GetIdentifiers())
+	if len(conditions) != 0 && noIdrConditionCnt == len(conditions) {
+		idr := pickIdentifier(idrs)
 		nw.createClassFilterNode(rule, nodesOfRule, classNodeLinksOfRule, idr, nil, nodeSet)
 	}
 
-	nw.buildNetwork(rule, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet, conditionSetNoIdr)
-
-	cntxt := make([]interface{}, 2)
-	cntxt[0] = nw
-	cntxt[1] = nodesOfRule
+	return nw.buildNetwork(rule, idrs, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet, conditionSetNoIdr)
+}
 
-	for _, classNode := range nw.allClassNodes {
-		optimizeNetwork(classNode, cntxt)
+//buildConditionGroup builds the group's own identifiers and conditions into a sub network and
+//joins it to the rest of the rule's network with the group's node
+func (nw *reteNetworkImpl) buildConditionGroup(rule model.Rule, group model.ConditionGroup, leftNode node,
+	nodesOfRule *list.List, classNodeLinksOfRule *list.List) node {
+	innerConditions := []model.Condition{}
+	joinConditions := []model.Condition{}
+	for _, conditionVar := range group.GetConditions() {
+		if ContainedByFirst(group.GetIdentifiers(), conditionVar.GetIdentifiers()) {
+			innerConditions = append(innerConditions, conditionVar)
+		} else {
+			joinConditions = append(joinConditions, conditionVar)
+		}
 	}
-	// nw.optimizeNetwork(nodesOfRule)
+	rightNode := nw.buildSubNetwork(rule, group.GetIdentifiers(), innerConditions, nodesOfRule, classNodeLinksOfRule)
 
-	nw.setClassNodeAndLinkJoinTables(nodesOfRule, classNodeLinksOfRule)
+	groupNode := newGroupNode(nw, rule, group.GetGroupType(), leftNode.getIdentifiers(), rightNode.getIdentifiers(), joinConditions)
+	newNodeLink(nw, leftNode, groupNode, false)
+	newNodeLink(nw, rightNode, groupNode, true)
+	nodesOfRule.PushBack(groupNode)
+	return groupNode
+}
 
-	//Add the rule to the network
-	nw.allRules[rule.GetName()] = rule
+//buildAccumulate builds the accumulate's identifier and conditions into a sub network ending in the accumulate's
+//node, which feeds its results to the class node of the result type
+func (nw *reteNetworkImpl) buildAccumulate(rule model.Rule, accumulate model.Accumulate,
+	nodesOfRule *list.List, classNodeLinksOfRule *list.List) {
+	idrs := []model.TupleType{accumulate.GetIdentifier()}
+	lastNode := nw.buildSubNetwork(rule, idrs, accumulate.GetConditions(), nodesOfRule, classNodeLinksOfRule)
 
-	//Add RuleNodes
-	nw.ruleNameNodesOfRule[rule.GetName()] = nodesOfRule
+	accumulateNode := newAccumulateNode(nw, rule, accumulate, getClassNode(nw, model.TupleType(accumulate.GetName())))
+	newNodeLink(nw, lastNode, accumulateNode, false)
+	nodesOfRule.PushBack(accumulateNode)
+}
 
-	//Add NodeLinks
-	nw.ruleNameClassNodeLinksOfRule[rule.GetName()] = classNodeLinksOfRule
+func validateAccumulates(rule model.Rule) error {
+	for _, accumulate := range rule.GetAccumulates() {
+		idrs := []model.TupleType{accumulate.GetIdentifier()}
+		if ContainedByFirst(rule.GetIdentifiers(), idrs) {
+			return fmt.Errorf("Identifier [%s] of accumulate [%s] is also an identifier of rule [%s]",
+				string(accumulate.GetIdentifier()), accumulate.GetName(), rule.GetName())
+		}
+		for _, group := range rule.GetConditionGroups() {
+			if ContainedByFirst(group.GetIdentifiers(), idrs) {
+				return fmt.Errorf("Identifier [%s] of accumulate [%s] is also an identifier of condition group [%s]",
+					string(accumulate.GetIdentifier()), accumulate.GetName(), group.GetName())
+			}
+		}
+	}
+	return nil
+}
 
+func validateConditionGroups(rule model.Rule) error {
+	if len(rule.GetConditionGroups()) > 0 && len(rule.GetIdentifiers()) == 0 {
+		return fmt.Errorf("Rule [%s] has no identifiers outside of its condition groups", rule.GetName())
+	}
+	for _, group := range rule.GetConditionGroups() {
+		for _, idr := range group.GetIdentifiers() {
+			if found, _ := model.Contains(rule.GetIdentifiers(), idr); found {
+				return fmt.Errorf("Identifier [%s] of condition group [%s] is also an identifier of rule [%s]",
+					string(idr), group.GetName(), rule.GetName())
+			}
+		}
+		for _, conditionVar := range group.GetConditions() {
+			for _, idr := range conditionVar.GetIdentifiers() {
+				if !ContainedByFirst(UnionIdentifiers(group.GetIdentifiers(), rule.GetIdentifiers()), []model.TupleType{idr}) {
+					return fmt.Errorf("Identifier [%s] of condition [%s] is neither in condition group [%s] nor in rule [%s]",
+						string(idr), conditionVar.GetName(), group.GetName(), rule.GetName())
+				}
+			}
+		}
+	}
 	return nil
 }
 
@@ -151,17 +357,38 @@ func (nw *reteNetworkImpl) ReplayTuplesForRule(ruleName string, rs model.RuleSes
 	if rule, exists := nw.allRules[ruleName]; !exists {
 		return fmt.Errorf("Rule not found [%s]", ruleName)
 	} else {
-		for _, h := range nw.allHandles {
-			tt := h.getTuple().GetTupleType()
-			if ContainedByFirst(rule.GetIdentifiers(), []model.TupleType{tt}) {
-				//assert it but only for this rule.
-				nw.assert(context.TODO(), rs, h.getTuple(), nil, ADD, ruleName)
+		//replay the tuples of condition groups and accumulates first, so that rule identifiers see them
+		//a tuple is asserted to all the links of the rule, so each tuple type is replayed once
+		groupTypes := []model.TupleType{}
+		for _, group := range rule.GetConditionGroups() {
+			groupTypes = UnionIdentifiers(groupTypes, identifierTypes(rule, group.GetIdentifiers()))
+		}
+		for _, accumulate := range rule.GetAccumulates() {
+			groupTypes = UnionIdentifiers(groupTypes, identifierTypes(rule, []model.TupleType{accumulate.GetIdentifier()}))
+		}
+		ruleTypes := SecondMinusFirst(groupTypes, identifierTypes(rule, rule.GetIdentifiers()))
+		for _, types := range [][]model.TupleType{groupTypes, ruleTypes} {
+			for _, h := range nw.store.getHandles() {
+				tt := h.getTuple().GetTupleType()
+				if ContainedByFirst(types, []model.TupleType{tt}) {
+					//assert it but only for this rule.
+					nw.assert(context.TODO(), rs, h.getTuple(), nil, ADD, ruleName)
+				}
 			}
 		}
 	}
 	return nil
 }
 
+//identifierTypes returns the tuple types of the rule's identifiers, some may be aliases
+func identifierTypes(rule model.Rule, idrs []model.TupleType) []model.TupleType {
+	types := []model.TupleType{}
+	for _, idr := range idrs {
+		types = UnionIdentifiers(types, []model.TupleType{rule.GetIdentifierType(idr)})
+	}
+	return types
+}
+
 func (nw *reteNetworkImpl) setClassNodeAndLinkJoinTables(nodesOfRule *list.List,
 	classNodeLinksOfRule *list.List) {
 }
@@ -177,7 +404,13 @@ func (nw *reteNetworkImpl) RemoveRule(ruleName string) model.Rule {
 		//TODO: log a message
 		return nil
 	}
+	nw.cr.deleteAgendaForRule(rule)
+	nw.removeNodesOfRule(ruleName)
+	return rule
+}
 
+//removeNodesOfRule unlinks the nodes of the rule from the class nodes and forgets their join tables
+func (nw *reteNetworkImpl) removeNodesOfRule(ruleName string) {
 	classNodeLinksOfRule := nw.ruleNameClassNodeLinksOfRule[ruleName]
 	delete(nw.ruleNameClassNodeLinksOfRule, ruleName)
 	if classNodeLinksOfRule != nil {
@@ -199,12 +432,14 @@ func (nw *reteNetworkImpl) RemoveRule(ruleName string) model.Rule {
 			case *joinNodeImpl:
 				removeRefsFromReteHandles(nodeImpl.leftTable)
 				removeRefsFromReteHandles(nodeImpl.rightTable)
+			case *groupNodeImpl:
+				removeRefsFromReteHandles(nodeImpl.leftTable)
+				removeRefsFromReteHandles(nodeImpl.rightTable)
+			case *accumulateNodeImpl:
+				removeRefsFromReteHandles(nodeImpl.table)
 			}
 		}
 	}
-	rstr := nw.String()
-	fmt.Print(rstr)
-	return rule
 }
 
 func (nw *reteNetworkImpl) GetRules() []model.Rule {
@@ -225,6 +460,7 @@ func removeRefsFromReteHandles(joinTableVar joinTable) {
 			handle.removeJoinTable(joinTableVar)
 		}
 	}
+	joinTableVar.clear()
 }
 
 func removeRuleHelper(classNodeLinkOfRule classNodeLink) {
@@ -267,12 +503,13 @@ func contains(nodeSet *list.List, identifierVar model.TupleType) bool {
 	return false
 }
 
-func (nw *reteNetworkImpl) buildNetwork(rule model.Rule, nodesOfRule *list.List, classNodeLinksOfRule *list.List,
-	conditionSet *list.List, nodeSet *list.List, conditionSetNoIdr *list.List) {
+//buildNetwork returns the last node that holds all the idrs, after adding filter nodes for conditions with no identifiers
+func (nw *reteNetworkImpl) buildNetwork(rule model.Rule, idrs []model.TupleType, nodesOfRule *list.List, classNodeLinksOfRule *list.List,
+	conditionSet *list.List, nodeSet *list.List, conditionSetNoIdr *list.List) node {
 	if conditionSet.Len() == 0 {
 		if nodeSet.Len() == 1 {
 			n := nodeSet.Front().Value.(node)
-			if ContainedByFirst(n.getIdentifiers(), rule.GetIdentifiers()) {
+			if ContainedByFirst(n.getIdentifiers(), idrs) {
 				//TODO: Re evaluate set later..
 
 				lastNode := n
@@ -284,34 +521,27 @@ func (nw *reteNetworkImpl) buildNetwork(rule model.Rule, nodesOfRule *list.List,
 					newNodeLink(nw, lastNode, fNode, false)
 					lastNode = fNode
 				}
-				//Yoohoo! We have a Rule!!
-				ruleNode := newRuleNode(nw, rule)
-				newNodeLink(nw, lastNode, ruleNode, false)
-				nodesOfRule.PushBack(ruleNode)
-			} else {
-				idrs := SecondMinusFirst(n.getIdentifiers(), rule.GetIdentifiers())
-				fNode := nw.createClassFilterNode(rule, nodesOfRule, classNodeLinksOfRule, idrs[0], nil, nodeSet)
-				nw.createJoinNode(rule, nodesOfRule, n, fNode, nil, conditionSet, nodeSet)
-				nw.buildNetwork(rule, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet, conditionSetNoIdr)
+				return lastNode
 			}
-		} else {
-			nodes := findSimilarNodes(nodeSet)
-			nw.createJoinNode(rule, nodesOfRule, nodes[0], nodes[1], nil, conditionSet, nodeSet)
-			nw.buildNetwork(rule, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet, conditionSetNoIdr)
-		}
-	} else {
-		if nw.createFilterNode(rule, nodesOfRule, conditionSet, nodeSet) {
-			nw.buildNetwork(rule, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet, conditionSetNoIdr)
-		} else if nw.createJoinNodeFromExisting(rule, nodesOfRule, conditionSet, nodeSet) {
-			nw.buildNetwork(rule, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet, conditionSetNoIdr)
-		} else if nw.createJoinNodeFromSome(rule, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet) {
-			nw.buildNetwork(rule, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet, conditionSetNoIdr)
-		} else {
-			conditionVar := nw.findConditionWithLeastIdentifiers(conditionSet)
-			nw.createClassFilterNode(rule, nodesOfRule, classNodeLinksOfRule, conditionVar.GetIdentifiers()[0], nil, nodeSet)
-			nw.buildNetwork(rule, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet, conditionSetNoIdr)
+			missingIdrs := SecondMinusFirst(n.getIdentifiers(), idrs)
+			fNode := nw.createClassFilterNode(rule, nodesOfRule, classNodeLinksOfRule, missingIdrs[0], nil, nodeSet)
+			nw.createJoinNode(rule, nodesOfRule, n, fNode, nil, conditionSet, nodeSet)
+			return nw.buildNetwork(rule, idrs, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet, conditionSetNoIdr)
 		}
+		nodes := findSimilarNodes(nodeSet)
+		nw.createJoinNode(rule, nodesOfRule, nodes[0], nodes[1], nil, conditionSet, nodeSet)
+		return nw.buildNetwork(rule, idrs, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet, conditionSetNoIdr)
 	}
+	if nw.createFilterNode(rule, nodesOfRule, conditionSet, nodeSet) {
+		return nw.buildNetwork(rule, idrs, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet, conditionSetNoIdr)
+	} else if nw.createJoinNodeFromExisting(rule, nodesOfRule, conditionSet, nodeSet) {
+		return nw.buildNetwork(rule, idrs, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet, conditionSetNoIdr)
+	} else if nw.createJoinNodeFromSome(rule, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet) {
+		return nw.buildNetwork(rule, idrs, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet, conditionSetNoIdr)
+	}
+	conditionVar := nw.findConditionWithLeastIdentifiers(conditionSet)
+	nw.createClassFilterNode(rule, nodesOfRule, classNodeLinksOfRule, conditionVar.GetIdentifiers()[0], nil, nodeSet)
+	return nw.buildNetwork(rule, idrs, nodesOfRule, classNodeLinksOfRule, conditionSet, nodeSet, conditionSetNoIdr)
 }
 
 func (nw *reteNetworkImpl) createFilterNode(rule model.Rule, nodesOfRule *list.List, conditionSet *list.List, nodeSet *list.List) bool {
@@ -321,7 +551,7 @@ func (nw *reteNetworkImpl) createFilterNode(rule model.Rule, nodesOfRule *list.L
 			n := f.Value.(node)
 			if ContainedByFirst(n.getIdentifiers(), conditionVar.GetIdentifiers()) {
 				//TODO
-				filterNode := newFilterNode(nw, rule, conditionVar.GetIdentifiers(), conditionVar)
+				filterNode := newFilterNode(nw, rule, n.getIdentifiers(), conditionVar)
 				newNodeLink(nw, n, filterNode, false)
 				removeFromList(nodeSet, n)
 				nodeSet.PushBack(filterNode)
@@ -421,7 +651,7 @@ func (nw *reteNetworkImpl) createJoinNodeFromSome(rule model.Rule, nodesOfRule *
 
 func (nw *reteNetworkImpl) createClassFilterNode(rule model.Rule, nodesOfRule *list.List, classNodeLinksOfRule *list.List, identifierVar model.TupleType, conditionVar model.Condition, nodeSet *list.List) filterNode {
 	identifiers := []model.TupleType{identifierVar}
-	classNodeVar := getClassNode(nw, identifierVar)
+	classNodeVar := getClassNode(nw, rule.GetIdentifierType(identifierVar))
 	filterNodeVar := newFilterNode(nw, rule, identifiers, conditionVar)
 	classNodeLink := newClassNodeLink(nw, classNodeVar, filterNodeVar, rule, identifierVar)
 	classNodeVar.addClassNodeLink(classNodeLink)
@@ -528,8 +758,12 @@ func (nw *reteNetworkImpl) PrintRule(rule model.Rule) string {
 			str += nodeImpl.String()
 		case *joinNodeImpl:
 			str += nodeImpl.String()
+		case *groupNodeImpl:
+			str += nodeImpl.String()
+		case *accumulateNodeImpl:
+			str += nodeImpl.String()
 		case *classNodeImpl:
-			str += nw.printClassNode(rule.GetName(), nodeImpl)
+			str += nw.printClassNode(rule, nodeImpl)
 		case *ruleNodeImpl:
 			str += nodeImpl.String()
 		}
@@ -538,12 +772,12 @@ func (nw *reteNetworkImpl) PrintRule(rule model.Rule) string {
 	return str
 }
 
-func (nw *reteNetworkImpl) printClassNode(ruleName string, classNodeImpl *classNodeImpl) string {
-	classNodesLinksOfRule := nw.ruleNameClassNodeLinksOfRule[ruleName]
+func (nw *reteNetworkImpl) printClassNode(rule model.Rule, classNodeImpl *classNodeImpl) string {
+	classNodesLinksOfRule := nw.ruleNameClassNodeLinksOfRule[rule.GetName()]
 	links := ""
 	for e := classNodesLinksOfRule.Front(); e != nil; e = e.Next() {
 		classNodeLinkOfRule := e.Value.(classNodeLink)
-		if string(classNodeLinkOfRule.GetIdentifier()) == classNodeImpl.name {
+		if string(rule.GetIdentifierType(classNodeLinkOfRule.GetIdentifier())) == classNodeImpl.name {
 			links += "\n\t\t" + classNodeLinkOfRule.String()
 		}
 	}
@@ -554,26 +788,38 @@ func (nw *reteNetworkImpl) Assert(ctx context.Context, rs model.RuleSession, tup
 	nw.assert(ctx, rs, tuple, changedProps, mode, "")
 }
 
-func (nw *reteNetworkImpl) removeTupleFromRete(tuple model.Tuple) {
-	reteHandle, found := nw.allHandles[tuple.GetKey().String()]
-	if found && reteHandle != nil {
-		delete(nw.allHandles, tuple.GetKey().String())
-		reteHandle.removeJoinTableRowRefs(nil)
+func (nw *reteNetworkImpl) removeTupleFromRete(ctx context.Context, tuple model.Tuple) {
+	reteHandle := nw.store.getHandle(tuple.GetKey().String())
+	if reteHandle != nil {
+		nw.store.deleteHandle(tuple.GetKey().String())
+		reteHandle.removeJoinTableRowRefs(ctx, nil)
+		//the activations of rules without joins, or of agenda groups without the focus
+		nw.cr.removeAgendaItemsFor(ctx, reteHandle)
+		nw.liveQueriesRetracted(tuple, nil)
+		nw.windows.remove(tuple)
+		nw.expiries.remove(tuple)
+		nw.getListeners().tupleRetracted(ctx, tuple, true)
+		nw.tms.tupleRetracted(tuple, nil)
+		nw.tms.retractUnjustified(ctx)
 	}
 }
 
-func (nw *reteNetworkImpl) Retract(ctx context.Context, tuple model.Tuple, changedProps map[string]bool, mode RtcOprn) {
+func (nw *reteNetworkImpl) Retract(ctx context.Context, rs model.RuleSession, tuple model.Tuple, changedProps map[string]bool, mode RtcOprn) {
 
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	reteCtxVar, isRecursive, _ := getOrSetReteCtx(ctx, nw, nil)
+	reteCtxVar, isRecursive, newCtx := getOrSetReteCtx(ctx, nw, rs)
 	if !isRecursive {
 		nw.assertLock.Lock()
 		defer nw.assertLock.Unlock()
-		nw.retractInternal(ctx, tuple, changedProps, mode)
-		if nw.txnHandler != nil && mode == DELETE {
-			rtcTxn := newRtcTxn(reteCtxVar.getRtcAdded(), reteCtxVar.getRtcModified(), reteCtxVar.getRtcDeleted())
+		defer nw.endRtc(newCtx, nw.startRtc(newCtx))
+		nw.retractInternal(newCtx, tuple, changedProps, mode)
+		//retracting may unblock negated conditions, fire those rules
+		reteCtxVar.getConflictResolver().resolveConflict(newCtx)
+		//the tuples it justified may have been deleted too
+		if nw.txnHandler != nil && (mode == DELETE || len(reteCtxVar.getRtcDeleted()) > 0 || len(reteCtxVar.getRtcRetracted()) > 0) {
+			rtcTxn := newRtcTxn(reteCtxVar.getRtcAdded(), reteCtxVar.getRtcModified(), reteCtxVar.getRtcDeleted(), reteCtxVar.getRtcRetracted(), reteCtxVar.getExplanations())
 			nw.txnHandler(ctx, reteCtxVar.getRuleSession(), rtcTxn, nw.txnContext)
 		}
 	} else {
@@ -588,44 +834,74 @@ func (nw *reteNetworkImpl) retractInternal(ctx context.Context, tuple model.Tupl
 	}
 	rCtx, _, _ := getOrSetReteCtx(ctx, nw, nil)
 
-	reteHandle := nw.allHandles[tuple.GetKey().String()]
+	reteHandle := nw.store.getHandle(tuple.GetKey().String())
 	if reteHandle != nil {
-		reteHandle.removeJoinTableRowRefs(changedProps)
+		reteHandle.removeJoinTableRowRefs(ctx, changedProps)
+		nw.liveQueriesRetracted(tuple, changedProps)
 
 		//add it to the delete list
 		if mode == DELETE {
 			rCtx.addToRtcDeleted(tuple)
+		} else if mode == RETRACT {
+			rCtx.addToRtcRetracted(tuple)
+		}
+		if mode != MODIFY {
+			nw.getListeners().tupleRetracted(ctx, tuple, mode == DELETE)
+		}
+		nw.store.deleteHandle(tuple.GetKey().String())
+		if mode != MODIFY {
+			//the activations of rules without joins, or of agenda groups without the focus
+			nw.cr.removeAgendaItemsFor(ctx, reteHandle)
+			nw.windows.remove(tuple)
+			nw.expiries.remove(tuple)
+		}
+		nw.tms.tupleRetracted(tuple, changedProps)
+		if mode != MODIFY {
+			//else once the modify reasserted the tuple, see assertInternal
+			nw.tms.retractUnjustified(ctx)
 		}
-		delete(nw.allHandles, tuple.GetKey().String())
 	}
 }
 
 func (nw *reteNetworkImpl) GetAssertedTuple(key model.TupleKey) model.Tuple {
-	reteHandle, found := nw.allHandles[key.String()]
-	if found {
+	reteHandle := nw.store.getHandle(key.String())
+	if reteHandle != nil {
 		return reteHandle.getTuple()
 	}
 	return nil
 }
 
 func (nw *reteNetworkImpl) GetAssertedTupleByStringKey(key string) model.Tuple {
-	reteHandle, found := nw.allHandles[key]
-	if found {
+	reteHandle := nw.store.getHandle(key)
+	if reteHandle != nil {
 		return reteHandle.getTuple()
 	}
 	return nil
 }
 
 func (nw *reteNetworkImpl) assertInternal(ctx context.Context, tuple model.Tuple, changedProps map[string]bool, mode RtcOprn, forRule string) {
+	if mode == ADD {
+		model.SetAssertionTime(tuple, nw.clock.Now())
+		nw.getListeners().tupleAsserted(ctx, tuple)
+	}
 	tupleType := tuple.GetTupleType()
 	listItem := nw.allClassNodes[string(tupleType)]
 	if listItem != nil {
+		if mode == ADD {
+			//make room in the window before the tuple gets matched
+			nw.windows.admit(ctx, tuple)
+			if ttl := model.GetTTL(tuple); ttl > 0 {
+				nw.expiries.schedule(ctx, tuple, ttl)
+			}
+		}
 		classNodeVar := listItem.(classNode)
 		classNodeVar.assert(ctx, tuple, changedProps, forRule)
+		//the tuple may block negated conditions justifying logically asserted tuples
+		nw.tms.retractUnjustified(ctx)
 	}
 	td := model.GetTupleDescriptor(tuple.GetTupleType())
 	if td != nil {
-		if td.TTLInSeconds != 0 && mode == ADD {
+		if model.GetTTL(tuple) != 0 && mode == ADD {
 			rCtx := getReteCtx(ctx)
 			if rCtx != nil {
 				rCtx.addToRtcAdded(tuple)
@@ -635,19 +911,19 @@ func (nw *reteNetworkImpl) assertInternal(ctx context.Context, tuple model.Tuple
 }
 
 func (nw *reteNetworkImpl) getOrCreateHandle(ctx context.Context, tuple model.Tuple) reteHandle {
-	h := nw.allHandles[tuple.GetKey().String()]
+	h := nw.store.getHandle(tuple.GetKey().String())
 	if h == nil {
 		h1 := handleImpl{}
 		h1.initHandleImpl()
 		h1.setTuple(tuple)
 		h = &h1
-		nw.allHandles[tuple.GetKey().String()] = h
+		nw.store.putHandle(tuple.GetKey().String(), h)
 	}
 	return h
 }
 
 func (nw *reteNetworkImpl) getHandle(tuple model.Tuple) reteHandle {
-	h := nw.allHandles[tuple.GetKey().String()]
+	h := nw.store.getHandle(tuple.GetKey().String())
 
 	return h
 }
@@ -657,6 +933,214 @@ func (nw *reteNetworkImpl) incrementAndGetId() int {
 	return nw.currentId
 }
 
+func (nw *reteNetworkImpl) incrementAndGetRecency() int {
+	nw.recency++
+	return nw.recency
+}
+
+func (nw *reteNetworkImpl) SetConflictResolver(resolver model.ConflictResolver) {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	nw.resolver = resolver
+}
+
+func (nw *reteNetworkImpl) GetConflictResolver() model.ConflictResolver {
+	return nw.resolver
+}
+
+func (nw *reteNetworkImpl) SetClock(clock model.Clock) error {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	//their expiries and windows are timed by the clock set before
+	if len(nw.store.getHandles()) > 0 {
+		return fmt.Errorf("Cannot change the clock, tuples are asserted")
+	}
+	nw.clock = clock
+	return nil
+}
+
+func (nw *reteNetworkImpl) GetClock() model.Clock {
+	return nw.clock
+}
+
+//SetFocus does not lock from an action, which holds the lock. Otherwise the activations of the
+//group fire in an RTC of their own
+func (nw *reteNetworkImpl) SetFocus(ctx context.Context, rs model.RuleSession, agendaGroup string) {
+	if inRtcOf(ctx, nw) {
+		nw.cr.setFocus(agendaGroup)
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	newCtx, reteCtxVar := newReteCtx(ctx, nw, rs)
+	defer nw.endRtc(newCtx, nw.startRtc(newCtx))
+	nw.cr.setFocus(agendaGroup)
+	reteCtxVar.getConflictResolver().resolveConflict(newCtx)
+	if nw.txnHandler != nil && (len(reteCtxVar.getRtcAdded()) > 0 || len(reteCtxVar.getRtcModified()) > 0 ||
+		len(reteCtxVar.getRtcDeleted()) > 0 || len(reteCtxVar.getRtcRetracted()) > 0) {
+		rtcTxn := newRtcTxn(reteCtxVar.getRtcAdded(), reteCtxVar.getRtcModified(), reteCtxVar.getRtcDeleted(), reteCtxVar.getRtcRetracted(), reteCtxVar.getExplanations())
+		nw.txnHandler(ctx, rs, rtcTxn, nw.txnContext)
+	}
+}
+
+func (nw *reteNetworkImpl) GetFocus(ctx context.Context) string {
+	if !inRtcOf(ctx, nw) {
+		nw.assertLock.Lock()
+		defer nw.assertLock.Unlock()
+	}
+	return nw.cr.getFocus()
+}
+
+func (nw *reteNetworkImpl) SetRefraction(refraction bool) {
+	nw.refraction = refraction
+}
+
+func (nw *reteNetworkImpl) GetRefraction() bool {
+	return nw.refraction
+}
+
+func (nw *reteNetworkImpl) getConflictRes() conflictRes {
+	return nw.cr
+}
+
+func (nw *reteNetworkImpl) LogicalAssert(ctx context.Context, rs model.RuleSession, tuple model.Tuple) error {
+	key := tuple.GetKey().String()
+	var reteCtxVar reteCtx
+	if ctx != nil {
+		reteCtxVar = getReteCtx(ctx)
+	}
+	if reteCtxVar == nil || reteCtxVar.getFiring() == nil {
+		return fmt.Errorf("Cannot logically assert tuple [%s] outside of an action", key)
+	}
+	if !nw.tms.isJustified(key) && nw.GetAssertedTupleByStringKey(key) != nil {
+		return fmt.Errorf("Tuple with key [%s] already asserted", key)
+	}
+	firing := reteCtxVar.getFiring()
+	if nw.tms.justify(key, firing.GetRule(), firing.getHandles()) {
+		nw.Assert(ctx, rs, tuple, nil, ADD)
+	}
+	return nil
+}
+
+func (nw *reteNetworkImpl) getTms() truthMaintenance {
+	return nw.tms
+}
+
+func (nw *reteNetworkImpl) expireWindow(rs model.RuleSession, tupleType model.TupleType) {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	ctx, reteCtxVar := newReteCtx(context.Background(), nw, rs)
+	defer nw.endRtc(ctx, nw.startRtc(ctx))
+	nw.windows.expire(ctx, tupleType)
+	reteCtxVar.getConflictResolver().resolveConflict(ctx)
+	if nw.txnHandler != nil && len(reteCtxVar.getRtcDeleted()) > 0 {
+		rtcTxn := newRtcTxn(reteCtxVar.getRtcAdded(), reteCtxVar.getRtcModified(), reteCtxVar.getRtcDeleted(), reteCtxVar.getRtcRetracted(), reteCtxVar.getExplanations())
+		nw.txnHandler(ctx, rs, rtcTxn, nw.txnContext)
+	}
+}
+
+func (nw *reteNetworkImpl) expireTuples(rs model.RuleSession) {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	ctx, reteCtxVar := newReteCtx(context.Background(), nw, rs)
+	defer nw.endRtc(ctx, nw.startRtc(ctx))
+	expired := nw.expiries.expire(ctx)
+	reteCtxVar.getConflictResolver().resolveConflict(ctx)
+	for _, tuple := range expired {
+		nw.removeTupleFromRete(ctx, tuple)
+	}
+	reteCtxVar.getConflictResolver().resolveConflict(ctx)
+	if nw.txnHandler != nil && len(reteCtxVar.getRtcDeleted()) > 0 {
+		rtcTxn := newRtcTxn(reteCtxVar.getRtcAdded(), reteCtxVar.getRtcModified(), reteCtxVar.getRtcDeleted(), reteCtxVar.getRtcRetracted(), reteCtxVar.getExplanations())
+		nw.txnHandler(ctx, rs, rtcTxn, nw.txnContext)
+	}
+}
+
+func (nw *reteNetworkImpl) Modify(ctx context.Context, rs model.RuleSession, tuple model.MutableTuple, values map[string]interface{}) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if getReteCtx(ctx) != nil {
+		return fmt.Errorf("Cannot modify in an RTC, set the values instead")
+	}
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	if nw.getHandle(tuple) == nil {
+		return fmt.Errorf("Tuple with key [%s] not asserted", tuple.GetKey().String())
+	}
+	newCtx, reteCtxVar := newReteCtx(ctx, nw, rs)
+	defer nw.endRtc(newCtx, nw.startRtc(newCtx))
+	var err error
+	for name, value := range values {
+		//the values set before still propagate
+		if err = tuple.SetValue(newCtx, name, value); err != nil {
+			break
+		}
+	}
+	reteCtxVar.addRuleModifiedToOpsList()
+	reteCtxVar.copyRuleModifiedToRtcModified()
+	reteCtxVar.resetModified()
+	for e := reteCtxVar.getOpsList().Front(); e != nil; e = reteCtxVar.getOpsList().Front() {
+		reteCtxVar.getOpsList().Remove(e).(opsEntry).execute(newCtx)
+	}
+	reteCtxVar.getConflictResolver().resolveConflict(newCtx)
+	if nw.txnHandler != nil {
+		rtcTxn := newRtcTxn(reteCtxVar.getRtcAdded(), reteCtxVar.getRtcModified(), reteCtxVar.getRtcDeleted(), reteCtxVar.getRtcRetracted(), reteCtxVar.getExplanations())
+		nw.txnHandler(ctx, rs, rtcTxn, nw.txnContext)
+	}
+	return err
+}
+
+func (nw *reteNetworkImpl) Stop() {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	nw.expiries.stop()
+	nw.windows.stop()
+	nw.store.Close()
+}
+
+func (nw *reteNetworkImpl) SetStore(rs model.RuleSession, store WorkingMemoryStore) error {
+	if err := nw.replaceStore(store); err != nil {
+		return err
+	}
+	state, err := store.load()
+	if err != nil {
+		return err
+	}
+	if len(state.Tuples) > 0 {
+		if err = nw.Restore(context.Background(), rs, state, false); err != nil {
+			return err
+		}
+	}
+	return store.loaded()
+}
+
+func (nw *reteNetworkImpl) replaceStore(store WorkingMemoryStore) error {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	if len(nw.store.getHandles()) > 0 || nw.store.getAgenda().Len() > 0 {
+		return fmt.Errorf("Cannot change the store, tuples are asserted")
+	}
+	nw.store.Close()
+	nw.store = store
+	return nil
+}
+
+func (nw *reteNetworkImpl) getStore() WorkingMemoryStore {
+	return nw.store
+}
+
+func (nw *reteNetworkImpl) StoreErr() error {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	err := nw.storeErr
+	nw.storeErr = nil
+	return err
+}
+
 func (nw *reteNetworkImpl) RegisterRtcTransactionHandler(txnHandler model.RtcTransactionHandler, txnContext interface{}) {
 	nw.txnHandler = txnHandler
 	nw.txnContext = txnContext
@@ -673,21 +1157,19 @@ func (nw *reteNetworkImpl) assert(ctx context.Context, rs model.RuleSession, tup
 	if !isRecursive {
 		nw.assertLock.Lock()
 		defer nw.assertLock.Unlock()
+		defer nw.endRtc(newCtx, nw.startRtc(newCtx))
 		nw.assertInternal(newCtx, tuple, changedProps, mode, forRule)
 		reteCtxVar.getConflictResolver().resolveConflict(newCtx)
 		//if Timeout is 0, remove it from rete
 		td := model.GetTupleDescriptor(tuple.GetTupleType())
 		if td != nil {
-			if td.TTLInSeconds == 0 { //remove immediately.
-				nw.removeTupleFromRete(tuple)
-			} else if td.TTLInSeconds > 0 { // TTL for the tuple type, after that, remove it from RETE
-				go time.AfterFunc(time.Second*time.Duration(td.TTLInSeconds), func() {
-					nw.removeTupleFromRete(tuple)
-				})
-			} //else, its -ve and means, never expire
+			if model.GetTTL(tuple) == 0 { //remove immediately.
+				nw.removeTupleFromRete(newCtx, tuple)
+				reteCtxVar.getConflictResolver().resolveConflict(newCtx)
+			} //else, a positive TTL expires it later, see expiries, -ve means never expire
 		}
 		if nw.txnHandler != nil {
-			rtcTxn := newRtcTxn(reteCtxVar.getRtcAdded(), reteCtxVar.getRtcModified(), reteCtxVar.getRtcDeleted())
+			rtcTxn := newRtcTxn(reteCtxVar.getRtcAdded(), reteCtxVar.getRtcModified(), reteCtxVar.getRtcDeleted(), reteCtxVar.getRtcRetracted(), reteCtxVar.getExplanations())
 			nw.txnHandler(ctx, rs, rtcTxn, nw.txnContext)
 		}
 	} else {
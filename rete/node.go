@@ -16,6 +16,8 @@ type node interface {
 	getID() int
 	addNodeLink(nodeLink)
 	assertObjects(ctx context.Context, handles []reteHandle, isRight bool)
+	//retractObjects withdraws handles previously asserted into this node, see groupNodeImpl
+	retractObjects(ctx context.Context, handles []reteHandle, isRight bool)
 }
 
 type nodeImpl struct {
@@ -23,6 +25,7 @@ type nodeImpl struct {
 	nodeLinkVar nodeLink
 	id          int
 	rule        model.Rule
+	nw          Network
 }
 
 //NewNode ... returns a new node
@@ -35,6 +38,7 @@ type nodeImpl struct {
 func (n *nodeImpl) initNodeImpl(nw Network, rule model.Rule, identifiers []model.TupleType) {
 
 	n.id = nw.incrementAndGetId()
+	n.nw = nw
 
 	n.identifiers = identifiers
 	n.rule = rule
@@ -52,6 +56,17 @@ func (n *nodeImpl) addNodeLink(nl nodeLink) {
 	n.nodeLinkVar = nl
 }
 
+//evaluate evaluates the condition on the tuples, counting it in the metrics of the network
+func (n *nodeImpl) evaluate(cv model.Condition, tupleMap map[model.TupleType]model.Tuple) (bool, error) {
+	var ctx model.RuleContext = cv.GetContext()
+	if qr, ok := n.rule.(*queryRule); ok {
+		ctx = model.QueryContext{Context: ctx, Params: qr.params}
+	}
+	pass, err := cv.Evaluate(cv.GetName(), cv.GetRule().GetName(), tupleMap, ctx)
+	n.nw.getMetrics().conditionEvaluated(cv, err == nil && pass)
+	return pass, err
+}
+
 func (n *nodeImpl) String() string {
 	str := "id:" + strconv.Itoa(n.id) + ", idrs:"
 	for _, nodeIdentifier := range n.identifiers {
@@ -90,3 +105,7 @@ func findSimilarNodes(nodeSet *list.List) []node {
 func (n *nodeImpl) assertObjects(ctx context.Context, handles []reteHandle, isRight bool) {
 	fmt.Println("Abstract method here.., see filterNodeImpl and joinNodeImpl")
 }
+
+func (n *nodeImpl) retractObjects(ctx context.Context, handles []reteHandle, isRight bool) {
+	fmt.Println("Abstract method here.., see filterNodeImpl and joinNodeImpl")
+}
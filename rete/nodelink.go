@@ -16,6 +16,7 @@ type nodeLink interface {
 	setChild(child node)
 	setIsRightChild(isRight bool)
 	propagateObjects(ctx context.Context, handles []reteHandle)
+	retractObjects(ctx context.Context, handles []reteHandle)
 }
 
 type nodeLinkImpl struct {
@@ -51,6 +52,12 @@ func (nl *nodeLinkImpl) initNodeLink(nw Network, parent node, child node, isRigh
 		} else {
 			nl.childIds = v.leftIdrs
 		}
+	case *groupNodeImpl:
+		if isRight {
+			nl.childIds = v.rightIdrs
+		} else {
+			nl.childIds = v.leftIdrs
+		}
 	case *nodeImpl:
 		nl.childIds = v.identifiers
 	}
@@ -113,8 +120,16 @@ func (nl *nodeLinkImpl) String() string {
 		} else {
 			nextNode += "j" + strconv.Itoa(nl.child.getID()) + "L"
 		}
+	case *groupNodeImpl:
+		if nl.isRight {
+			nextNode += "n" + strconv.Itoa(nl.child.getID()) + "R"
+		} else {
+			nextNode += "n" + strconv.Itoa(nl.child.getID()) + "L"
+		}
 	case *filterNodeImpl:
 		nextNode += "f" + strconv.Itoa(nl.child.getID())
+	case *accumulateNodeImpl:
+		nextNode += "a" + strconv.Itoa(nl.child.getID())
 	}
 	return "link (" + nextNode + ")"
 }
@@ -131,6 +146,14 @@ func (nl *nodeLinkImpl) setIsRightChild(isRight bool) {
 }
 
 func (nl *nodeLinkImpl) propagateObjects(ctx context.Context, handles []reteHandle) {
+	nl.child.assertObjects(ctx, nl.convertHandles(handles), nl.isRightNode())
+}
+
+func (nl *nodeLinkImpl) retractObjects(ctx context.Context, handles []reteHandle) {
+	nl.child.retractObjects(ctx, nl.convertHandles(handles), nl.isRightNode())
+}
+
+func (nl *nodeLinkImpl) convertHandles(handles []reteHandle) []reteHandle {
 	if nl.convert != nil {
 		convertedHandles := make([]reteHandle, nl.numIdentifiers)
 		for i := 0; i < nl.numIdentifiers; i++ {
@@ -138,5 +161,5 @@ func (nl *nodeLinkImpl) propagateObjects(ctx context.Context, handles []reteHand
 		}
 		handles = convertedHandles
 	}
-	nl.child.assertObjects(ctx, handles, nl.isRightNode())
+	return handles
 }
@@ -58,8 +58,9 @@ func newModifyEntry(tuple model.Tuple, changeProps map[string]bool) modifyEntry
 
 func (me *modifyEntryImpl) execute(ctx context.Context) {
 	reteCtx := getReteCtx(ctx)
+	reteCtx.getNetwork().getListeners().tupleModified(ctx, me.tuple, me.changeProps)
 	reteCtx.getConflictResolver().deleteAgendaFor(ctx, me.tuple, me.changeProps)
-	reteCtx.getNetwork().Retract(ctx, me.tuple, me.changeProps, MODIFY)
+	reteCtx.getNetwork().Retract(ctx, reteCtx.getRuleSession(), me.tuple, me.changeProps, MODIFY)
 	reteCtx.getNetwork().Assert(ctx, reteCtx.getRuleSession(), me.tuple, me.changeProps, MODIFY)
 }
 
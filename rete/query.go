@@ -0,0 +1,167 @@
+package rete
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+func (nw *reteNetworkImpl) AddQuery(query model.Query) error {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	if nw.queries[query.GetName()] != nil {
+		return fmt.Errorf("Query already exists [%s]", query.GetName())
+	}
+	if len(query.GetIdentifiers()) == 0 {
+		return fmt.Errorf("Query has no identifiers [%s]", query.GetName())
+	}
+	//the network keeps the tuples of the types it has class nodes for
+	for _, idr := range query.GetIdentifiers() {
+		getClassNode(nw, query.GetIdentifierType(idr))
+	}
+	nw.queries[query.GetName()] = query
+	return nil
+}
+
+func (nw *reteNetworkImpl) RemoveQuery(queryName string) model.Query {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	query := nw.queries[queryName]
+	delete(nw.queries, queryName)
+	return query
+}
+
+func (nw *reteNetworkImpl) GetQueries() []model.Query {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	queries := []model.Query{}
+	for _, query := range nw.queries {
+		queries = append(queries, query)
+	}
+	return queries
+}
+
+func (nw *reteNetworkImpl) RunQuery(ctx context.Context, queryName string, params map[string]interface{}) ([]model.QueryResult, error) {
+	//an action runs with the lock held, its RTC's view is the one to query
+	if !inRtcOf(ctx, nw) {
+		nw.assertLock.Lock()
+		defer nw.assertLock.Unlock()
+	}
+	query := nw.queries[queryName]
+	if query == nil {
+		return nil, fmt.Errorf("Query not found [%s]", queryName)
+	}
+
+	//the asserted tuples by type, by key for a stable order of the results
+	tuplesOfType := map[model.TupleType][]model.Tuple{}
+	for _, idr := range query.GetIdentifiers() {
+		tuplesOfType[query.GetIdentifierType(idr)] = []model.Tuple{}
+	}
+	for _, handle := range nw.store.getHandles() {
+		tuple := handle.getTuple()
+		if tuples, found := tuplesOfType[tuple.GetTupleType()]; found {
+			tuplesOfType[tuple.GetTupleType()] = append(tuples, tuple)
+		}
+	}
+	for _, tuples := range tuplesOfType {
+		sort.Slice(tuples, func(i, j int) bool {
+			return tuples[i].GetKey().String() < tuples[j].GetKey().String()
+		})
+	}
+
+	qr := queryRun{query: query, params: params, tuplesOfType: tuplesOfType,
+		bound: map[model.TupleType]model.Tuple{}, results: []model.QueryResult{}}
+	qr.conditionsAt = conditionsByLastIdentifier(query)
+	if err := qr.bind(0); err != nil {
+		return nil, err
+	}
+	return qr.results, nil
+}
+
+//queryRun binds the query's identifiers one at a time, evaluating each condition as soon as its
+//identifiers are bound
+type queryRun struct {
+	query        model.Query
+	params       map[string]interface{}
+	tuplesOfType map[model.TupleType][]model.Tuple
+	//conditionsAt are the conditions to evaluate once the identifier at the index is bound
+	conditionsAt [][]model.Condition
+	bound        map[model.TupleType]model.Tuple
+	results      []model.QueryResult
+}
+
+func (qr *queryRun) bind(i int) error {
+	idrs := qr.query.GetIdentifiers()
+	if i == len(idrs) {
+		result := model.QueryResult{}
+		for idr, tuple := range qr.bound {
+			result[idr] = tuple
+		}
+		qr.results = append(qr.results, result)
+		return nil
+	}
+	idr := idrs[i]
+	for _, tuple := range qr.tuplesOfType[qr.query.GetIdentifierType(idr)] {
+		if !qr.query.GetSelfMatch() && qr.isBound(tuple) {
+			continue
+		}
+		qr.bound[idr] = tuple
+		pass, err := qr.evaluate(qr.conditionsAt[i])
+		if err != nil {
+			delete(qr.bound, idr)
+			return err
+		}
+		if pass {
+			if err := qr.bind(i + 1); err != nil {
+				delete(qr.bound, idr)
+				return err
+			}
+		}
+	}
+	delete(qr.bound, idr)
+	return nil
+}
+
+func (qr *queryRun) isBound(tuple model.Tuple) bool {
+	for _, bound := range qr.bound {
+		if bound == tuple {
+			return true
+		}
+	}
+	return false
+}
+
+func (qr *queryRun) evaluate(conditions []model.Condition) (bool, error) {
+	for _, cv := range conditions {
+		qctx := model.QueryContext{Context: cv.GetContext(), Params: qr.params}
+		pass, err := cv.Evaluate(cv.GetName(), qr.query.GetName(), qr.bound, qctx)
+		if err != nil {
+			return false, fmt.Errorf("Query [%s] condition [%s]: %v", qr.query.GetName(), cv.GetName(), err)
+		}
+		if !pass {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+//conditionsByLastIdentifier returns the conditions of the query by the index of their identifier that
+//is bound last, those without identifiers with the first
+func conditionsByLastIdentifier(query model.Query) [][]model.Condition {
+	idrs := query.GetIdentifiers()
+	conditionsAt := make([][]model.Condition, len(idrs))
+	for _, cv := range query.GetConditions() {
+		last := 0
+		for _, cidr := range cv.GetIdentifiers() {
+			for i, idr := range idrs {
+				if idr == cidr && i > last {
+					last = i
+				}
+			}
+		}
+		conditionsAt[last] = append(conditionsAt[last], cv)
+	}
+	return conditionsAt
+}
@@ -12,8 +12,13 @@ type reteHandle interface {
 	setTuple(tuple model.Tuple)
 	getTuple() model.Tuple
 	addJoinTableRowRef(joinTableRowVar joinTableRow, joinTableVar joinTable)
-	removeJoinTableRowRefs(changedProps map[string]bool)
+	removeJoinTableRowRef(joinTableRowVar joinTableRow, joinTableVar joinTable)
+	getJoinTableRows(joinTableVar joinTable) *list.List
+	removeJoinTableRowRefs(ctx context.Context, changedProps map[string]bool)
 	removeJoinTable(joinTableVar joinTable)
+	//recency tells when the tuple was last asserted or modified, see model.Activation
+	getRecency() int
+	setRecency(recency int)
 }
 
 type handleImpl struct {
@@ -21,6 +26,7 @@ type handleImpl struct {
 	tablesAndRows map[joinTable]*list.List
 
 	rtcStatus uint8
+	recency   int
 }
 
 func (hdl *handleImpl) setTuple(tuple model.Tuple) {
@@ -36,6 +42,14 @@ func (hdl *handleImpl) getTuple() model.Tuple {
 	return hdl.tuple
 }
 
+func (hdl *handleImpl) getRecency() int {
+	return hdl.recency
+}
+
+func (hdl *handleImpl) setRecency(recency int) {
+	hdl.recency = recency
+}
+
 func getOrCreateHandle(ctx context.Context, tuple model.Tuple) reteHandle {
 	reteCtxVar := getReteCtx(ctx)
 	return reteCtxVar.getNetwork().getOrCreateHandle(ctx, tuple)
@@ -52,13 +66,31 @@ func (hdl *handleImpl) addJoinTableRowRef(joinTableRowVar joinTableRow, joinTabl
 
 }
 
-func (hdl *handleImpl) removeJoinTableRowRefs(changedProps map[string]bool) {
+func (hdl *handleImpl) removeJoinTableRowRef(joinTableRowVar joinTableRow, joinTableVar joinTable) {
+	rowsForJoinTable := hdl.tablesAndRows[joinTableVar]
+	if rowsForJoinTable == nil {
+		return
+	}
+	for e := rowsForJoinTable.Front(); e != nil; e = e.Next() {
+		if e.Value == joinTableRowVar {
+			rowsForJoinTable.Remove(e)
+			break
+		}
+	}
+	if rowsForJoinTable.Len() == 0 {
+		delete(hdl.tablesAndRows, joinTableVar)
+	}
+}
+
+func (hdl *handleImpl) getJoinTableRows(joinTableVar joinTable) *list.List {
+	return hdl.tablesAndRows[joinTableVar]
+}
+
+func (hdl *handleImpl) removeJoinTableRowRefs(ctx context.Context, changedProps map[string]bool) {
 
 	tuple := hdl.tuple
 	alias := tuple.GetTupleType()
 
-	emptyJoinTables := list.New()
-
 	for joinTable, listOfRows := range hdl.tablesAndRows {
 
 		toDelete := false
@@ -82,19 +114,15 @@ func (hdl *handleImpl) removeJoinTableRowRefs(changedProps map[string]bool) {
 			continue
 		}
 
+		//retracting a row removes it from this list as well, hence the copy
+		rows := make([]joinTableRow, 0, listOfRows.Len())
 		for e := listOfRows.Front(); e != nil; e = e.Next() {
-			row := e.Value.(joinTableRow)
-			joinTable.removeRow(row)
+			rows = append(rows, e.Value.(joinTableRow))
 		}
-		if joinTable.len() == 0 {
-			emptyJoinTables.PushBack(joinTable)
+		for _, row := range rows {
+			joinTable.retractRow(ctx, row)
 		}
 	}
-
-	for e := emptyJoinTables.Front(); e != nil; e = e.Next() {
-		emptyJoinTable := e.Value.(joinTable)
-		delete(hdl.tablesAndRows, emptyJoinTable)
-	}
 }
 
 //Used when a rule is deleted. See Network.RemoveRule
@@ -3,27 +3,35 @@ package rete
 import "github.com/project-flogo/rules/common/model"
 
 type rtcTxnImpl struct {
-	added    map[string]map[string]model.Tuple
-	modified map[string]map[string]model.RtcModified
-	deleted  map[string]map[string]model.Tuple
+	added     map[string]map[string]model.Tuple
+	modified  map[string]map[string]model.RtcModified
+	deleted   map[string]map[string]model.Tuple
+	retracted map[string]map[string]model.Tuple
+	//why the activations fired, if the network explains them
+	explanations []model.Explanation
 }
 
-func newRtcTxn(addedTxn map[string]model.Tuple, modifiedTxn map[string]model.RtcModified, deletedTxn map[string]model.Tuple) model.RtcTxn {
+func newRtcTxn(addedTxn map[string]model.Tuple, modifiedTxn map[string]model.RtcModified, deletedTxn map[string]model.Tuple, retractedTxn map[string]model.Tuple,
+	explanations []model.Explanation) model.RtcTxn {
 	rtxn := rtcTxnImpl{}
-	rtxn.init(addedTxn, modifiedTxn, deletedTxn)
+	rtxn.init(addedTxn, modifiedTxn, deletedTxn, retractedTxn)
+	rtxn.explanations = explanations
 	return &rtxn
 }
 
-func (tx *rtcTxnImpl) init(addedTxn map[string]model.Tuple, modifiedTxn map[string]model.RtcModified, deletedTxn map[string]model.Tuple) {
+func (tx *rtcTxnImpl) init(addedTxn map[string]model.Tuple, modifiedTxn map[string]model.RtcModified, deletedTxn map[string]model.Tuple, retractedTxn map[string]model.Tuple) {
 	tx.added = make(map[string]map[string]model.Tuple)
 	tx.modified = make(map[string]map[string]model.RtcModified)
 	tx.deleted = make(map[string]map[string]model.Tuple)
+	tx.retracted = make(map[string]map[string]model.Tuple)
 
 	tx.groupAddedByType(addedTxn)
 
 	tx.groupModifiedByType(modifiedTxn)
 
 	tx.groupDeletedByType(deletedTxn)
+
+	tx.groupRetractedByType(retractedTxn)
 }
 
 func (tx *rtcTxnImpl) groupDeletedByType(deletedTxn map[string]model.Tuple) {
@@ -38,6 +46,18 @@ func (tx *rtcTxnImpl) groupDeletedByType(deletedTxn map[string]model.Tuple) {
 	}
 }
 
+func (tx *rtcTxnImpl) groupRetractedByType(retractedTxn map[string]model.Tuple) {
+	for key, tuple := range retractedTxn {
+		tdType := tuple.GetTupleDescriptor().Name
+		tupleMap, found := tx.retracted[tdType]
+		if !found {
+			tupleMap = make(map[string]model.Tuple)
+			tx.retracted[tdType] = tupleMap
+		}
+		tupleMap[key] = tuple
+	}
+}
+
 func (tx *rtcTxnImpl) groupModifiedByType(modifiedTxn map[string]model.RtcModified) {
 	for key, rtcModified := range modifiedTxn {
 		tdType := rtcModified.GetTuple().GetTupleDescriptor().Name
@@ -73,3 +93,11 @@ func (tx *rtcTxnImpl) GetRtcModified() map[string]map[string]model.RtcModified {
 func (tx *rtcTxnImpl) GetRtcDeleted() map[string]map[string]model.Tuple {
 	return tx.deleted
 }
+
+func (tx *rtcTxnImpl) GetRtcRetracted() map[string]map[string]model.Tuple {
+	return tx.retracted
+}
+
+func (tx *rtcTxnImpl) GetExplanations() []model.Explanation {
+	return tx.explanations
+}
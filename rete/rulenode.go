@@ -18,10 +18,10 @@ type ruleNodeImpl struct {
 	rule model.Rule
 }
 
-func newRuleNode(nw Network, rule model.Rule) ruleNode {
+//newRuleNode identifiers are those of the node linking to it, in the order of its handles
+func newRuleNode(nw Network, rule model.Rule, identifiers []model.TupleType) ruleNode {
 	rn := ruleNodeImpl{}
-	rn.nodeImpl.initNodeImpl(nw, rule, rule.GetIdentifiers())
-	rn.identifiers = rule.GetIdentifiers()
+	rn.nodeImpl.initNodeImpl(nw, rule, identifiers)
 	rn.rule = rule
 	return &rn
 }
@@ -33,15 +33,26 @@ func (rn *ruleNodeImpl) String() string {
 }
 
 func (rn *ruleNodeImpl) assertObjects(ctx context.Context, handles []reteHandle, isRight bool) {
+	//the match justifies again what it logically asserted, whether the rule may fire again or not
+	getReteCtx(ctx).getNetwork().getTms().matchAsserted(rn.rule, handles)
+	if !getReteCtx(ctx).canActivate(rn.rule, handles) {
+		return
+	}
 
-	tupleMap := copyIntoTupleMap(handles)
+	tupleMap := copyIntoTupleMap(rn.identifiers, handles)
 
 	cr := getReteCtx(ctx).getConflictResolver()
 
-	cr.addAgendaItem(rn.getRule(), tupleMap)
+	cr.addAgendaItem(ctx, rn.getRule(), tupleMap, handles)
 
 }
 
+func (rn *ruleNodeImpl) retractObjects(ctx context.Context, handles []reteHandle, isRight bool) {
+	reteCtxVar := getReteCtx(ctx)
+	reteCtxVar.getConflictResolver().removeAgendaItem(ctx, rn.getRule(), handles)
+	reteCtxVar.getNetwork().getTms().matchRetracted(rn.getRule(), handles)
+}
+
 func (rn *ruleNodeImpl) getRule() model.Rule {
 	return rn.rule
 }
@@ -0,0 +1,134 @@
+package rete
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+//NetworkState is what a network holds between RTCs, see Network.Snapshot
+type NetworkState struct {
+	//the asserted tuples, in the order they were last asserted or modified
+	Tuples []model.Tuple
+	//when the expiring tuples expire, by tuple key
+	Expiries map[string]time.Time
+	//the agenda groups pushed by SetFocus, the focused one last
+	Focus []string
+	//the activations pending on the agenda, of agenda groups without the focus
+	Agenda []ActivationState
+	//the activations justifying the logically asserted tuples
+	Justifications []JustificationState
+}
+
+//ActivationState is an activation of a rule by the keys of its tuples
+type ActivationState struct {
+	Rule string   `json:"rule"`
+	Keys []string `json:"keys"`
+}
+
+//JustificationState is an activation justifying a logically asserted tuple, by the tuple's key
+type JustificationState struct {
+	Tuple string `json:"tuple"`
+	ActivationState
+}
+
+func (nw *reteNetworkImpl) Snapshot() NetworkState {
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+
+	handles := []reteHandle{}
+	for _, h := range nw.store.getHandles() {
+		handles = append(handles, h)
+	}
+	sort.Slice(handles, func(i, j int) bool {
+		return handles[i].getRecency() < handles[j].getRecency()
+	})
+	state := NetworkState{Expiries: map[string]time.Time{}, Focus: nw.cr.getFocusStack()}
+	for _, h := range handles {
+		tuple := h.getTuple()
+		state.Tuples = append(state.Tuples, tuple)
+		if deadline, found := nw.expiries.getDeadline(tuple); found {
+			state.Expiries[tuple.GetKey().String()] = deadline
+		}
+	}
+	for e := nw.store.getAgenda().Front(); e != nil; e = e.Next() {
+		item := e.Value.(agendaItem)
+		state.Agenda = append(state.Agenda, ActivationState{item.GetRule().GetName(), handleKeys(item.getHandles())})
+	}
+	state.Justifications = nw.tms.getJustifications()
+	return state
+}
+
+func (nw *reteNetworkImpl) Restore(ctx context.Context, rs model.RuleSession, state NetworkState, runActions bool) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	nw.assertLock.Lock()
+	defer nw.assertLock.Unlock()
+	if len(nw.store.getHandles()) > 0 {
+		return fmt.Errorf("Cannot restore, tuples are asserted")
+	}
+
+	newCtx, reteCtxVar := newReteCtx(ctx, nw, rs)
+	defer nw.endRtc(newCtx, nw.startRtc(newCtx))
+	for _, agendaGroup := range state.Focus {
+		nw.cr.setFocus(agendaGroup)
+	}
+	for _, tuple := range state.Tuples {
+		nw.assertInternal(newCtx, tuple, nil, ADD, "")
+		if deadline, found := state.Expiries[tuple.GetKey().String()]; found && nw.getHandle(tuple) != nil {
+			nw.expiries.schedule(newCtx, tuple, deadline.Sub(nw.clock.Now()))
+		}
+	}
+	//before actions fire, that logically assert the same tuples again
+	nw.restoreJustifications(state.Justifications)
+	if !runActions {
+		//the other activations fired before the snapshot
+		pending := map[string]bool{}
+		for _, activation := range state.Agenda {
+			pending[activation.Rule+"\x00"+strings.Join(activation.Keys, "\x00")] = true
+		}
+		agenda := nw.store.getAgenda()
+		for e := agenda.Front(); e != nil; {
+			next := e.Next()
+			item := e.Value.(agendaItem)
+			if !pending[item.GetRule().GetName()+"\x00"+strings.Join(handleKeys(item.getHandles()), "\x00")] {
+				nw.store.removeAgendaItem(e)
+				nw.metrics.activationCancelled(item.GetRule())
+				nw.getListeners().activationCancelled(newCtx, item)
+			}
+			e = next
+		}
+		return nil
+	}
+	reteCtxVar.getConflictResolver().resolveConflict(newCtx)
+	if nw.txnHandler != nil {
+		rtcTxn := newRtcTxn(reteCtxVar.getRtcAdded(), reteCtxVar.getRtcModified(), reteCtxVar.getRtcDeleted(), reteCtxVar.getRtcRetracted(), reteCtxVar.getExplanations())
+		nw.txnHandler(ctx, rs, rtcTxn, nw.txnContext)
+	}
+	return nil
+}
+
+//restoreJustifications justifies the logically asserted tuples again, those of a rule no longer in the
+//network or of tuples no longer asserted are dropped
+func (nw *reteNetworkImpl) restoreJustifications(justifications []JustificationState) {
+	for _, js := range justifications {
+		rule := nw.allRules[js.Rule]
+		if rule == nil || nw.store.getHandle(js.Tuple) == nil {
+			continue
+		}
+		handles := []reteHandle{}
+		for _, key := range js.Keys {
+			if h := nw.store.getHandle(key); h != nil {
+				handles = append(handles, h)
+			}
+		}
+		if len(handles) == len(js.Keys) {
+			nw.tms.justify(js.Tuple, rule, handles)
+		}
+	}
+}
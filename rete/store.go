@@ -0,0 +1,144 @@
+package rete
+
+import (
+	"container/list"
+	"sync"
+)
+
+//WorkingMemoryStore keeps the working memory of a network: the handles of the asserted tuples, the rows
+//of the join tables and the agenda. See NewMemoryStore and NewDiskStore
+type WorkingMemoryStore interface {
+	//getHandle may be called outside RTCs, while an RTC puts and deletes handles, see Network.GetAssertedTuple
+	getHandle(key string) reteHandle
+	//putHandle adds the handle of a tuple, or tells its tuple changed
+	putHandle(key string, handle reteHandle)
+	deleteHandle(key string)
+	//getHandles returns the handles by tuple key, not to be changed, and is to be called with the lock of the network held
+	getHandles() map[string]reteHandle
+
+	addRow(jt joinTable, row joinTableRow)
+	removeRow(jt joinTable, row joinTableRow)
+	//getRows returns the rows of the table, not to be changed
+	getRows(jt joinTable) map[joinTableRow]joinTableRow
+	//removeRows forgets the table, its rule got removed
+	removeRows(jt joinTable)
+
+	//getAgenda returns the activations in the order they fire, not to be changed
+	getAgenda() *list.List
+	//insertAgendaItem adds the activation before the element, at the back if nil
+	insertAgendaItem(item agendaItem, mark *list.Element)
+	removeAgendaItem(e *list.Element)
+
+	//load returns the tuples and pending activations a previous network left in the store, the tuples
+	//in the order they were put
+	load() (NetworkState, error)
+	//loaded tells the network asserted again what load returned, the records it did not write again are dropped
+	loaded() error
+
+	//sync makes the changes of the RTC durable at its end, and returns the first error writing them
+	sync() error
+
+	//Close releases the store, the network no longer uses it
+	Close() error
+}
+
+//NewMemoryStore returns the store of networks by default, in memory
+func NewMemoryStore() WorkingMemoryStore {
+	s := memoryStoreImpl{}
+	s.initMemoryStoreImpl()
+	return &s
+}
+
+type memoryStoreImpl struct {
+	//guards handles against reads outside RTCs
+	handlesLock sync.RWMutex
+	handles     map[string]reteHandle
+	rows        map[joinTable]map[joinTableRow]joinTableRow
+	agenda      *list.List
+}
+
+func (s *memoryStoreImpl) initMemoryStoreImpl() {
+	s.handles = make(map[string]reteHandle)
+	s.rows = make(map[joinTable]map[joinTableRow]joinTableRow)
+	s.agenda = list.New()
+}
+
+func (s *memoryStoreImpl) getHandle(key string) reteHandle {
+	s.handlesLock.RLock()
+	defer s.handlesLock.RUnlock()
+	return s.handles[key]
+}
+
+func (s *memoryStoreImpl) putHandle(key string, handle reteHandle) {
+	s.handlesLock.Lock()
+	defer s.handlesLock.Unlock()
+	s.handles[key] = handle
+}
+
+func (s *memoryStoreImpl) deleteHandle(key string) {
+	s.handlesLock.Lock()
+	defer s.handlesLock.Unlock()
+	delete(s.handles, key)
+}
+
+func (s *memoryStoreImpl) getHandles() map[string]reteHandle {
+	return s.handles
+}
+
+func (s *memoryStoreImpl) addRow(jt joinTable, row joinTableRow) {
+	rows := s.rows[jt]
+	if rows == nil {
+		rows = make(map[joinTableRow]joinTableRow)
+		s.rows[jt] = rows
+	}
+	rows[row] = row
+}
+
+func (s *memoryStoreImpl) removeRow(jt joinTable, row joinTableRow) {
+	delete(s.rows[jt], row)
+}
+
+func (s *memoryStoreImpl) getRows(jt joinTable) map[joinTableRow]joinTableRow {
+	rows := s.rows[jt]
+	if rows == nil {
+		//so that callers can range over it
+		return map[joinTableRow]joinTableRow{}
+	}
+	return rows
+}
+
+func (s *memoryStoreImpl) removeRows(jt joinTable) {
+	delete(s.rows, jt)
+}
+
+func (s *memoryStoreImpl) getAgenda() *list.List {
+	return s.agenda
+}
+
+func (s *memoryStoreImpl) insertAgendaItem(item agendaItem, mark *list.Element) {
+	if mark == nil {
+		s.agenda.PushBack(item)
+	} else {
+		s.agenda.InsertBefore(item, mark)
+	}
+}
+
+func (s *memoryStoreImpl) removeAgendaItem(e *list.Element) {
+	s.agenda.Remove(e)
+}
+
+func (s *memoryStoreImpl) load() (NetworkState, error) {
+	return NetworkState{}, nil
+}
+
+func (s *memoryStoreImpl) loaded() error {
+	return nil
+}
+
+func (s *memoryStoreImpl) sync() error {
+	return nil
+}
+
+func (s *memoryStoreImpl) Close() error {
+	return nil
+}
@@ -0,0 +1,220 @@
+package rete
+
+import (
+	"context"
+	"sort"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+//truthMaintenance keeps track of the activations justifying the logically asserted tuples, and
+//retracts a tuple once it has no justification left
+type truthMaintenance interface {
+	isJustified(tupleKey string) bool
+	//justify records the activation as a justification of the tuple, true if it is the first one
+	justify(tupleKey string, rule model.Rule, handles []reteHandle) bool
+	//matchRetracted drops the justifications of an activation whose tuples no longer match
+	matchRetracted(rule model.Rule, handles []reteHandle)
+	//matchAsserted restores the justifications the activation lost since the last retractUnjustified,
+	//its tuples match again as a modify reasserts them
+	matchAsserted(rule model.Rule, handles []reteHandle)
+	//tupleRetracted drops the justifications using the tuple, only those of rules depending on
+	//the changed properties if it is modified
+	tupleRetracted(tuple model.Tuple, changedProps map[string]bool)
+	//retractUnjustified deletes the tuples left without a justification, at the end of an operation so that
+	//the reassert of a modify may restore them
+	retractUnjustified(ctx context.Context)
+	//getJustifications returns the justifications of the logically asserted tuples, see NetworkState
+	getJustifications() []JustificationState
+}
+
+//justification is an activation that logically asserted a tuple
+type justification struct {
+	rule model.Rule
+	//the keys of the activation's tuples
+	tupleKeys map[string]bool
+	//the same in the order of the identifiers of the rule node
+	keys []string
+}
+
+type tmsImpl struct {
+	nw Network
+	//the justifications of each logically asserted tuple, by tuple key and activation key
+	justifications map[string]map[string]justification
+	//the logically asserted tuples the justifications using a tuple support, by tuple key
+	supported map[string]map[string]bool
+	//tuple keys left without a justification, not yet retracted
+	unjustified []string
+	//the justifications dropped since the last retractUnjustified, by tuple key and activation key
+	dropped map[string]map[string]justification
+}
+
+func newTms(nw Network) truthMaintenance {
+	tms := tmsImpl{}
+	tms.initTmsImpl(nw)
+	return &tms
+}
+
+func (tms *tmsImpl) initTmsImpl(nw Network) {
+	tms.nw = nw
+	tms.justifications = make(map[string]map[string]justification)
+	tms.supported = make(map[string]map[string]bool)
+	tms.dropped = make(map[string]map[string]justification)
+}
+
+func (tms *tmsImpl) isJustified(tupleKey string) bool {
+	return tms.justifications[tupleKey] != nil
+}
+
+func (tms *tmsImpl) justify(tupleKey string, rule model.Rule, handles []reteHandle) bool {
+	first := !tms.isJustified(tupleKey)
+	if first {
+		tms.justifications[tupleKey] = make(map[string]justification)
+	}
+	j := justification{rule, make(map[string]bool), handleKeys(handles)}
+	for _, h := range handles {
+		j.tupleKeys[h.getTuple().GetKey().String()] = true
+	}
+	tms.addJustification(tupleKey, activationKey(rule, handles), j)
+	return first
+}
+
+func (tms *tmsImpl) matchRetracted(rule model.Rule, handles []reteHandle) {
+	if len(handles) == 0 {
+		return
+	}
+	activation := activationKey(rule, handles)
+	for tupleKey := range tms.supported[handles[0].getTuple().GetKey().String()] {
+		if _, found := tms.justifications[tupleKey][activation]; found {
+			tms.unjustify(tupleKey, activation)
+		}
+	}
+}
+
+func (tms *tmsImpl) matchAsserted(rule model.Rule, handles []reteHandle) {
+	if len(tms.dropped) == 0 {
+		return
+	}
+	activation := activationKey(rule, handles)
+	for tupleKey, dropped := range tms.dropped {
+		if j, found := dropped[activation]; found && tms.justifications[tupleKey] != nil {
+			delete(dropped, activation)
+			tms.addJustification(tupleKey, activation, j)
+		}
+	}
+}
+
+func (tms *tmsImpl) tupleRetracted(tuple model.Tuple, changedProps map[string]bool) {
+	key := tuple.GetKey().String()
+	for tupleKey := range tms.supported[key] {
+		for activation, j := range tms.justifications[tupleKey] {
+			if j.tupleKeys[key] && dependsOnChange(j.rule, tuple.GetTupleType(), changedProps) {
+				tms.unjustify(tupleKey, activation)
+			}
+		}
+	}
+	if changedProps == nil {
+		//a logically asserted tuple retracted by other means is no longer maintained
+		tms.forget(key)
+	}
+}
+
+func (tms *tmsImpl) retractUnjustified(ctx context.Context) {
+	for len(tms.unjustified) > 0 {
+		tupleKey := tms.unjustified[0]
+		tms.unjustified = tms.unjustified[1:]
+		if len(tms.justifications[tupleKey]) > 0 {
+			continue
+		}
+		tms.forget(tupleKey)
+		tuple := tms.nw.GetAssertedTupleByStringKey(tupleKey)
+		if tuple != nil {
+			newDeleteEntry(tuple, DELETE, nil).execute(ctx)
+		}
+	}
+	tms.dropped = make(map[string]map[string]justification)
+}
+
+func (tms *tmsImpl) getJustifications() []JustificationState {
+	states := []JustificationState{}
+	for tupleKey, justifications := range tms.justifications {
+		for _, j := range justifications {
+			states = append(states, JustificationState{tupleKey, ActivationState{j.rule.GetName(), j.keys}})
+		}
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Tuple != states[j].Tuple {
+			return states[i].Tuple < states[j].Tuple
+		}
+		return activationKeyOf(states[i].Rule, states[i].Keys) < activationKeyOf(states[j].Rule, states[j].Keys)
+	})
+	return states
+}
+
+func (tms *tmsImpl) addJustification(tupleKey string, activation string, j justification) {
+	for key := range j.tupleKeys {
+		if tms.supported[key] == nil {
+			tms.supported[key] = make(map[string]bool)
+		}
+		tms.supported[key][tupleKey] = true
+	}
+	tms.justifications[tupleKey][activation] = j
+}
+
+//unjustify drops a justification, the tuple is retracted by retractUnjustified if it was the last
+func (tms *tmsImpl) unjustify(tupleKey string, activation string) {
+	j := tms.justifications[tupleKey][activation]
+	delete(tms.justifications[tupleKey], activation)
+	if tms.dropped[tupleKey] == nil {
+		tms.dropped[tupleKey] = make(map[string]justification)
+	}
+	tms.dropped[tupleKey][activation] = j
+	for key := range j.tupleKeys {
+		if !tms.usedBy(key, tupleKey) {
+			tms.unsupport(key, tupleKey)
+		}
+	}
+	if len(tms.justifications[tupleKey]) == 0 {
+		tms.unjustified = append(tms.unjustified, tupleKey)
+	}
+}
+
+func (tms *tmsImpl) forget(tupleKey string) {
+	for _, j := range tms.justifications[tupleKey] {
+		for key := range j.tupleKeys {
+			tms.unsupport(key, tupleKey)
+		}
+	}
+	delete(tms.justifications, tupleKey)
+}
+
+//usedBy tells if a justification of the logically asserted tuple uses the tuple
+func (tms *tmsImpl) usedBy(key string, tupleKey string) bool {
+	for _, j := range tms.justifications[tupleKey] {
+		if j.tupleKeys[key] {
+			return true
+		}
+	}
+	return false
+}
+
+func (tms *tmsImpl) unsupport(key string, tupleKey string) {
+	delete(tms.supported[key], tupleKey)
+	if len(tms.supported[key]) == 0 {
+		delete(tms.supported, key)
+	}
+}
+
+//dependsOnChange tells if the rule depends on any of the changed properties of the tuple type,
+//nil changedProps meaning the tuple is retracted
+func dependsOnChange(rule model.Rule, tupleType model.TupleType, changedProps map[string]bool) bool {
+	if changedProps == nil {
+		return true
+	}
+	for prop := range rule.GetDeps()[tupleType] {
+		if changedProps[prop] {
+			return true
+		}
+	}
+	return false
+}
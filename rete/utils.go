@@ -4,28 +4,42 @@ package rete
 
 import "github.com/project-flogo/rules/common/model"
 
-func copyIntoTupleArray(handles []reteHandle) []model.Tuple {
-	tuples := make([]model.Tuple, len(handles))
+//copyIntoTupleMap keys the handles' tuples by the identifiers they are bound to
+func copyIntoTupleMap(identifiers []model.TupleType, handles []reteHandle) map[model.TupleType]model.Tuple {
+	tupleMap := map[model.TupleType]model.Tuple{}
 	for i := 0; i < len(handles); i++ {
-		tuples[i] = handles[i].getTuple()
+		tupleMap[identifiers[i]] = handles[i].getTuple()
 	}
-	return tuples
+	return tupleMap
 }
 
-func copyIntoTupleMap(handles []reteHandle) map[model.TupleType]model.Tuple {
-	tupleMap := map[model.TupleType]model.Tuple{}
-	tuples := make([]model.Tuple, len(handles))
-	for i := 0; i < len(handles); i++ {
-		tuples[i] = handles[i].getTuple()
-		tupleMap[tuples[i].GetTupleType()] = tuples[i] //assuming no self-joins! need to correct this!
+//hasSelfMatch is true if the same handle is bound to more than one identifier
+func hasSelfMatch(handles []reteHandle) bool {
+	for i := range handles {
+		for j := i + 1; j < len(handles); j++ {
+			if handles[i] == handles[j] {
+				return true
+			}
+		}
 	}
-	return tupleMap
+	return false
 }
 
-func convertToTupleMap(tuples []model.Tuple) map[model.TupleType]model.Tuple {
-	tupleMap := map[model.TupleType]model.Tuple{}
-	for i := 0; i < len(tuples); i++ {
-		tupleMap[tuples[i].GetTupleType()] = tuples[i]
+//sameHandles is true if both hold the same handles in the same order
+func sameHandles(handles []reteHandle, others []reteHandle) bool {
+	if len(handles) != len(others) {
+		return false
 	}
-	return tupleMap
+	for i := range handles {
+		if handles[i] != others[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func appendHandles(handles []reteHandle, others []reteHandle) []reteHandle {
+	appended := make([]reteHandle, 0, len(handles)+len(others))
+	appended = append(appended, handles...)
+	return append(appended, others...)
 }
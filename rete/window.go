@@ -0,0 +1,188 @@
+package rete
+
+import (
+	"container/list"
+	"context"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+//windows deletes the tuples leaving the windows of their types, see model.WindowDescriptor
+type windows interface {
+	//admit makes room in the tuple's window, then adds it
+	admit(ctx context.Context, tuple model.Tuple)
+	//remove forgets a retracted tuple
+	remove(tuple model.Tuple)
+	//expire deletes the tuples of the type's window whose time is over
+	expire(ctx context.Context, tupleType model.TupleType)
+	//stop cancels the timers, the tuples no longer leave their windows in time
+	stop()
+}
+
+type windowEntry struct {
+	tuple    model.Tuple
+	groupKey string
+	//when the tuple's time is over, zero for none
+	deadline time.Time
+}
+
+//window holds the tuples of a type in the order they were admitted, by group
+type window struct {
+	wd     *model.WindowDescriptor
+	groups map[string]*list.List
+	//expires the window at its earliest deadline
+	timer    model.Timer
+	deadline time.Time
+}
+
+type windowsImpl struct {
+	nw      Network
+	windows map[model.TupleType]*window
+	//the elements of the admitted tuples in their groups, by tuple key
+	entries map[string]*list.Element
+	stopped bool
+}
+
+func newWindows(nw Network) windows {
+	w := windowsImpl{}
+	w.initWindowsImpl(nw)
+	return &w
+}
+
+func (w *windowsImpl) initWindowsImpl(nw Network) {
+	w.nw = nw
+	w.windows = make(map[model.TupleType]*window)
+	w.entries = make(map[string]*list.Element)
+}
+
+func (w *windowsImpl) admit(ctx context.Context, tuple model.Tuple) {
+	td := tuple.GetTupleDescriptor()
+	if td.Window == nil || w.entries[tuple.GetKey().String()] != nil {
+		return
+	}
+	win := w.windows[tuple.GetTupleType()]
+	if win == nil {
+		win = &window{wd: td.Window, groups: make(map[string]*list.List)}
+		w.windows[tuple.GetTupleType()] = win
+	}
+	w.expire(ctx, tuple.GetTupleType())
+
+	groupKey := ""
+	if win.wd.GroupBy != "" {
+		groupKey = model.ValueKey(tuple.GetMap()[win.wd.GroupBy])
+	}
+	if group := win.groups[groupKey]; group != nil && win.wd.Length > 0 && group.Len() >= win.wd.Length {
+		evicted := []model.Tuple{}
+		for e := group.Front(); e != nil; e = e.Next() {
+			if win.wd.Type != model.TumblingWindow && group.Len()-len(evicted) < win.wd.Length {
+				break
+			}
+			evicted = append(evicted, e.Value.(*windowEntry).tuple)
+		}
+		w.evict(ctx, evicted)
+	}
+	group := win.groups[groupKey]
+	if group == nil {
+		group = list.New()
+		win.groups[groupKey] = group
+	}
+
+	entry := &windowEntry{tuple: tuple, groupKey: groupKey}
+	if duration := win.wd.GetDuration(); duration > 0 {
+		start := model.GetEventTime(tuple).Start
+		if win.wd.Type == model.TumblingWindow {
+			start = start.Truncate(duration)
+		}
+		entry.deadline = start.Add(duration)
+	}
+	w.entries[tuple.GetKey().String()] = group.PushBack(entry)
+	w.schedule(ctx, tuple.GetTupleType(), win, entry.deadline)
+}
+
+func (w *windowsImpl) remove(tuple model.Tuple) {
+	key := tuple.GetKey().String()
+	e := w.entries[key]
+	if e == nil {
+		return
+	}
+	delete(w.entries, key)
+	win := w.windows[tuple.GetTupleType()]
+	groupKey := e.Value.(*windowEntry).groupKey
+	group := win.groups[groupKey]
+	group.Remove(e)
+	if group.Len() == 0 {
+		delete(win.groups, groupKey)
+	}
+}
+
+func (w *windowsImpl) expire(ctx context.Context, tupleType model.TupleType) {
+	win := w.windows[tupleType]
+	if win == nil || w.stopped {
+		return
+	}
+	now := w.nw.GetClock().Now()
+	expired := []model.Tuple{}
+	next := time.Time{}
+	for _, group := range win.groups {
+		for e := group.Front(); e != nil; e = e.Next() {
+			entry := e.Value.(*windowEntry)
+			if entry.deadline.IsZero() {
+				continue
+			}
+			if !entry.deadline.After(now) {
+				expired = append(expired, entry.tuple)
+			} else if next.IsZero() || entry.deadline.Before(next) {
+				next = entry.deadline
+			}
+		}
+	}
+	for _, tuple := range expired {
+		if w.nw.getHandle(tuple) != nil {
+			w.nw.getListeners().tupleExpired(ctx, tuple)
+		}
+	}
+	w.evict(ctx, expired)
+	if win.timer != nil && !win.deadline.Equal(next) {
+		win.timer.Stop()
+		win.timer = nil
+	}
+	w.schedule(ctx, tupleType, win, next)
+}
+
+//schedule expires the window at the deadline, unless it is due to expire before
+func (w *windowsImpl) schedule(ctx context.Context, tupleType model.TupleType, win *window, deadline time.Time) {
+	if w.stopped || deadline.IsZero() || win.timer != nil && !deadline.Before(win.deadline) {
+		return
+	}
+	if win.timer != nil {
+		win.timer.Stop()
+	}
+	rs := getReteCtx(ctx).getRuleSession()
+	clock := w.nw.GetClock()
+	win.deadline = deadline
+	win.timer = clock.AfterFunc(deadline.Sub(clock.Now()), func() {
+		w.nw.expireWindow(rs, tupleType)
+	})
+}
+
+func (w *windowsImpl) stop() {
+	w.stopped = true
+	for _, win := range w.windows {
+		if win.timer != nil {
+			win.timer.Stop()
+			win.timer = nil
+		}
+	}
+}
+
+//evict deletes the tuples in the current RTC
+func (w *windowsImpl) evict(ctx context.Context, tuples []model.Tuple) {
+	for _, tuple := range tuples {
+		if w.nw.getHandle(tuple) != nil {
+			newDeleteEntry(tuple, DELETE, nil).execute(ctx)
+		} else {
+			w.remove(tuple)
+		}
+	}
+}
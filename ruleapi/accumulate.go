@@ -0,0 +1,184 @@
+package ruleapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/project-flogo/core/data"
+	"github.com/project-flogo/rules/common/model"
+)
+
+type accumulateImpl struct {
+	name       string
+	fn         model.AccumulateFunction
+	rule       *ruleImpl
+	identifier model.TupleType
+	property   string
+	groupBy    string
+	conditions []model.Condition
+}
+
+func newAccumulate(name string, fn model.AccumulateFunction, rule *ruleImpl, identifier model.TupleType, property string, groupBy string) model.MutableAccumulate {
+	acc := accumulateImpl{}
+	acc.initAccumulateImpl(name, fn, rule, identifier, property, groupBy)
+	return &acc
+}
+
+func (acc *accumulateImpl) initAccumulateImpl(name string, fn model.AccumulateFunction, rule *ruleImpl, identifier model.TupleType, property string, groupBy string) {
+	acc.name = name
+	acc.fn = fn
+	acc.rule = rule
+	acc.identifier = identifier
+	acc.property = property
+	acc.groupBy = groupBy
+	acc.conditions = []model.Condition{}
+}
+
+func (acc *accumulateImpl) GetName() string {
+	return acc.name
+}
+
+func (acc *accumulateImpl) GetFunction() model.AccumulateFunction {
+	return acc.fn
+}
+
+func (acc *accumulateImpl) GetRule() model.Rule {
+	return acc.rule
+}
+
+func (acc *accumulateImpl) GetIdentifier() model.TupleType {
+	return acc.identifier
+}
+
+func (acc *accumulateImpl) GetProperty() string {
+	return acc.property
+}
+
+func (acc *accumulateImpl) GetGroupBy() string {
+	return acc.groupBy
+}
+
+func (acc *accumulateImpl) GetConditions() []model.Condition {
+	return acc.conditions
+}
+
+func (acc *accumulateImpl) String() string {
+	str := "[Accumulate: " + acc.name + " = " + string(acc.fn) + "(" + string(acc.identifier)
+	if acc.property != "" {
+		str += "." + acc.property
+	}
+	str += ")"
+	if acc.groupBy != "" {
+		str += " by " + string(acc.identifier) + "." + acc.groupBy
+	}
+	str += "\n"
+	for _, cond := range acc.conditions {
+		str += "\t\t" + cond.String() + "\n"
+	}
+	return str + "\t]"
+}
+
+func (acc *accumulateImpl) AddCondition(conditionName string, idrs []string, cFn model.ConditionEvaluator, ctx model.RuleContext) (err error) {
+	typeDeps, err := acc.rule.addDeps(idrs)
+	if err != nil {
+		return err
+	}
+	err = acc.validateIdentifiers(conditionName, typeDeps)
+	if err != nil {
+		return err
+	}
+	acc.conditions = append(acc.conditions, newCondition(conditionName, acc.rule, typeDeps, cFn, ctx))
+	return nil
+}
+
+func (acc *accumulateImpl) AddExprCondition(conditionName string, cExpr string, ctx model.RuleContext) error {
+	refs := getRefs(cExpr)
+	err := acc.rule.validateRefs(refs)
+	if err != nil {
+		return err
+	}
+	typeDeps, err := acc.rule.addDeps(refs)
+	if err != nil {
+		return err
+	}
+	err = acc.validateIdentifiers(conditionName, typeDeps)
+	if err != nil {
+		return err
+	}
+	acc.conditions = append(acc.conditions, newExprCondition(conditionName, acc.rule, typeDeps, cExpr, ctx))
+	return nil
+}
+
+//validateIdentifiers allows only the accumulated identifier in the accumulate's conditions
+func (acc *accumulateImpl) validateIdentifiers(conditionName string, idrs []model.TupleType) error {
+	for _, idr := range idrs {
+		if idr != acc.identifier {
+			return fmt.Errorf("Condition [%s] of accumulate [%s] can only refer to [%s], not [%s]",
+				conditionName, acc.name, string(acc.identifier), string(idr))
+		}
+	}
+	return nil
+}
+
+//parseAccumulateProp splits "idr.prop" into its identifier and property, the property is optional
+func (rule *ruleImpl) parseAccumulateProp(idrProp string) (model.TupleType, string, error) {
+	aliasProp := strings.Split(idrProp, ".")
+	if len(aliasProp) > 2 {
+		return "", "", fmt.Errorf("Expected [identifier.property], got [%s]", idrProp)
+	}
+	idr, err := rule.resolveIdentifier(aliasProp[0])
+	if err != nil {
+		return "", "", err
+	}
+	if len(aliasProp) == 1 {
+		return idr, "", nil
+	}
+	td := rule.getTupleDescriptor(rule.GetIdentifierType(idr))
+	if td.GetProperty(aliasProp[1]) == nil {
+		return "", "", fmt.Errorf("TupleType property not found [%s]", aliasProp[1])
+	}
+	return idr, aliasProp[1], nil
+}
+
+//accumulateTupleDescriptor returns the type of the accumulate's result tuples, see model.Accumulate. It
+//fails if another type of the name is registered
+func accumulateTupleDescriptor(name string, fn model.AccumulateFunction) (*model.TupleDescriptor, error) {
+	valueType := data.TypeFloat64
+	switch fn {
+	case model.CountFunction:
+		valueType = data.TypeInt
+	case model.CollectFunction:
+		valueType = data.TypeArray
+	}
+	td := model.TupleDescriptor{
+		Name:         name,
+		TTLInSeconds: -1,
+		Props: []model.TuplePropertyDescriptor{
+			{Name: "key", PropType: data.TypeString, KeyIndex: 0},
+			{Name: "value", PropType: valueType, KeyIndex: -1},
+			{Name: "count", PropType: data.TypeInt, KeyIndex: -1},
+		},
+	}
+	if existing := model.GetTupleDescriptor(model.TupleType(name)); existing != nil && !reflect.DeepEqual(existing.Props, td.Props) {
+		return nil, fmt.Errorf("Tuple type [%s] already exists", name)
+	}
+	return &td, nil
+}
+
+//registerAccumulateTupleDescriptors registers the types of the result tuples of the rule's accumulates,
+//as it is added to a session
+func registerAccumulateTupleDescriptors(rule model.Rule) error {
+	for _, acc := range rule.GetAccumulates() {
+		td, err := accumulateTupleDescriptor(acc.GetName(), acc.GetFunction())
+		if err != nil {
+			return err
+		}
+		if model.GetTupleDescriptor(model.TupleType(td.Name)) == nil {
+			if err = model.RegisterTupleDescriptorsFromTds([]model.TupleDescriptor{*td}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
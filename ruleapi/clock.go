@@ -0,0 +1,107 @@
+package ruleapi
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+//NewRealtimeClock returns the clock of the system, the default of rule sessions
+func NewRealtimeClock() model.Clock {
+	return &realtimeClockImpl{}
+}
+
+type realtimeClockImpl struct {
+}
+
+func (c *realtimeClockImpl) Now() time.Time {
+	return time.Now()
+}
+
+func (c *realtimeClockImpl) AfterFunc(d time.Duration, f func()) model.Timer {
+	return time.AfterFunc(d, f)
+}
+
+//PseudoClock only moves when advanced, to test timed rules deterministically
+type PseudoClock interface {
+	model.Clock
+	//Advance moves the clock forward by d, running the functions due in time order in the caller's goroutine.
+	//Not to be called from an action
+	Advance(d time.Duration)
+}
+
+//NewPseudoClock returns a clock set to start
+func NewPseudoClock(start time.Time) PseudoClock {
+	c := pseudoClockImpl{}
+	c.initPseudoClockImpl(start)
+	return &c
+}
+
+type pseudoClockImpl struct {
+	lock sync.Mutex
+	now  time.Time
+	//pending timers, by deadline and in the order they were scheduled
+	timers []*pseudoTimer
+}
+
+type pseudoTimer struct {
+	clock    *pseudoClockImpl
+	deadline time.Time
+	f        func()
+}
+
+func (c *pseudoClockImpl) initPseudoClockImpl(start time.Time) {
+	c.now = start
+}
+
+func (c *pseudoClockImpl) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *pseudoClockImpl) AfterFunc(d time.Duration, f func()) model.Timer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	timer := &pseudoTimer{c, c.now.Add(d), f}
+	i := sort.Search(len(c.timers), func(i int) bool {
+		return c.timers[i].deadline.After(timer.deadline)
+	})
+	c.timers = append(c.timers, nil)
+	copy(c.timers[i+1:], c.timers[i:])
+	c.timers[i] = timer
+	return timer
+}
+
+func (c *pseudoClockImpl) Advance(d time.Duration) {
+	c.lock.Lock()
+	to := c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].deadline.After(to) {
+		timer := c.timers[0]
+		c.timers = c.timers[1:]
+		if timer.deadline.After(c.now) {
+			c.now = timer.deadline
+		}
+		//the function may schedule others
+		c.lock.Unlock()
+		timer.f()
+		c.lock.Lock()
+	}
+	c.now = to
+	c.lock.Unlock()
+}
+
+func (t *pseudoTimer) Stop() bool {
+	c := t.clock
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
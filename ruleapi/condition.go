@@ -1,7 +1,9 @@
 package ruleapi
 
 import (
+	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/project-flogo/rules/common/model"
 )
@@ -45,7 +47,7 @@ func (cnd *conditionImpl) GetContext() model.RuleContext {
 //}
 
 func (cnd *conditionImpl) String() string {
-	return "[Condition: name:" + cnd.name + ", idrs: TODO]"
+	return "[Condition: name:" + cnd.name + ", idrs: " + model.IdentifiersToString(cnd.identifiers) + "]"
 }
 
 func (cnd *conditionImpl) GetName() string {
@@ -67,3 +69,77 @@ func (cnd *conditionImpl) Evaluate(condName string, ruleNm string, tuples map[mo
 
 	return result, nil
 }
+
+//equiJoinConditionImpl is a condition that passes when two properties of two identifiers are equal
+type equiJoinConditionImpl struct {
+	conditionImpl
+	equiJoin model.EquiJoin
+}
+
+func newEquiJoinCondition(name string, rule model.Rule, equiJoin model.EquiJoin, ctx model.RuleContext) model.Condition {
+	c := equiJoinConditionImpl{}
+	c.initConditionImpl(name, rule, []model.TupleType{equiJoin.Left, equiJoin.Right}, nil, ctx)
+	c.equiJoin = equiJoin
+	return &c
+}
+
+func (cnd *equiJoinConditionImpl) GetEquiJoins() []model.EquiJoin {
+	return []model.EquiJoin{cnd.equiJoin}
+}
+
+func (cnd *equiJoinConditionImpl) GetProperties() map[model.TupleType][]string {
+	return map[model.TupleType][]string{cnd.equiJoin.Left: {cnd.equiJoin.LeftProp}, cnd.equiJoin.Right: {cnd.equiJoin.RightProp}}
+}
+
+func (cnd *equiJoinConditionImpl) String() string {
+	return "[Condition: name:" + cnd.name + ", " + string(cnd.equiJoin.Left) + "." + cnd.equiJoin.LeftProp +
+		" == " + string(cnd.equiJoin.Right) + "." + cnd.equiJoin.RightProp + "]"
+}
+
+func (cnd *equiJoinConditionImpl) Evaluate(condName string, ruleNm string, tuples map[model.TupleType]model.Tuple, ctx model.RuleContext) (bool, error) {
+	left := tuples[cnd.equiJoin.Left]
+	right := tuples[cnd.equiJoin.Right]
+	if left == nil || right == nil {
+		return false, nil
+	}
+	lv := left.GetMap()[cnd.equiJoin.LeftProp]
+	rv := right.GetMap()[cnd.equiJoin.RightProp]
+	if lv == nil || rv == nil {
+		return lv == nil && rv == nil, nil
+	}
+	return model.ValueKey(lv) == model.ValueKey(rv), nil
+}
+
+//temporalConditionImpl is a condition that passes when the events of two identifiers relate by a temporal operator
+type temporalConditionImpl struct {
+	conditionImpl
+	left     model.TupleType
+	operator model.TemporalOperator
+	right    model.TupleType
+	bounds   []time.Duration
+}
+
+func newTemporalCondition(name string, rule model.Rule, left model.TupleType, operator model.TemporalOperator,
+	right model.TupleType, bounds []time.Duration, ctx model.RuleContext) model.Condition {
+	c := temporalConditionImpl{}
+	c.initConditionImpl(name, rule, []model.TupleType{left, right}, nil, ctx)
+	c.left = left
+	c.operator = operator
+	c.right = right
+	c.bounds = bounds
+	return &c
+}
+
+func (cnd *temporalConditionImpl) String() string {
+	return "[Condition: name:" + cnd.name + ", " + string(cnd.left) + " " + string(cnd.operator) +
+		fmt.Sprint(cnd.bounds) + " " + string(cnd.right) + "]"
+}
+
+func (cnd *temporalConditionImpl) Evaluate(condName string, ruleNm string, tuples map[model.TupleType]model.Tuple, ctx model.RuleContext) (bool, error) {
+	left := tuples[cnd.left]
+	right := tuples[cnd.right]
+	if left == nil || right == nil {
+		return false, nil
+	}
+	return cnd.operator.Eval(model.GetEventTime(left), model.GetEventTime(right), cnd.bounds...)
+}
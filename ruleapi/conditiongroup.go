@@ -0,0 +1,109 @@
+package ruleapi
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+type conditionGroupImpl struct {
+	name        string
+	groupType   model.ConditionGroupType
+	rule        *ruleImpl
+	identifiers []model.TupleType
+	conditions  []model.Condition
+}
+
+func newConditionGroup(name string, groupType model.ConditionGroupType, rule *ruleImpl, identifiers []model.TupleType) model.MutableConditionGroup {
+	g := conditionGroupImpl{}
+	g.initConditionGroupImpl(name, groupType, rule, identifiers)
+	return &g
+}
+
+func (g *conditionGroupImpl) initConditionGroupImpl(name string, groupType model.ConditionGroupType, rule *ruleImpl, identifiers []model.TupleType) {
+	if name == "" {
+		name = "g_" + strconv.Itoa(len(rule.GetConditionGroups())+1)
+	}
+	g.name = name
+	g.groupType = groupType
+	g.rule = rule
+	g.identifiers = append(g.identifiers, identifiers...)
+	g.conditions = []model.Condition{}
+}
+
+func (g *conditionGroupImpl) GetName() string {
+	return g.name
+}
+
+func (g *conditionGroupImpl) GetGroupType() model.ConditionGroupType {
+	return g.groupType
+}
+
+func (g *conditionGroupImpl) GetRule() model.Rule {
+	return g.rule
+}
+
+func (g *conditionGroupImpl) GetIdentifiers() []model.TupleType {
+	return g.identifiers
+}
+
+func (g *conditionGroupImpl) GetConditions() []model.Condition {
+	return g.conditions
+}
+
+func (g *conditionGroupImpl) String() string {
+	str := "[" + string(g.groupType) + " Group: " + g.name + ", idrs: " + model.IdentifiersToString(g.identifiers) + "\n"
+	for _, cond := range g.conditions {
+		str += "\t\t" + cond.String() + "\n"
+	}
+	return str + "\t]"
+}
+
+func (g *conditionGroupImpl) AddCondition(conditionName string, idrs []string, cFn model.ConditionEvaluator, ctx model.RuleContext) (err error) {
+	typeDeps, err := g.rule.addDeps(idrs)
+	if err != nil {
+		return err
+	}
+	return g.addCondition(newCondition(conditionName, g.rule, typeDeps, cFn, ctx))
+}
+
+func (g *conditionGroupImpl) AddExprCondition(conditionName string, cExpr string, ctx model.RuleContext) error {
+	typeDeps, err := g.rule.addExprDeps(cExpr)
+	if err != nil {
+		return err
+	}
+	return g.addCondition(newExprCondition(conditionName, g.rule, typeDeps, cExpr, ctx))
+}
+
+func (g *conditionGroupImpl) AddEquiJoinCondition(conditionName string, leftProp string, rightProp string, ctx model.RuleContext) error {
+	condition, err := g.rule.newEquiJoinCondition(conditionName, leftProp, rightProp, ctx)
+	if err != nil {
+		return err
+	}
+	return g.addCondition(condition)
+}
+
+func (g *conditionGroupImpl) AddTemporalCondition(conditionName string, left string, operator model.TemporalOperator, right string,
+	ctx model.RuleContext, bounds ...time.Duration) error {
+	condition, err := g.rule.newTemporalCondition(conditionName, left, operator, right, ctx, bounds)
+	if err != nil {
+		return err
+	}
+	return g.addCondition(condition)
+}
+
+//addCondition adds the condition to the group, and identifiers of the condition not quantified by the group to the rule
+func (g *conditionGroupImpl) addCondition(condition model.Condition) error {
+	outerIdrs := []model.TupleType{}
+	for _, idr := range condition.GetIdentifiers() {
+		if found, _ := model.Contains(g.identifiers, idr); !found {
+			outerIdrs = append(outerIdrs, idr)
+		}
+	}
+	if err := g.rule.AddIdrsToRule(outerIdrs); err != nil {
+		return err
+	}
+	g.conditions = append(g.conditions, condition)
+	return nil
+}
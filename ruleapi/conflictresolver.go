@@ -0,0 +1,141 @@
+package ruleapi
+
+import (
+	"errors"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+//Built-in conflict resolvers, all but FIFO and LIFO fire rules of a lower priority first
+const (
+	//SalienceResolver fires the most recent activation first, the default
+	SalienceResolver = "salience"
+	//LexResolver fires the activation with the most recent tuples first, comparing their recencies
+	//the most recent first, then the more specific rule
+	LexResolver = "lex"
+	//MeaResolver fires the activation whose tuple bound to the rule's first identifier is the most
+	//recent first, then as LexResolver
+	MeaResolver = "mea"
+	//SpecificityResolver fires the activation of the rule with the most conditions first
+	SpecificityResolver = "specificity"
+	//FifoResolver fires activations in the order they were made, ignoring priorities
+	FifoResolver = "fifo"
+	//LifoResolver fires the most recent activation first, ignoring priorities
+	LifoResolver = "lifo"
+)
+
+var conflictResolvers = map[string]model.ConflictResolver{}
+
+func init() {
+	for _, resolver := range []model.ConflictResolver{
+		newConflictResolver(SalienceResolver, byPriority, byRecency),
+		newConflictResolver(LexResolver, byPriority, byTupleRecencies, bySpecificity, byRecency),
+		newConflictResolver(MeaResolver, byPriority, byFirstTupleRecency, byTupleRecencies, bySpecificity, byRecency),
+		newConflictResolver(SpecificityResolver, byPriority, bySpecificity, byRecency),
+		newConflictResolver(FifoResolver, byAge),
+		newConflictResolver(LifoResolver, byRecency),
+	} {
+		conflictResolvers[resolver.GetName()] = resolver
+	}
+}
+
+//RegisterConflictResolver registers a resolver so that it can be selected by name in the JSON config
+func RegisterConflictResolver(resolver model.ConflictResolver) error {
+	if resolver == nil {
+		return errors.New("cannot register 'nil' ConflictResolver")
+	}
+	if _, dup := conflictResolvers[resolver.GetName()]; dup {
+		return errors.New("ConflictResolver already registered: " + resolver.GetName())
+	}
+	conflictResolvers[resolver.GetName()] = resolver
+	return nil
+}
+
+//GetConflictResolver returns the built-in or registered resolver, nil if not found
+func GetConflictResolver(name string) model.ConflictResolver {
+	return conflictResolvers[name]
+}
+
+//compares two activations, negative if a fires first, positive if b does, zero if they tie
+type activationComparison func(a model.Activation, b model.Activation) int
+
+//conflictResolverImpl orders activations by the first of its comparisons that does not tie
+type conflictResolverImpl struct {
+	name        string
+	comparisons []activationComparison
+}
+
+func newConflictResolver(name string, comparisons ...activationComparison) model.ConflictResolver {
+	cr := conflictResolverImpl{}
+	cr.initConflictResolverImpl(name, comparisons)
+	return &cr
+}
+
+func (cr *conflictResolverImpl) initConflictResolverImpl(name string, comparisons []activationComparison) {
+	cr.name = name
+	cr.comparisons = comparisons
+}
+
+func (cr *conflictResolverImpl) GetName() string {
+	return cr.name
+}
+
+func (cr *conflictResolverImpl) Less(a model.Activation, b model.Activation) bool {
+	for _, compare := range cr.comparisons {
+		if c := compare(a, b); c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+func byPriority(a model.Activation, b model.Activation) int {
+	return a.GetRule().GetPriority() - b.GetRule().GetPriority()
+}
+
+func byRecency(a model.Activation, b model.Activation) int {
+	return b.GetRecency() - a.GetRecency()
+}
+
+func byAge(a model.Activation, b model.Activation) int {
+	return a.GetRecency() - b.GetRecency()
+}
+
+//byTupleRecencies compares the most recent tuples first, an activation with more tuples wins a tie
+func byTupleRecencies(a model.Activation, b model.Activation) int {
+	aRecencies, bRecencies := a.GetTupleRecencies(), b.GetTupleRecencies()
+	for i := 0; i < len(aRecencies) && i < len(bRecencies); i++ {
+		if aRecencies[i] != bRecencies[i] {
+			return bRecencies[i] - aRecencies[i]
+		}
+	}
+	return len(bRecencies) - len(aRecencies)
+}
+
+func byFirstTupleRecency(a model.Activation, b model.Activation) int {
+	return firstTupleRecency(b) - firstTupleRecency(a)
+}
+
+func firstTupleRecency(activation model.Activation) int {
+	idrs := activation.GetRule().GetIdentifiers()
+	if len(idrs) == 0 {
+		return 0
+	}
+	return activation.GetTupleRecency(idrs[0])
+}
+
+func bySpecificity(a model.Activation, b model.Activation) int {
+	return specificity(b.GetRule()) - specificity(a.GetRule())
+}
+
+//specificity is the number of conditions of the rule, including those of its groups and accumulates
+func specificity(rule model.Rule) int {
+	n := len(rule.GetConditions())
+	for _, group := range rule.GetConditionGroups() {
+		n += len(group.GetConditions())
+	}
+	for _, acc := range rule.GetAccumulates() {
+		n += len(acc.GetConditions())
+	}
+	return n
+}
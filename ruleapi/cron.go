@@ -0,0 +1,158 @@
+package ruleapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//cronSchedule tells when a recurring job runs next
+type cronSchedule interface {
+	//next returns the first time after the given one, zero for never
+	next(after time.Time) time.Time
+}
+
+//everySchedule runs at a fixed interval
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (s *everySchedule) next(after time.Time) time.Time {
+	return after.Add(s.interval)
+}
+
+//fieldsSchedule runs at the minutes matching its fields, a bit per allowed value
+type fieldsSchedule struct {
+	minute, hour, dom, month, dow uint64
+	//a day matches either of dom and dow when both are restricted
+	domStar, dowStar bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+//parseCron parses 5 cron fields, minute hour day-of-month month day-of-week, each a * or a list of values
+//and ranges with an optional /step. Also "@every <duration>" and the macros such as "@daily"
+func parseCron(spec string) (cronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, err
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("Cron interval must be positive [%s]", spec)
+		}
+		return &everySchedule{interval}, nil
+	}
+	if macro, found := cronMacros[spec]; found {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Cron needs 5 fields [%s]", spec)
+	}
+	s := fieldsSchedule{domStar: fields[2] == "*", dowStar: fields[4] == "*"}
+	var err error
+	for i, f := range []struct {
+		bits     *uint64
+		min, max int
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dom, 1, 31},
+		{&s.month, 1, 12},
+		{&s.dow, 0, 7},
+	} {
+		*f.bits, err = parseCronField(fields[i], f.min, f.max)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid cron [%s]: %s", spec, err)
+		}
+	}
+	//7 is sunday too
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return &s, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	bits := uint64(0)
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step [%s]", item)
+			}
+			item = item[:i]
+		}
+		from, to := min, max
+		if item != "*" {
+			bounds := strings.SplitN(item, "-", 2)
+			var err error
+			from, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid value [%s]", item)
+			}
+			to = from
+			if len(bounds) == 2 {
+				to, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, fmt.Errorf("invalid value [%s]", item)
+				}
+			} else if step > 1 {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return 0, fmt.Errorf("[%s] out of %d-%d", item, min, max)
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (s *fieldsSchedule) next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	//no match within 5 years means never, e.g. on february 30th
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *fieldsSchedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if !s.domStar && !s.dowStar {
+		return dom || dow
+	}
+	return dom && dow
+}
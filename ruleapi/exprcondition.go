@@ -1,19 +1,27 @@
 package ruleapi
 
 import (
+	"fmt"
 	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/project-flogo/core/data/property"
 
 	"github.com/project-flogo/core/data"
 	"github.com/project-flogo/core/data/expression"
+	"github.com/project-flogo/core/data/expression/function"
 	"github.com/project-flogo/core/data/expression/script"
 	"github.com/project-flogo/core/data/resolve"
 	"github.com/project-flogo/rules/common/model"
+	//registers the temporal.* expression functions
+	_ "github.com/project-flogo/rules/common/temporal"
 )
 
 var td tuplePropertyResolver
+var equalityRe = regexp.MustCompile(`^\$\.(\w+)\.(\w+)\s*==\s*\$\.(\w+)\.(\w+)$`)
+var eventRefRe = regexp.MustCompile(`\$event\[(\w+)\]`)
 var resolver resolve.CompositeResolver
 var factory expression.Factory
 
@@ -22,6 +30,8 @@ func init() {
 	//resolver = resolve.NewCompositeResolver(map[string]resolve.Resolver{".": &td})
 	resolver = resolve.NewCompositeResolver(map[string]resolve.Resolver{
 		".":        &td,
+		"event":    &eventResolver{},
+		"param":    &paramResolver{},
 		"env":      &resolve.EnvResolver{},
 		"property": &property.Resolver{},
 		"loop":     &resolve.LoopResolver{},
@@ -35,6 +45,7 @@ type exprConditionImpl struct {
 	identifiers []model.TupleType
 	cExpr       string
 	ctx         model.RuleContext
+	equiJoins   []model.EquiJoin
 }
 
 func newExprCondition(name string, rule model.Rule, identifiers []model.TupleType, cExpr string, ctx model.RuleContext) model.Condition {
@@ -53,6 +64,9 @@ func (cnd *exprConditionImpl) initExprConditionImpl(name string, rule model.Rule
 	cnd.identifiers = append(cnd.identifiers, identifiers...)
 	cnd.cExpr = cExpr
 	cnd.ctx = ctx
+	cnd.equiJoins = getEquiJoins(cExpr)
+	//expression functions registered by the packages' init are only found once their aliases are resolved
+	function.ResolveAliases()
 }
 
 func (cnd *exprConditionImpl) GetIdentifiers() []model.TupleType {
@@ -66,8 +80,32 @@ func (cnd *exprConditionImpl) GetEvaluator() model.ConditionEvaluator {
 	return nil
 }
 
+//GetProperties returns the properties of the expression's refs, and the time properties of its
+//$event[<identifier>]s
+func (cnd *exprConditionImpl) GetProperties() map[model.TupleType][]string {
+	props := map[model.TupleType][]string{}
+	seen := map[string]bool{}
+	add := func(idr string, prop string) {
+		if prop != "" && !seen[idr+"."+prop] {
+			seen[idr+"."+prop] = true
+			props[model.TupleType(idr)] = append(props[model.TupleType(idr)], prop)
+		}
+	}
+	for _, ref := range getRefs(cnd.cExpr) {
+		idrProp := strings.Split(ref, ".")
+		add(idrProp[0], idrProp[1])
+	}
+	for _, idr := range getEventRefs(cnd.cExpr) {
+		if td := model.GetTupleDescriptor(cnd.rule.GetIdentifierType(model.TupleType(idr))); td != nil {
+			add(idr, td.TimestampProp)
+			add(idr, td.DurationProp)
+		}
+	}
+	return props
+}
+
 func (cnd *exprConditionImpl) String() string {
-	return "[Condition: name:" + cnd.name + ", idrs: TODO]"
+	return "[Condition: name:" + cnd.name + ", " + cnd.cExpr + "]"
 }
 
 func (cnd *exprConditionImpl) GetName() string {
@@ -81,6 +119,10 @@ func (cnd *exprConditionImpl) GetTupleTypeAlias() []model.TupleType {
 	return cnd.identifiers
 }
 
+func (cnd *exprConditionImpl) GetEquiJoins() []model.EquiJoin {
+	return cnd.equiJoins
+}
+
 func (cnd *exprConditionImpl) Evaluate(condName string, ruleNm string, tuples map[model.TupleType]model.Tuple, ctx model.RuleContext) (bool, error) {
 	result := false
 	if cnd.cExpr != "" {
@@ -90,7 +132,7 @@ func (cnd *exprConditionImpl) Evaluate(condName string, ruleNm string, tuples ma
 			return result, err
 		}
 
-		scope := tupleScope{tuples}
+		scope := tupleScope{tuples: tuples, params: model.GetQueryParams(ctx)}
 		res, err := exprn.Eval(&scope)
 		if err != nil {
 			return false, err
@@ -102,9 +144,96 @@ func (cnd *exprConditionImpl) Evaluate(condName string, ruleNm string, tuples ma
 	return result, nil
 }
 
+//getEquiJoins returns the $.a.x == $.b.y equalities that are top level conjuncts of the expression
+func getEquiJoins(cExpr string) []model.EquiJoin {
+	equiJoins := []model.EquiJoin{}
+	conjuncts, ok := splitConjuncts(cExpr)
+	if !ok {
+		return equiJoins
+	}
+	for _, conjunct := range conjuncts {
+		m := equalityRe.FindStringSubmatch(conjunct)
+		if m != nil && m[1] != m[3] {
+			equiJoins = append(equiJoins, model.EquiJoin{
+				Left:      model.TupleType(m[1]),
+				LeftProp:  m[2],
+				Right:     model.TupleType(m[3]),
+				RightProp: m[4],
+			})
+		}
+	}
+	return equiJoins
+}
+
+//splitConjuncts splits an expression on its top level "&&"s, false if there is a top level "||"
+func splitConjuncts(cExpr string) ([]string, bool) {
+	cExpr = strings.TrimSpace(cExpr)
+	if isParenthesized(cExpr) {
+		return splitConjuncts(cExpr[1 : len(cExpr)-1])
+	}
+	parts := []string{}
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(cExpr); i++ {
+		c := cExpr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && strings.HasPrefix(cExpr[i:], "||"):
+			return nil, false
+		case depth == 0 && strings.HasPrefix(cExpr[i:], "&&"):
+			parts = append(parts, cExpr[start:i])
+			start = i + 2
+			i++
+		}
+	}
+	parts = append(parts, cExpr[start:])
+
+	if len(parts) == 1 {
+		return parts, true
+	}
+	conjuncts := []string{}
+	for _, part := range parts {
+		partConjuncts, ok := splitConjuncts(part)
+		if ok {
+			conjuncts = append(conjuncts, partConjuncts...)
+		}
+	}
+	return conjuncts, true
+}
+
+//isParenthesized is true if the whole expression is enclosed in a pair of matching parentheses
+func isParenthesized(cExpr string) bool {
+	if len(cExpr) < 2 || cExpr[0] != '(' || cExpr[len(cExpr)-1] != ')' {
+		return false
+	}
+	depth := 0
+	for i := 0; i < len(cExpr)-1; i++ {
+		if cExpr[i] == '(' {
+			depth++
+		} else if cExpr[i] == ')' {
+			depth--
+		}
+		if depth == 0 {
+			return false
+		}
+	}
+	return true
+}
+
 //////////////////////////////////////////////////////////
 type tupleScope struct {
 	tuples map[model.TupleType]model.Tuple
+	//params are those of the query running, see model.QueryContext
+	params map[string]interface{}
 }
 
 func (ts *tupleScope) GetValue(name string) (value interface{}, exists bool) {
@@ -160,3 +289,37 @@ func (t *tuplePropertyResolver) Resolve(scope data.Scope, item string, field str
 func (*tuplePropertyResolver) GetResolverInfo() *resolve.ResolverInfo {
 	return resolve.NewResolverInfo(false, false)
 }
+
+//eventResolver resolves $event[<identifier>] to the model.EventTime of the tuple bound to the identifier
+type eventResolver struct {
+}
+
+func (*eventResolver) Resolve(scope data.Scope, item string, field string) (interface{}, error) {
+	ts := scope.(*tupleScope)
+	tuple := ts.tuples[model.TupleType(item)]
+	if tuple == nil {
+		return nil, fmt.Errorf("No tuple bound to identifier [%s]", item)
+	}
+	return model.GetEventTime(tuple), nil
+}
+
+func (*eventResolver) GetResolverInfo() *resolve.ResolverInfo {
+	return resolve.NewResolverInfo(false, true)
+}
+
+//paramResolver resolves $param[<name>] to the value of the parameter of the query running
+type paramResolver struct {
+}
+
+func (*paramResolver) Resolve(scope data.Scope, item string, field string) (interface{}, error) {
+	ts := scope.(*tupleScope)
+	value, found := ts.params[item]
+	if !found {
+		return nil, fmt.Errorf("Query parameter not found [%s]", item)
+	}
+	return value, nil
+}
+
+func (*paramResolver) GetResolverInfo() *resolve.ResolverInfo {
+	return resolve.NewResolverInfo(false, true)
+}
@@ -0,0 +1,95 @@
+package ruleapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+//NewFileJobStore returns a job store keeping the jobs in a JSON file, created as needed
+func NewFileJobStore(path string) (model.JobStore, error) {
+	s := fileJobStoreImpl{}
+	err := s.initFileJobStoreImpl(path)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+type fileJobStoreImpl struct {
+	lock sync.Mutex
+	path string
+	jobs map[string]model.Job
+}
+
+func (s *fileJobStoreImpl) initFileJobStoreImpl(path string) error {
+	s.path = path
+	s.jobs = make(map[string]model.Job)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	jobs := []model.Job{}
+	err = json.Unmarshal(data, &jobs)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		s.jobs[job.Key] = job
+	}
+	return nil
+}
+
+func (s *fileJobStoreImpl) Save(job model.Job) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.jobs[job.Key] = job
+	return s.write()
+}
+
+func (s *fileJobStoreImpl) Delete(key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, found := s.jobs[key]; !found {
+		return nil
+	}
+	delete(s.jobs, key)
+	return s.write()
+}
+
+func (s *fileJobStoreImpl) LoadAll() ([]model.Job, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.sorted(), nil
+}
+
+func (s *fileJobStoreImpl) sorted() []model.Job {
+	jobs := make([]model.Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Key < jobs[j].Key
+	})
+	return jobs
+}
+
+//write replaces the file, so that a crash leaves either version
+func (s *fileJobStoreImpl) write() error {
+	data, err := json.MarshalIndent(s.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
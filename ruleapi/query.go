@@ -0,0 +1,72 @@
+package ruleapi
+
+import (
+	"github.com/project-flogo/rules/common/model"
+)
+
+//queryImpl declares its identifiers and conditions on a rule of the same name, that is never added to a session
+type queryImpl struct {
+	rule *ruleImpl
+}
+
+//NewQuery ... Create a new query, see RuleSession.AddQuery
+func NewQuery(name string) model.MutableQuery {
+	query := queryImpl{}
+	query.initQueryImpl(name)
+	return &query
+}
+
+func (query *queryImpl) initQueryImpl(name string) {
+	query.rule = &ruleImpl{}
+	query.rule.initRuleImpl(name)
+}
+
+func (query *queryImpl) GetName() string {
+	return query.rule.GetName()
+}
+
+func (query *queryImpl) GetIdentifiers() []model.TupleType {
+	return query.rule.GetIdentifiers()
+}
+
+func (query *queryImpl) GetIdentifierType(idr model.TupleType) model.TupleType {
+	return query.rule.GetIdentifierType(idr)
+}
+
+func (query *queryImpl) GetConditions() []model.Condition {
+	return query.rule.GetConditions()
+}
+
+func (query *queryImpl) GetSelfMatch() bool {
+	return query.rule.GetSelfMatch()
+}
+
+func (query *queryImpl) GetDeps() map[model.TupleType]map[string]bool {
+	return query.rule.GetDeps()
+}
+
+func (query *queryImpl) SetSelfMatch(selfMatch bool) {
+	query.rule.SetSelfMatch(selfMatch)
+}
+
+func (query *queryImpl) AddIdentifier(idr string) error {
+	return query.rule.AddIdrsToRule([]model.TupleType{model.TupleType(idr)})
+}
+
+func (query *queryImpl) AddCondition(conditionName string, idrs []string, cFn model.ConditionEvaluator, ctx model.RuleContext) error {
+	return query.rule.AddCondition(conditionName, idrs, cFn, ctx)
+}
+
+func (query *queryImpl) AddExprCondition(conditionName string, cExpr string, ctx model.RuleContext) error {
+	return query.rule.AddExprCondition(conditionName, cExpr, ctx)
+}
+
+func (query *queryImpl) String() string {
+	str := "[Query: " + query.rule.name + "\n"
+	str += "\t[Conditions:\n"
+	for _, cond := range query.rule.conditions {
+		str += "\t\t" + cond.String() + "\n"
+	}
+	str += "\t[Idrs:" + model.IdentifiersToString(query.rule.identifiers) + "]\n"
+	return str
+}
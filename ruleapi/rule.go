@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/project-flogo/rules/common/model"
 )
@@ -16,6 +17,18 @@ type ruleImpl struct {
 	priority    int
 	deps        map[model.TupleType]map[string]bool
 	ctx         model.RuleContext
+	groups      []model.ConditionGroup
+	accumulates []model.Accumulate
+	//the descriptors of the accumulates' result tuples, registered as the rule is added to a session
+	resultTds map[model.TupleType]*model.TupleDescriptor
+	//tuple types of the identifiers declared as "alias:tupletype"
+	aliases   map[model.TupleType]model.TupleType
+	selfMatch bool
+
+	agendaGroup     string
+	activationGroup string
+	noLoop          bool
+	lockOnActive    bool
 }
 
 func (rule *ruleImpl) GetContext() model.RuleContext {
@@ -38,6 +51,11 @@ func (rule *ruleImpl) initRuleImpl(name string) {
 	rule.identifiers = []model.TupleType{}
 	rule.conditions = []model.Condition{}
 	rule.deps = make(map[model.TupleType]map[string]bool)
+	rule.groups = []model.ConditionGroup{}
+	rule.accumulates = []model.Accumulate{}
+	rule.resultTds = make(map[model.TupleType]*model.TupleDescriptor)
+	rule.aliases = make(map[model.TupleType]model.TupleType)
+	rule.agendaGroup = model.MainAgendaGroup
 }
 
 func (rule *ruleImpl) GetName() string {
@@ -56,64 +74,179 @@ func (rule *ruleImpl) SetActionFn(actionFn model.ActionFunction) {
 	rule.actionFn = actionFn
 }
 
-func (rule *ruleImpl) addCond(conditionName string, idrs []model.TupleType, cfn model.ConditionEvaluator, ctx model.RuleContext, setIdr bool) {
+func (rule *ruleImpl) addCond(conditionName string, idrs []model.TupleType, cfn model.ConditionEvaluator, ctx model.RuleContext, setIdr bool) error {
+	if err := rule.AddIdrsToRule(idrs); err != nil {
+		return err
+	}
 	condition := newCondition(conditionName, rule, idrs, cfn, ctx)
 	rule.conditions = append(rule.conditions, condition)
+	return nil
+}
 
-	for _, cidr := range idrs {
-		if len(rule.identifiers) == 0 {
+//AddIdrsToRule adds identifiers to the rule, either tuple types or aliases, "alias:tupletype" declares an alias.
+//None are added if one is invalid
+func (rule *ruleImpl) AddIdrsToRule(idrs []model.TupleType) error {
+	cidrs := []model.TupleType{}
+	for _, idr := range idrs {
+		//TODO: configure the rulesession
+		cidr, err := rule.resolveIdentifier(string(idr))
+		if err != nil {
+			return err
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	for _, cidr := range cidrs {
+		if found, _ := model.Contains(rule.identifiers, cidr); !found {
 			rule.identifiers = append(rule.identifiers, cidr)
-		} else {
-			for _, ridr := range rule.identifiers {
-				if cidr != ridr {
-					rule.identifiers = append(rule.identifiers, cidr)
-					break
-				}
-			}
 		}
 	}
+	return nil
+}
 
+func (rule *ruleImpl) addExprCond(conditionName string, idrs []model.TupleType, cExpr string, ctx model.RuleContext) error {
+	if err := rule.AddIdrsToRule(idrs); err != nil {
+		return err
+	}
+	condition := newExprCondition(conditionName, rule, idrs, cExpr, ctx)
+	rule.conditions = append(rule.conditions, condition)
+	return nil
 }
 
-func (rule *ruleImpl) AddIdrsToRule(idrs []model.TupleType) {
-	for _, cidr := range idrs {
-		//TODO: configure the rulesession
-		if model.GetTupleDescriptor(cidr) == nil {
-			return
+//resolveIdentifier returns the identifier for an alias or a tuple type, "alias:tupletype" declares the alias
+func (rule *ruleImpl) resolveIdentifier(idr string) (model.TupleType, error) {
+	aliasType := strings.Split(idr, ":")
+	if len(aliasType) > 2 || aliasType[0] == "" {
+		return "", fmt.Errorf("Invalid identifier [%s], expecting <tupletype> or <alias>:<tupletype>", idr)
+	}
+	alias := model.TupleType(aliasType[0])
+	if len(aliasType) == 1 {
+		if _, found := rule.aliases[alias]; !found && rule.getTupleDescriptor(alias) == nil {
+			return "", fmt.Errorf("Tuple type not found [%s]", idr)
 		}
-		if len(rule.identifiers) == 0 {
-			rule.identifiers = append(rule.identifiers, cidr)
-		} else {
-			found := false
-			for _, ridr := range rule.identifiers {
-				if cidr == ridr {
-					found = true
-					break
-				}
-			}
-			if !found {
-				rule.identifiers = append(rule.identifiers, cidr)
-			}
+		return alias, nil
+	}
+	tupleType := model.TupleType(aliasType[1])
+	if rule.getTupleDescriptor(tupleType) == nil {
+		return "", fmt.Errorf("Tuple type not found [%s]", aliasType[1])
+	}
+	bound, found := rule.aliases[alias]
+	if !found && alias != tupleType && rule.getTupleDescriptor(alias) != nil {
+		bound, found = alias, true
+	}
+	if found && bound != tupleType {
+		return "", fmt.Errorf("Identifier [%s] is already bound to tuple type [%s]", aliasType[0], string(bound))
+	}
+	rule.aliases[alias] = tupleType
+	return alias, nil
+}
+
+//getTupleDescriptor returns the descriptor of the tuple type, also of the result tuples of the rule's
+//accumulates before the rule is added
+func (rule *ruleImpl) getTupleDescriptor(tupleType model.TupleType) *model.TupleDescriptor {
+	if td, found := rule.resultTds[tupleType]; found {
+		return td
+	}
+	return model.GetTupleDescriptor(tupleType)
+}
+
+func (rule *ruleImpl) GetIdentifierType(idr model.TupleType) model.TupleType {
+	if tupleType, found := rule.aliases[idr]; found {
+		return tupleType
+	}
+	return idr
+}
+
+func (rule *ruleImpl) GetSelfMatch() bool {
+	return rule.selfMatch
+}
+
+func (rule *ruleImpl) SetSelfMatch(selfMatch bool) {
+	rule.selfMatch = selfMatch
+}
+
+func (rule *ruleImpl) GetConditionGroups() []model.ConditionGroup {
+	return rule.groups
+}
+
+func (rule *ruleImpl) AddNotGroup(groupName string, idrs []string) (model.MutableConditionGroup, error) {
+	return rule.addGroup(groupName, model.NotGroup, idrs)
+}
+
+func (rule *ruleImpl) AddExistsGroup(groupName string, idrs []string) (model.MutableConditionGroup, error) {
+	return rule.addGroup(groupName, model.ExistsGroup, idrs)
+}
+
+func (rule *ruleImpl) addGroup(groupName string, groupType model.ConditionGroupType, idrs []string) (model.MutableConditionGroup, error) {
+	groupIdrs := []model.TupleType{}
+	for _, idr := range idrs {
+		groupIdr, err := rule.resolveIdentifier(idr)
+		if err != nil {
+			return nil, err
 		}
+		groupIdrs = append(groupIdrs, groupIdr)
 	}
+	if len(groupIdrs) == 0 {
+		return nil, fmt.Errorf("Condition group [%s] needs at least one identifier", groupName)
+	}
+	group := newConditionGroup(groupName, groupType, rule, groupIdrs)
+	rule.groups = append(rule.groups, group)
+	return group, nil
 }
 
-func (rule *ruleImpl) addExprCond(conditionName string, idrs []model.TupleType, cExpr string, ctx model.RuleContext) {
-	condition := newExprCondition(conditionName, rule, idrs, cExpr, ctx)
-	rule.conditions = append(rule.conditions, condition)
+func (rule *ruleImpl) GetAccumulates() []model.Accumulate {
+	return rule.accumulates
+}
 
-	for _, cidr := range idrs {
-		if len(rule.identifiers) == 0 {
-			rule.identifiers = append(rule.identifiers, cidr)
-		} else {
-			for _, ridr := range rule.identifiers {
-				if cidr != ridr {
-					rule.identifiers = append(rule.identifiers, cidr)
-					break
-				}
-			}
+func (rule *ruleImpl) AddAccumulate(resultIdr string, fn model.AccumulateFunction, over string, groupBy string) (model.MutableAccumulate, error) {
+	switch fn {
+	case model.CountFunction, model.SumFunction, model.MinFunction, model.MaxFunction, model.AvgFunction, model.CollectFunction:
+	default:
+		return nil, fmt.Errorf("Unknown accumulate function [%s]", fn)
+	}
+	idr, prop, err := rule.parseAccumulateProp(over)
+	if err != nil {
+		return nil, err
+	}
+	if prop == "" && fn != model.CountFunction && fn != model.CollectFunction {
+		return nil, fmt.Errorf("Accumulate function [%s] needs a property, got [%s]", fn, over)
+	}
+	idrProps := []string{over}
+	var groupByIdr model.TupleType
+	groupByProp := ""
+	if groupBy != "" {
+		groupByIdr, groupByProp, err = rule.parseAccumulateProp(groupBy)
+		if err != nil {
+			return nil, err
+		}
+		if groupByIdr != idr || groupByProp == "" {
+			return nil, fmt.Errorf("Accumulate needs to be grouped by a property of [%s], got [%s]", string(idr), groupBy)
+		}
+		idrProps = append(idrProps, groupBy)
+	}
+	for _, acc := range rule.accumulates {
+		if acc.GetName() == resultIdr {
+			return nil, fmt.Errorf("Accumulate [%s] already exists", resultIdr)
 		}
 	}
+	if _, found := rule.aliases[model.TupleType(resultIdr)]; found {
+		return nil, fmt.Errorf("Identifier [%s] is already bound to tuple type [%s]", resultIdr, string(rule.aliases[model.TupleType(resultIdr)]))
+	}
+	td, err := accumulateTupleDescriptor(resultIdr, fn)
+	if err != nil {
+		return nil, err
+	}
+	_, err = rule.addDeps(idrProps)
+	if err != nil {
+		return nil, err
+	}
+	acc := newAccumulate(resultIdr, fn, rule, idr, prop, groupByProp)
+	rule.resultTds[model.TupleType(resultIdr)] = td
+	if err = rule.AddIdrsToRule([]model.TupleType{model.TupleType(resultIdr)}); err != nil {
+		delete(rule.resultTds, model.TupleType(resultIdr))
+		return nil, err
+	}
+	rule.accumulates = append(rule.accumulates, acc)
+	return acc, nil
 }
 
 func (rule *ruleImpl) GetPriority() int {
@@ -124,9 +257,48 @@ func (rule *ruleImpl) SetPriority(priority int) {
 	rule.priority = priority
 }
 
+func (rule *ruleImpl) GetAgendaGroup() string {
+	return rule.agendaGroup
+}
+
+func (rule *ruleImpl) SetAgendaGroup(agendaGroup string) {
+	if agendaGroup == "" {
+		agendaGroup = model.MainAgendaGroup
+	}
+	rule.agendaGroup = agendaGroup
+}
+
+func (rule *ruleImpl) GetActivationGroup() string {
+	return rule.activationGroup
+}
+
+func (rule *ruleImpl) SetActivationGroup(activationGroup string) {
+	rule.activationGroup = activationGroup
+}
+
+func (rule *ruleImpl) GetNoLoop() bool {
+	return rule.noLoop
+}
+
+func (rule *ruleImpl) SetNoLoop(noLoop bool) {
+	rule.noLoop = noLoop
+}
+
+func (rule *ruleImpl) GetLockOnActive() bool {
+	return rule.lockOnActive
+}
+
+func (rule *ruleImpl) SetLockOnActive(lockOnActive bool) {
+	rule.lockOnActive = lockOnActive
+}
+
 func (rule *ruleImpl) String() string {
 	str := ""
 	str += "[Rule: (" + ") " + rule.name + "\n"
+	str += "\t[AgendaGroup: " + rule.agendaGroup + "]\n"
+	if rule.activationGroup != "" {
+		str += "\t[ActivationGroup: " + rule.activationGroup + "]\n"
+	}
 	//str += "[Rule: (" + strconv.Itoa(rule.id) + ") " + rule.name + "\n"
 
 	str += "\t[Conditions:\n"
@@ -134,6 +306,12 @@ func (rule *ruleImpl) String() string {
 		str += "\t\t" + cond.String() + "\n"
 	}
 	str += "\t[Idrs:" + model.IdentifiersToString(rule.identifiers) + "]\n"
+	for _, group := range rule.groups {
+		str += "\t" + group.String() + "\n"
+	}
+	for _, acc := range rule.accumulates {
+		str += "\t" + acc.String() + "\n"
+	}
 	return str
 }
 
@@ -146,13 +324,24 @@ func (rule *ruleImpl) SetAction(actionFn model.ActionFunction) {
 }
 
 func (rule *ruleImpl) AddCondition2(conditionName string, idrs []string, cFn model.ConditionEvaluator, ctx model.RuleContext) (err error) {
+	return rule.AddCondition(conditionName, idrs, cFn, ctx)
+}
+
+func (rule *ruleImpl) AddCondition(conditionName string, idrs []string, cFn model.ConditionEvaluator, ctx model.RuleContext) (err error) {
+	typeDeps, err := rule.addDeps(idrs)
+	if err != nil {
+		return err
+	}
+	return rule.addCond(conditionName, typeDeps, cFn, ctx, true)
+}
+
+func (rule *ruleImpl) addDeps(idrs []string) ([]model.TupleType, error) {
 	typeDeps := []model.TupleType{}
 	for _, idr := range idrs {
 		aliasProp := strings.Split(string(idr), ".")
-		alias := model.TupleType(aliasProp[0])
-
-		if model.GetTupleDescriptor(model.TupleType(alias)) == nil {
-			return fmt.Errorf("Tuple type not found [%s]", string(alias))
+		alias, err := rule.resolveIdentifier(aliasProp[0])
+		if err != nil {
+			return typeDeps, err
 		}
 
 		exists, _ := model.Contains(typeDeps, alias)
@@ -162,64 +351,134 @@ func (rule *ruleImpl) AddCondition2(conditionName string, idrs []string, cFn mod
 		if len(aliasProp) == 2 { //specifically 2, else do not consider
 			prop := aliasProp[1]
 
-			td := model.GetTupleDescriptor(model.TupleType(alias))
+			//deps are by tuple type, tuples are modified irrespective of the identifiers bound to them
+			tupleType := rule.GetIdentifierType(alias)
+			td := rule.getTupleDescriptor(tupleType)
 			if prop != "none" && td.GetProperty(prop) == nil { //"none" is a special case
-				return fmt.Errorf("TupleType property not found [%s]", prop)
+				return typeDeps, fmt.Errorf("TupleType property not found [%s]", prop)
 			}
 
-			propMap, found := rule.deps[alias]
+			propMap, found := rule.deps[tupleType]
 			if !found {
 				propMap = map[string]bool{}
-				rule.deps[alias] = propMap
+				rule.deps[tupleType] = propMap
 			}
 			propMap[prop] = true
 		}
 	}
 
-	rule.addCond(conditionName, typeDeps, cFn, ctx, true)
-	return err
+	return typeDeps, nil
 }
 
-func (rule *ruleImpl) AddCondition(conditionName string, idrs []string, cFn model.ConditionEvaluator, ctx model.RuleContext) (err error) {
-	typeDeps, err := rule.addDeps(idrs)
+func (rule *ruleImpl) AddEquiJoinCondition(conditionName string, leftProp string, rightProp string, ctx model.RuleContext) error {
+	condition, err := rule.newEquiJoinCondition(conditionName, leftProp, rightProp, ctx)
 	if err != nil {
 		return err
 	}
-	rule.addCond(conditionName, typeDeps, cFn, ctx, true)
-	return err
+	if err = rule.AddIdrsToRule(condition.GetIdentifiers()); err != nil {
+		return err
+	}
+	rule.conditions = append(rule.conditions, condition)
+	return nil
 }
 
-func (rule *ruleImpl) addDeps(idrs []string) ([]model.TupleType, error) {
-	typeDeps := []model.TupleType{}
-	for _, idr := range idrs {
-		aliasProp := strings.Split(string(idr), ".")
-		alias := model.TupleType(aliasProp[0])
-
-		if model.GetTupleDescriptor(model.TupleType(alias)) == nil {
-			return typeDeps, fmt.Errorf("Tuple type not found [%s]", string(alias))
+func (rule *ruleImpl) newEquiJoinCondition(conditionName string, leftProp string, rightProp string, ctx model.RuleContext) (model.Condition, error) {
+	for _, prop := range []string{leftProp, rightProp} {
+		aliasProp := strings.Split(prop, ".")
+		if len(aliasProp) != 2 || aliasProp[1] == "none" {
+			return nil, fmt.Errorf("Invalid property [%s], expecting <identifier>.<property>", prop)
 		}
+	}
+	leftAliasProp := strings.Split(leftProp, ".")
+	rightAliasProp := strings.Split(rightProp, ".")
 
-		exists, _ := model.Contains(typeDeps, alias)
-		if !exists {
-			typeDeps = append(typeDeps, alias)
-		}
-		if len(aliasProp) == 2 { //specifically 2, else do not consider
-			prop := aliasProp[1]
+	typeDeps, err := rule.addDeps([]string{leftProp, rightProp})
+	if err != nil {
+		return nil, err
+	}
+	if len(typeDeps) != 2 {
+		return nil, fmt.Errorf("Equi-join condition [%s] needs properties of two different identifiers", conditionName)
+	}
+	equiJoin := model.EquiJoin{
+		Left:      typeDeps[0],
+		LeftProp:  leftAliasProp[1],
+		Right:     typeDeps[1],
+		RightProp: rightAliasProp[1],
+	}
+	return newEquiJoinCondition(conditionName, rule, equiJoin, ctx), nil
+}
 
-			td := model.GetTupleDescriptor(model.TupleType(alias))
-			if prop != "none" && td.GetProperty(prop) == nil { //"none" is a special case
-				return typeDeps, fmt.Errorf("TupleType property not found [%s]", prop)
-			}
+func (rule *ruleImpl) AddTemporalCondition(conditionName string, left string, operator model.TemporalOperator, right string,
+	ctx model.RuleContext, bounds ...time.Duration) error {
+	condition, err := rule.newTemporalCondition(conditionName, left, operator, right, ctx, bounds)
+	if err != nil {
+		return err
+	}
+	if err = rule.AddIdrsToRule(condition.GetIdentifiers()); err != nil {
+		return err
+	}
+	rule.conditions = append(rule.conditions, condition)
+	return nil
+}
 
-			propMap, found := rule.deps[alias]
-			if !found {
-				propMap = map[string]bool{}
-				rule.deps[alias] = propMap
-			}
-			propMap[prop] = true
+func (rule *ruleImpl) newTemporalCondition(conditionName string, left string, operator model.TemporalOperator, right string,
+	ctx model.RuleContext, bounds []time.Duration) (model.Condition, error) {
+	err := operator.ValidateBounds(bounds)
+	if err != nil {
+		return nil, err
+	}
+	leftIdr, err := rule.addEventDeps(left)
+	if err != nil {
+		return nil, err
+	}
+	rightIdr, err := rule.addEventDeps(right)
+	if err != nil {
+		return nil, err
+	}
+	if leftIdr == rightIdr {
+		return nil, fmt.Errorf("Temporal condition [%s] needs two different identifiers", conditionName)
+	}
+	return newTemporalCondition(conditionName, rule, leftIdr, operator, rightIdr, bounds, ctx), nil
+}
+
+//addEventDeps adds the identifier, and the dependencies on the timestamp and duration properties of its type
+func (rule *ruleImpl) addEventDeps(idr string) (model.TupleType, error) {
+	typeDeps, err := rule.addDeps([]string{idr})
+	if err != nil {
+		return "", err
+	}
+	alias := typeDeps[0]
+	td := rule.getTupleDescriptor(rule.GetIdentifierType(alias))
+	props := []string{}
+	for _, prop := range []string{td.TimestampProp, td.DurationProp} {
+		if prop != "" {
+			props = append(props, string(alias)+"."+prop)
 		}
 	}
+	_, err = rule.addDeps(props)
+	return alias, err
+}
 
+//addExprDeps adds the dependencies of the expression's refs, and of its $event[<identifier>]s, see addEventDeps
+func (rule *ruleImpl) addExprDeps(cstr string) ([]model.TupleType, error) {
+	refs := getRefs(cstr)
+	err := rule.validateRefs(refs)
+	if err != nil {
+		return nil, err
+	}
+	typeDeps, err := rule.addDeps(refs)
+	if err != nil {
+		return nil, err
+	}
+	for _, idr := range getEventRefs(cstr) {
+		alias, err := rule.addEventDeps(idr)
+		if err != nil {
+			return nil, err
+		}
+		if found, _ := model.Contains(typeDeps, alias); !found {
+			typeDeps = append(typeDeps, alias)
+		}
+	}
 	return typeDeps, nil
 }
 
@@ -235,27 +494,19 @@ func (rule *ruleImpl) AddExprCondition(conditionName string, cstr string, ctx mo
 	//}
 	//exprn := e.(*expr.Expression)
 	//refs, err := getRefs(exprn)
-	refs := getRefs(cstr)
-
-	err := validateRefs(refs)
-	if err != nil {
-		return err
-	}
-
-	typeDeps, err := rule.addDeps(refs)
+	typeDeps, err := rule.addExprDeps(cstr)
 	if err != nil {
 		return err
 	}
-	rule.addExprCond(conditionName, typeDeps, cstr, ctx)
-	return nil
+	return rule.addExprCond(conditionName, typeDeps, cstr, ctx)
 
 }
 
-func validateRefs(refs []string) error {
+func (rule *ruleImpl) validateRefs(refs []string) error {
 	for _, ref := range refs {
 		ref := strings.TrimPrefix(ref, "$.")
 		vals := strings.Split(ref, ".")
-		td := model.GetTupleDescriptor(model.TupleType(vals[0]))
+		td := rule.getTupleDescriptor(rule.GetIdentifierType(model.TupleType(vals[0])))
 		if td == nil {
 			return fmt.Errorf("Invalid TupleType [%s]", vals[0])
 		}
@@ -326,3 +577,12 @@ func getRefs(cstr string) []string {
 	}
 	return keys2
 }
+
+//getEventRefs returns the identifiers of the $event[<identifier>]s of the expression
+func getEventRefs(cstr string) []string {
+	idrs := []string{}
+	for _, m := range eventRefRe.FindAllStringSubmatch(cstr, -1) {
+		idrs = append(idrs, m[1])
+	}
+	return idrs
+}
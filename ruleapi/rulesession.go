@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/project-flogo/rules/common/metrics"
 	"github.com/project-flogo/rules/common/model"
 	"github.com/project-flogo/rules/config"
 	"github.com/project-flogo/rules/rete"
@@ -21,9 +22,14 @@ type rulesessionImpl struct {
 	name        string
 	reteNetwork rete.Network
 
-	timers    map[interface{}]*time.Timer
+	scheduler scheduler
 	startupFn model.StartupRSFunction
 	started   bool
+
+	//the handler registered, the network calls handleTxn
+	txnHandler model.RtcTransactionHandler
+	txnContext interface{}
+	wal        writeAheadLog
 }
 
 func GetOrCreateRuleSession(name string) (model.RuleSession, error) {
@@ -49,12 +55,44 @@ func GetOrCreateRuleSessionFromConfig(name string, jsonConfig string) (model.Rul
 		return nil, err
 	}
 
+	if ruleSessionDescriptor.ConflictResolver != "" {
+		resolver := GetConflictResolver(ruleSessionDescriptor.ConflictResolver)
+		if resolver == nil {
+			return nil, fmt.Errorf("Unknown conflict resolver [%s]", ruleSessionDescriptor.ConflictResolver)
+		}
+		rs.SetConflictResolver(resolver)
+	}
+	rs.SetRefraction(ruleSessionDescriptor.Refraction)
+	rs.SetExplain(ruleSessionDescriptor.Explain)
+
 	for _, ruleCfg := range ruleSessionDescriptor.Rules {
 		rule := NewRule(ruleCfg.Name)
 		rule.SetContext("This is a test of context")
 		rule.SetAction(ruleCfg.ActionFunc)
 		rule.SetPriority(ruleCfg.Priority)
-
+		rule.SetSelfMatch(ruleCfg.SelfMatch)
+		rule.SetAgendaGroup(ruleCfg.AgendaGroup)
+		rule.SetActivationGroup(ruleCfg.ActivationGroup)
+		rule.SetNoLoop(ruleCfg.NoLoop)
+		rule.SetLockOnActive(ruleCfg.LockOnActive)
+
+		//accumulates first, their results can be referred to by the rule's conditions
+		for _, accCfg := range ruleCfg.Accumulates {
+			err = addAccumulateFromConfig(rule, accCfg)
+			if err != nil {
+				return nil, err
+			}
+		}
+		//explicit rule identifiers before the conditions, they may declare aliases the conditions refer to
+		if ruleCfg.Identifiers != nil {
+			idrs := []model.TupleType{}
+			for _, idr := range ruleCfg.Identifiers {
+				idrs = append(idrs, model.TupleType(idr))
+			}
+			if err = rule.AddIdrsToRule(idrs); err != nil {
+				return nil, err
+			}
+		}
 		for _, condCfg := range ruleCfg.Conditions {
 			if condCfg.Expression == "" {
 				rule.AddCondition(condCfg.Name, condCfg.Identifiers, condCfg.Evaluator, nil)
@@ -62,13 +100,11 @@ func GetOrCreateRuleSessionFromConfig(name string, jsonConfig string) (model.Rul
 				rule.AddExprCondition(condCfg.Name, condCfg.Expression, nil)
 			}
 		}
-		//now add explicit rule identifiers if any
-		if ruleCfg.Identifiers != nil {
-			idrs := []model.TupleType{}
-			for _, idr := range ruleCfg.Identifiers {
-				idrs = append(idrs, model.TupleType(idr))
+		for _, groupCfg := range ruleCfg.Groups {
+			err = addConditionGroupFromConfig(rule, groupCfg)
+			if err != nil {
+				return nil, err
 			}
-			rule.AddIdrsToRule(idrs)
 		}
 
 		rs.AddRule(rule)
@@ -79,14 +115,96 @@ func GetOrCreateRuleSessionFromConfig(name string, jsonConfig string) (model.Rul
 	return rs, nil
 }
 
+func addConditionGroupFromConfig(rule model.MutableRule, groupCfg *config.ConditionGroupDescriptor) error {
+	var group model.MutableConditionGroup
+	var err error
+	switch model.ConditionGroupType(groupCfg.Type) {
+	case model.NotGroup:
+		group, err = rule.AddNotGroup(groupCfg.Name, groupCfg.Identifiers)
+	case model.ExistsGroup:
+		group, err = rule.AddExistsGroup(groupCfg.Name, groupCfg.Identifiers)
+	default:
+		err = fmt.Errorf("Unknown type [%s] of condition group [%s] in rule [%s]", groupCfg.Type, groupCfg.Name, rule.GetName())
+	}
+	if err != nil {
+		return err
+	}
+	for _, condCfg := range groupCfg.Conditions {
+		if condCfg.Expression == "" {
+			err = group.AddCondition(condCfg.Name, condCfg.Identifiers, condCfg.Evaluator, nil)
+		} else {
+			err = group.AddExprCondition(condCfg.Name, condCfg.Expression, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addAccumulateFromConfig(rule model.MutableRule, accCfg *config.AccumulateDescriptor) error {
+	acc, err := rule.AddAccumulate(accCfg.Name, model.AccumulateFunction(accCfg.Function), accCfg.Over, accCfg.GroupBy)
+	if err != nil {
+		return err
+	}
+	for _, condCfg := range accCfg.Conditions {
+		if condCfg.Expression == "" {
+			err = acc.AddCondition(condCfg.Name, condCfg.Identifiers, condCfg.Evaluator, nil)
+		} else {
+			err = acc.AddExprCondition(condCfg.Name, condCfg.Expression, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//SetWorkingMemoryStore replaces the store of the session's working memory, such as rete.NewDiskStore,
+//after adding the rules and before asserting any tuple. The tuples the store holds from a previous
+//session are asserted again, only the activations pending then stay on the agenda
+func SetWorkingMemoryStore(rs model.RuleSession, store rete.WorkingMemoryStore) error {
+	rsImpl, ok := rs.(*rulesessionImpl)
+	if !ok {
+		return fmt.Errorf("Unknown rule session [%s]", rs.GetName())
+	}
+	return rsImpl.reteNetwork.SetStore(rs, store)
+}
+
+//GetNetworkGraph returns the rete network of the session, to write as JSON or DOT. With rows, its
+//nodes tell how many rows their join tables hold. An action passes its ctx, the session is locked as it runs
+func GetNetworkGraph(ctx context.Context, rs model.RuleSession, withRows bool) (rete.Graph, error) {
+	rsImpl, ok := rs.(*rulesessionImpl)
+	if !ok {
+		return rete.Graph{}, fmt.Errorf("Unknown rule session [%s]", rs.GetName())
+	}
+	return rsImpl.reteNetwork.GetGraph(ctx, withRows), nil
+}
+
+//EnableMetrics counts the activations, condition evaluations, action and RTC durations of the session
+//in the registry, with gauges of its join tables and asserted tuples. A nil registry disables them
+func EnableMetrics(rs model.RuleSession, registry metrics.Registry) error {
+	rsImpl, ok := rs.(*rulesessionImpl)
+	if !ok {
+		return fmt.Errorf("Unknown rule session [%s]", rs.GetName())
+	}
+	rsImpl.reteNetwork.SetMetrics(registry, rs.GetName())
+	return nil
+}
+
 func (rs *rulesessionImpl) initRuleSession(name string) {
 	rs.reteNetwork = rete.NewReteNetwork()
+	rs.reteNetwork.SetConflictResolver(GetConflictResolver(SalienceResolver))
+	rs.reteNetwork.SetClock(NewRealtimeClock())
 	rs.name = name
-	rs.timers = make(map[interface{}]*time.Timer)
+	rs.scheduler = newScheduler(rs)
 	rs.started = false
 }
 
 func (rs *rulesessionImpl) AddRule(rule model.Rule) (err error) {
+	if err = registerAccumulateTupleDescriptors(rule); err != nil {
+		return err
+	}
 	return rs.reteNetwork.AddRule(rule)
 }
 
@@ -111,16 +229,48 @@ func (rs *rulesessionImpl) Assert(ctx context.Context, tuple model.Tuple) (err e
 	if ctx == nil {
 		ctx = context.Context(context.Background())
 	}
-	rs.reteNetwork.Assert(ctx, rs, tuple, nil, rete.ADD)
-	return nil
+	return rs.runOp(ctx, walAssert, tuple, func(ctx context.Context) {
+		rs.reteNetwork.Assert(ctx, rs, tuple, nil, rete.ADD)
+	})
+}
+
+func (rs *rulesessionImpl) AssertWithTTL(ctx context.Context, tuple model.Tuple, ttl time.Duration) (err error) {
+	model.SetTTL(tuple, ttl)
+	return rs.Assert(ctx, tuple)
+}
+
+func (rs *rulesessionImpl) LogicalAssert(ctx context.Context, tuple model.Tuple) (err error) {
+	if !rs.started {
+		return fmt.Errorf("Cannot assert tuple. Rulesession [%s] not started", rs.name)
+	}
+	return rs.reteNetwork.LogicalAssert(ctx, rs, tuple)
+}
+
+func (rs *rulesessionImpl) Retract(ctx context.Context, tuple model.Tuple) (err error) {
+	return rs.runOp(ctx, walRetract, tuple, func(ctx context.Context) {
+		rs.reteNetwork.Retract(ctx, rs, tuple, nil, rete.RETRACT)
+	})
 }
 
-func (rs *rulesessionImpl) Retract(ctx context.Context, tuple model.Tuple) {
-	rs.reteNetwork.Retract(ctx, tuple, nil, rete.RETRACT)
+func (rs *rulesessionImpl) Delete(ctx context.Context, tuple model.Tuple) (err error) {
+	return rs.runOp(ctx, walDelete, tuple, func(ctx context.Context) {
+		rs.reteNetwork.Retract(ctx, rs, tuple, nil, rete.DELETE)
+	})
 }
 
-func (rs *rulesessionImpl) Delete(ctx context.Context, tuple model.Tuple) {
-	rs.reteNetwork.Retract(ctx, tuple, nil, rete.DELETE)
+//runOp runs an external operation, logged first to the write-ahead log if any. It returns the error of
+//the working memory store if it could not make the changes durable
+func (rs *rulesessionImpl) runOp(ctx context.Context, op string, tuple model.Tuple, run func(ctx context.Context)) error {
+	if rete.InRtc(ctx) {
+		run(ctx)
+		return nil
+	}
+	if rs.wal == nil {
+		run(ctx)
+	} else if err := rs.wal.runOp(ctx, op, tuple, run); err != nil {
+		return err
+	}
+	return rs.reteNetwork.StoreErr()
 }
 
 func (rs *rulesessionImpl) printNetwork() {
@@ -133,28 +283,52 @@ func (rs *rulesessionImpl) GetName() string {
 
 func (rs *rulesessionImpl) Unregister() {
 	sessionMap.Delete(rs.name)
+	rs.scheduler.stop()
+	rs.reteNetwork.Stop()
+	rs.reteNetwork.SetMetrics(nil, "")
+	if rs.wal != nil {
+		rs.wal.close()
+	}
 }
 
-func (rs *rulesessionImpl) ScheduleAssert(ctx context.Context, delayInMillis uint64, key interface{}, tuple model.Tuple) {
+func (rs *rulesessionImpl) AddListener(listener model.RuleSessionListener) {
+	rs.reteNetwork.AddListener(listener)
+}
 
-	timer := time.AfterFunc(time.Millisecond*time.Duration(delayInMillis), func() {
-		ctxNew := context.TODO()
-		delete(rs.timers, key)
-		rs.Assert(ctxNew, tuple)
-	})
+func (rs *rulesessionImpl) RemoveListener(listener model.RuleSessionListener) {
+	rs.reteNetwork.RemoveListener(listener)
+}
 
-	rs.timers[key] = timer
+func (rs *rulesessionImpl) ScheduleAssert(ctx context.Context, delayInMillis uint64, key interface{}, tuple model.Tuple) {
+	at := rs.GetClock().Now().Add(time.Millisecond * time.Duration(delayInMillis))
+	err := rs.scheduler.schedule(newAssertJob(key, at, tuple), tuple)
+	if err != nil {
+		fmt.Printf("Cannot schedule assert for key [%v]: %s\n", key, err)
+	}
 }
 
 func (rs *rulesessionImpl) CancelScheduledAssert(ctx context.Context, key interface{}) {
-	timer, ok := rs.timers[key]
-	if ok {
+	if rs.scheduler.cancel(fmt.Sprint(key)) {
 		fmt.Printf("Cancelling timer attached to key [%v]\n", key)
-		delete(rs.timers, key)
-		timer.Stop()
 	}
 }
 
+func (rs *rulesessionImpl) ScheduleJob(ctx context.Context, job model.Job) (err error) {
+	return rs.scheduler.schedule(job, nil)
+}
+
+func (rs *rulesessionImpl) CancelScheduledJob(ctx context.Context, key string) {
+	rs.scheduler.cancel(key)
+}
+
+func (rs *rulesessionImpl) GetScheduledJobs() []model.Job {
+	return rs.scheduler.list()
+}
+
+func (rs *rulesessionImpl) SetJobStore(store model.JobStore) (err error) {
+	return rs.scheduler.setStore(store)
+}
+
 func (rs *rulesessionImpl) SetStartupFunction(startupFn model.StartupRSFunction) {
 	rs.startupFn = startupFn
 }
@@ -167,12 +341,18 @@ func (rs *rulesessionImpl) Start(startupCtx map[string]interface{}) error {
 
 	if !rs.started {
 		rs.started = true
+		if rs.wal != nil {
+			if err := rs.wal.replayPending(); err != nil {
+				return err
+			}
+		}
 		if rs.startupFn != nil {
 			err := rs.startupFn(context.TODO(), rs, startupCtx)
 			if err != nil {
 				return err
 			}
 		}
+		rs.scheduler.start()
 	} else {
 		return fmt.Errorf("Rulesession [%s] already started", rs.name)
 	}
@@ -184,7 +364,105 @@ func (rs *rulesessionImpl) GetAssertedTuple(key model.TupleKey) model.Tuple {
 }
 
 func (rs *rulesessionImpl) RegisterRtcTransactionHandler(txnHandler model.RtcTransactionHandler, txnContext interface{}) {
-	rs.reteNetwork.RegisterRtcTransactionHandler(txnHandler, txnContext)
+	rs.txnHandler = txnHandler
+	rs.txnContext = txnContext
+	rs.registerTxnHandler()
+}
+
+//registerTxnHandler has the network call handleTxn, if there is a handler or a write-ahead log
+func (rs *rulesessionImpl) registerTxnHandler() {
+	if rs.txnHandler == nil && rs.wal == nil {
+		rs.reteNetwork.RegisterRtcTransactionHandler(nil, nil)
+	} else {
+		rs.reteNetwork.RegisterRtcTransactionHandler(rs.handleTxn, nil)
+	}
+}
+
+func (rs *rulesessionImpl) handleTxn(ctx context.Context, session model.RuleSession, txn model.RtcTxn, handlerCtx interface{}) {
+	if rs.wal != nil {
+		rs.wal.logCommit(ctx, txn)
+	}
+	if rs.txnHandler != nil {
+		rs.txnHandler(ctx, session, txn, rs.txnContext)
+	}
+}
+
+func (rs *rulesessionImpl) SetConflictResolver(resolver model.ConflictResolver) {
+	rs.reteNetwork.SetConflictResolver(resolver)
+}
+
+func (rs *rulesessionImpl) GetConflictResolver() model.ConflictResolver {
+	return rs.reteNetwork.GetConflictResolver()
+}
+
+func (rs *rulesessionImpl) SetClock(clock model.Clock) (err error) {
+	if rs.started {
+		return fmt.Errorf("Cannot change the clock. Rulesession [%s] already started", rs.name)
+	}
+	if len(rs.scheduler.list()) > 0 {
+		return fmt.Errorf("Cannot change the clock. Rulesession [%s] has scheduled jobs", rs.name)
+	}
+	return rs.reteNetwork.SetClock(clock)
+}
+
+func (rs *rulesessionImpl) GetClock() model.Clock {
+	return rs.reteNetwork.GetClock()
+}
+
+func (rs *rulesessionImpl) SetFocus(ctx context.Context, agendaGroup string) {
+	rs.reteNetwork.SetFocus(ctx, rs, agendaGroup)
+}
+
+func (rs *rulesessionImpl) GetFocus(ctx context.Context) string {
+	return rs.reteNetwork.GetFocus(ctx)
+}
+
+func (rs *rulesessionImpl) SetRefraction(refraction bool) {
+	rs.reteNetwork.SetRefraction(refraction)
+}
+
+func (rs *rulesessionImpl) GetRefraction() bool {
+	return rs.reteNetwork.GetRefraction()
+}
+
+func (rs *rulesessionImpl) SetExplain(explain bool) {
+	rs.reteNetwork.SetExplain(explain)
+}
+
+func (rs *rulesessionImpl) GetExplain() bool {
+	return rs.reteNetwork.GetExplain()
+}
+
+func (rs *rulesessionImpl) Diagnose(ruleName string, keys []model.TupleKey) (model.Diagnosis, error) {
+	strKeys := make([]string, len(keys))
+	for i, key := range keys {
+		strKeys[i] = key.String()
+	}
+	return rs.reteNetwork.Diagnose(ruleName, strKeys)
+}
+
+func (rs *rulesessionImpl) AddQuery(query model.Query) (err error) {
+	return rs.reteNetwork.AddQuery(query)
+}
+
+func (rs *rulesessionImpl) DeleteQuery(queryName string) {
+	rs.reteNetwork.RemoveQuery(queryName)
+}
+
+func (rs *rulesessionImpl) GetQueries() []model.Query {
+	return rs.reteNetwork.GetQueries()
+}
+
+func (rs *rulesessionImpl) RunQuery(ctx context.Context, queryName string, params map[string]interface{}) ([]model.QueryResult, error) {
+	return rs.reteNetwork.RunQuery(ctx, queryName, params)
+}
+
+func (rs *rulesessionImpl) AddLiveQuery(ctx context.Context, query model.Query, params map[string]interface{}, listener model.LiveQueryListener) (err error) {
+	return rs.reteNetwork.AddLiveQuery(ctx, rs, query, params, listener)
+}
+
+func (rs *rulesessionImpl) RemoveLiveQuery(queryName string) {
+	rs.reteNetwork.RemoveLiveQuery(queryName)
 }
 
 func (rs *rulesessionImpl) ReplayTuplesForRule(ruleName string) (err error) {
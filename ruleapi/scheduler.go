@@ -0,0 +1,300 @@
+package ruleapi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+)
+
+//scheduler runs the jobs of a session at their time, see model.Job
+type scheduler interface {
+	//schedule replaces the job of the same key, the tuple if any is asserted instead of one of the job's values
+	schedule(job model.Job, tuple model.Tuple) error
+	cancel(key string) bool
+	//list returns the pending jobs by time
+	list() []model.Job
+	//setStore keeps the jobs in the store, and schedules those it holds
+	setStore(store model.JobStore) error
+	//start arms the timers, jobs run only while the session is started
+	start()
+	stop()
+}
+
+type scheduledJob struct {
+	job   model.Job
+	tuple model.Tuple
+	cron  cronSchedule
+	timer model.Timer
+	//the tuple the last run of a recurring assert asserted
+	asserted model.Tuple
+}
+
+type schedulerImpl struct {
+	rs      *rulesessionImpl
+	lock    sync.Mutex
+	jobs    map[string]*scheduledJob
+	store   model.JobStore
+	started bool
+}
+
+func newScheduler(rs *rulesessionImpl) scheduler {
+	s := schedulerImpl{}
+	s.initSchedulerImpl(rs)
+	return &s
+}
+
+func (s *schedulerImpl) initSchedulerImpl(rs *rulesessionImpl) {
+	s.rs = rs
+	s.jobs = make(map[string]*scheduledJob)
+}
+
+func (s *schedulerImpl) schedule(job model.Job, tuple model.Tuple) error {
+	sj, err := s.newScheduledJob(job, tuple)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.store != nil {
+		if err := s.store.Save(sj.job); err != nil {
+			return err
+		}
+	}
+	if existing := s.jobs[job.Key]; existing != nil && existing.timer != nil {
+		existing.timer.Stop()
+	}
+	s.jobs[job.Key] = sj
+	if s.started {
+		s.arm(sj)
+	}
+	return nil
+}
+
+//newScheduledJob validates the job, and sets when it runs first
+func (s *schedulerImpl) newScheduledJob(job model.Job, tuple model.Tuple) (*scheduledJob, error) {
+	if job.Key == "" {
+		return nil, fmt.Errorf("Job key cannot be empty")
+	}
+	if model.GetTupleDescriptor(job.TupleType) == nil {
+		return nil, fmt.Errorf("Tuple descriptor not found [%s]", job.TupleType)
+	}
+	var err error
+	switch job.Operation {
+	case model.AssertJob:
+		if tuple == nil {
+			_, err = model.NewTuple(job.TupleType, job.Values)
+		}
+	case model.RetractJob, model.ModifyJob:
+		_, err = model.NewTupleKey(job.TupleType, job.Values)
+	default:
+		err = fmt.Errorf("Unknown job operation [%s]", job.Operation)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sj := &scheduledJob{job: job, tuple: tuple}
+	if job.Cron != "" {
+		sj.cron, err = parseCron(job.Cron)
+		if err != nil {
+			return nil, err
+		}
+		if sj.job.At.IsZero() {
+			sj.job.At = sj.cron.next(s.rs.GetClock().Now())
+			if sj.job.At.IsZero() {
+				return nil, fmt.Errorf("Cron never runs [%s]", job.Cron)
+			}
+		}
+	} else if sj.job.At.IsZero() {
+		sj.job.At = s.rs.GetClock().Now()
+	}
+	return sj, nil
+}
+
+func (s *schedulerImpl) arm(sj *scheduledJob) {
+	clock := s.rs.GetClock()
+	sj.timer = clock.AfterFunc(sj.job.At.Sub(clock.Now()), func() {
+		s.run(sj)
+	})
+}
+
+//run reschedules a cron job, else forgets it, then runs it
+func (s *schedulerImpl) run(sj *scheduledJob) {
+	s.lock.Lock()
+	if s.jobs[sj.job.Key] != sj || !s.started {
+		s.lock.Unlock()
+		return
+	}
+	job := sj.job
+	tuple := sj.tuple
+	last := sj.asserted
+	if sj.cron != nil {
+		//a recurring assert asserts a new tuple each time
+		tuple = nil
+		sj.job.At = sj.cron.next(s.rs.GetClock().Now())
+	}
+	if sj.cron != nil && !sj.job.At.IsZero() {
+		if s.store != nil {
+			if err := s.store.Save(sj.job); err != nil {
+				fmt.Printf("Cannot save scheduled job [%s]: %s\n", job.Key, err)
+			}
+		}
+		s.arm(sj)
+	} else {
+		delete(s.jobs, job.Key)
+		if s.store != nil {
+			if err := s.store.Delete(job.Key); err != nil {
+				fmt.Printf("Cannot delete scheduled job [%s]: %s\n", job.Key, err)
+			}
+		}
+	}
+	s.lock.Unlock()
+
+	if job.Operation == model.AssertJob {
+		//a recurring assert skips its run while the tuple of its last run is still asserted
+		if last != nil && s.rs.GetAssertedTuple(last.GetKey()) == last {
+			return
+		}
+		if tuple == nil {
+			var err error
+			if tuple, err = model.NewTuple(job.TupleType, job.Values); err != nil {
+				fmt.Printf("Scheduled job [%s] failed: %s\n", job.Key, err)
+				return
+			}
+		}
+	}
+	if err := s.execute(job, tuple); err != nil {
+		fmt.Printf("Scheduled job [%s] failed: %s\n", job.Key, err)
+	} else if sj.cron != nil && tuple != nil {
+		s.lock.Lock()
+		sj.asserted = tuple
+		s.lock.Unlock()
+	}
+}
+
+//execute runs the job, asserting the tuple of an assert job
+func (s *schedulerImpl) execute(job model.Job, tuple model.Tuple) error {
+	ctx := context.Background()
+	if job.Operation == model.AssertJob {
+		return s.rs.Assert(ctx, tuple)
+	}
+	key, err := model.NewTupleKey(job.TupleType, job.Values)
+	if err != nil {
+		return err
+	}
+	asserted := s.rs.GetAssertedTuple(key)
+	if asserted == nil {
+		return fmt.Errorf("Tuple with key [%s] not asserted", key.String())
+	}
+	if job.Operation == model.RetractJob {
+		return s.rs.Retract(ctx, asserted)
+	}
+	values := map[string]interface{}{}
+	for name, value := range job.Values {
+		if key.GetValue(name) == nil {
+			values[name] = value
+		}
+	}
+	return s.rs.reteNetwork.Modify(ctx, s.rs, asserted.(model.MutableTuple), values)
+}
+
+func (s *schedulerImpl) cancel(key string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sj := s.jobs[key]
+	if sj == nil {
+		return false
+	}
+	if sj.timer != nil {
+		sj.timer.Stop()
+	}
+	delete(s.jobs, key)
+	if s.store != nil {
+		s.store.Delete(key)
+	}
+	return true
+}
+
+func (s *schedulerImpl) list() []model.Job {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	jobs := make([]model.Job, 0, len(s.jobs))
+	for _, sj := range s.jobs {
+		jobs = append(jobs, sj.job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].At.Equal(jobs[j].At) {
+			return jobs[i].Key < jobs[j].Key
+		}
+		return jobs[i].At.Before(jobs[j].At)
+	})
+	return jobs
+}
+
+func (s *schedulerImpl) setStore(store model.JobStore) error {
+	stored, err := store.LoadAll()
+	if err != nil {
+		return err
+	}
+	loaded := []*scheduledJob{}
+	for _, job := range stored {
+		sj, err := s.newScheduledJob(job, nil)
+		if err != nil {
+			return fmt.Errorf("Cannot load job [%s]: %s", job.Key, err)
+		}
+		loaded = append(loaded, sj)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	//the jobs scheduled so far are kept too
+	for _, sj := range s.jobs {
+		if err := store.Save(sj.job); err != nil {
+			return err
+		}
+	}
+	s.store = store
+	for _, sj := range loaded {
+		if existing := s.jobs[sj.job.Key]; existing != nil && existing.timer != nil {
+			existing.timer.Stop()
+		}
+		s.jobs[sj.job.Key] = sj
+		if s.started {
+			s.arm(sj)
+		}
+	}
+	return nil
+}
+
+func (s *schedulerImpl) start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.started = true
+	for _, sj := range s.jobs {
+		s.arm(sj)
+	}
+}
+
+//stop cancels the timers, the stored jobs are kept for the next session
+func (s *schedulerImpl) stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.started = false
+	for _, sj := range s.jobs {
+		if sj.timer != nil {
+			sj.timer.Stop()
+			sj.timer = nil
+		}
+	}
+}
+
+//newAssertJob is the job of ScheduleAssert
+func newAssertJob(key interface{}, at time.Time, tuple model.Tuple) model.Job {
+	values := map[string]interface{}{}
+	for name, value := range tuple.GetMap() {
+		values[name] = value
+	}
+	return model.Job{Key: fmt.Sprint(key), Operation: model.AssertJob, TupleType: tuple.GetTupleType(), Values: values, At: at}
+}
@@ -0,0 +1,134 @@
+package ruleapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/rete"
+)
+
+//snapshotVersion is the version of the snapshot format, Restore reads only this one
+const snapshotVersion = 1
+
+//snapshot is the JSON written by Snapshot
+type snapshot struct {
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	//the descriptors of the types of the tuples and jobs
+	Descriptors []model.TupleDescriptor `json:"descriptors"`
+	//the asserted tuples, in the order they were last asserted or modified
+	Tuples []snapshotTuple        `json:"tuples"`
+	Focus  []string               `json:"focus,omitempty"`
+	Agenda []rete.ActivationState `json:"agenda,omitempty"`
+	//the activations justifying the logically asserted tuples
+	Justifications []rete.JustificationState `json:"justifications,omitempty"`
+	Jobs           []model.Job               `json:"jobs,omitempty"`
+}
+
+type snapshotTuple struct {
+	TupleType  model.TupleType        `json:"type"`
+	Values     map[string]interface{} `json:"values"`
+	AssertedAt time.Time              `json:"assertedAt"`
+	//milliseconds until the tuple expires, if it does
+	ExpiresIn *int64 `json:"expiresIn,omitempty"`
+}
+
+func (rs *rulesessionImpl) Snapshot(w io.Writer) (err error) {
+	state := rs.reteNetwork.Snapshot()
+	now := rs.GetClock().Now()
+	snap := snapshot{Version: snapshotVersion, Time: now, Tuples: []snapshotTuple{}, Focus: state.Focus,
+		Agenda: state.Agenda, Justifications: state.Justifications, Jobs: rs.scheduler.list()}
+	types := map[model.TupleType]bool{}
+	for _, tuple := range state.Tuples {
+		snap.Tuples = append(snap.Tuples, newSnapshotTuple(tuple, now, state.Expiries[tuple.GetKey().String()]))
+		types[tuple.GetTupleType()] = true
+	}
+	for _, job := range snap.Jobs {
+		types[job.TupleType] = true
+	}
+	for tupleType := range types {
+		if td := model.GetTupleDescriptor(tupleType); td != nil {
+			snap.Descriptors = append(snap.Descriptors, *td)
+		}
+	}
+	sort.Slice(snap.Descriptors, func(i, j int) bool {
+		return snap.Descriptors[i].Name < snap.Descriptors[j].Name
+	})
+	return json.NewEncoder(w).Encode(snap)
+}
+
+func (rs *rulesessionImpl) Restore(r io.Reader, runActions bool) (err error) {
+	snap, err := readSnapshot(r)
+	if err != nil {
+		return err
+	}
+	now := rs.GetClock().Now()
+	state := rete.NetworkState{Expiries: map[string]time.Time{}, Focus: snap.Focus, Agenda: snap.Agenda,
+		Justifications: snap.Justifications}
+	for _, st := range snap.Tuples {
+		tuple, err := st.newTuple()
+		if err != nil {
+			return err
+		}
+		if st.ExpiresIn != nil {
+			state.Expiries[tuple.GetKey().String()] = now.Add(time.Duration(*st.ExpiresIn) * time.Millisecond)
+		}
+		state.Tuples = append(state.Tuples, tuple)
+	}
+	if err = rs.reteNetwork.Restore(context.Background(), rs, state, runActions); err != nil {
+		return err
+	}
+	for _, job := range snap.Jobs {
+		if err = rs.scheduler.schedule(job, nil); err != nil {
+			return fmt.Errorf("Cannot restore job [%s]: %s", job.Key, err)
+		}
+	}
+	return nil
+}
+
+//readSnapshot reads a snapshot, and registers the descriptors not registered yet
+func readSnapshot(r io.Reader) (snapshot, error) {
+	snap := snapshot{}
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return snap, err
+	}
+	if snap.Version != snapshotVersion {
+		return snap, fmt.Errorf("Unsupported snapshot version [%d]", snap.Version)
+	}
+	for _, td := range snap.Descriptors {
+		existing := model.GetTupleDescriptor(model.TupleType(td.Name))
+		if existing == nil {
+			model.RegisterTupleDescriptorsFromTds([]model.TupleDescriptor{td})
+		} else if !reflect.DeepEqual(existing.Props, td.Props) {
+			return snap, fmt.Errorf("Tuple descriptor [%s] differs from the snapshot", td.Name)
+		}
+	}
+	return snap, nil
+}
+
+//newSnapshotTuple records the tuple, and when it expires if deadline is not zero
+func newSnapshotTuple(tuple model.Tuple, now time.Time, deadline time.Time) snapshotTuple {
+	st := snapshotTuple{TupleType: tuple.GetTupleType(), Values: tuple.GetMap(), AssertedAt: model.GetAssertionTime(tuple)}
+	if !deadline.IsZero() {
+		expiresIn := int64(deadline.Sub(now) / time.Millisecond)
+		st.ExpiresIn = &expiresIn
+	}
+	return st
+}
+
+func (st snapshotTuple) newTuple() (model.Tuple, error) {
+	tuple, err := model.NewTuple(st.TupleType, st.Values)
+	if err != nil {
+		return nil, err
+	}
+	if !st.AssertedAt.IsZero() {
+		model.SetAssertionTime(tuple, st.AssertedAt)
+	}
+	return tuple, nil
+}
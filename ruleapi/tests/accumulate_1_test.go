@@ -0,0 +1,256 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/config"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//A t1 with more than 2 t3 of the same p3, totalling over 100
+func Test_Accumulate_1(t *testing.T) {
+
+	fired := map[string][]float64{}
+	rs, _ := createRuleSession()
+
+	r1 := ruleapi.NewRule("bigT3s")
+	total, err := r1.AddAccumulate("t3Total", model.SumFunction, "t3.p2", "t3.p3")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = total.AddExprCondition("c1", "$.t3.p1 > 0", nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r1.AddExprCondition("c2", "($.t1.p3 == $.t3Total.key) && $.t3Total.value > 100 && $.t3Total.count > 2", nil)
+	r1.SetAction(accumulateAction)
+	r1.SetContext(fired)
+	err = rs.AddRule(r1)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	rs.Start(nil)
+
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	t1.SetString(context.TODO(), "p3", "a")
+	rs.Assert(context.TODO(), t1)
+
+	t3s := []model.Tuple{}
+	for i, id := range []string{"t3_a", "t3_b", "t3_c", "t3_d", "t3_e"} {
+		t3, _ := model.NewTupleWithKeyValues("t3", id)
+		t3.SetInt(context.TODO(), "p1", 1)
+		t3.SetDouble(context.TODO(), "p2", 50.0)
+		t3.SetString(context.TODO(), "p3", "a")
+		if i == 3 {
+			//filtered out
+			t3.SetInt(context.TODO(), "p1", 0)
+		} else if i == 4 {
+			//another group
+			t3.SetString(context.TODO(), "p3", "b")
+		}
+		rs.Assert(context.TODO(), t3)
+		t3s = append(t3s, t3)
+	}
+	expectValues(t, fired["bigT3s"], 150.0)
+
+	//retracting withdraws the result, a new t3 brings the total back up
+	rs.Retract(context.TODO(), t3s[0])
+	t3, _ := model.NewTupleWithKeyValues("t3", "t3_f")
+	t3.SetInt(context.TODO(), "p1", 1)
+	t3.SetDouble(context.TODO(), "p2", 60.0)
+	t3.SetString(context.TODO(), "p3", "a")
+	rs.Assert(context.TODO(), t3)
+	expectValues(t, fired["bigT3s"], 150.0, 160.0)
+
+	rs.Unregister()
+}
+
+//count, min, max, avg and collect over all t3, kept up to date as t3 are modified
+func Test_Accumulate_2(t *testing.T) {
+
+	fired := map[string][]float64{}
+	rs, _ := createRuleSession()
+
+	for _, fn := range []model.AccumulateFunction{model.CountFunction, model.MinFunction, model.MaxFunction,
+		model.AvgFunction, model.CollectFunction} {
+		r := ruleapi.NewRule(string(fn))
+		_, err := r.AddAccumulate("t3"+string(fn), fn, "t3.p2", "")
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		r.SetAction(accumulateAction)
+		r.SetContext(fired)
+		rs.AddRule(r)
+	}
+	r := ruleapi.NewRule("modifyT3")
+	r.AddCondition("c1", []string{"t2"}, trueCondition, nil)
+	r.SetAction(modifyT3Action)
+	rs.AddRule(r)
+
+	rs.Start(nil)
+
+	for i, p2 := range []float64{10.0, 30.0, 20.0} {
+		t3, _ := model.NewTupleWithKeyValues("t3", "t3_"+string(rune('a'+i)))
+		t3.SetDouble(context.TODO(), "p2", p2)
+		rs.Assert(context.TODO(), t3)
+	}
+	expectValues(t, fired["count"], 1, 2, 3)
+	expectValues(t, fired["min"], 10, 10, 10)
+	expectValues(t, fired["max"], 10, 30, 30)
+	expectValues(t, fired["avg"], 10, 20, 20)
+	expectValues(t, fired["collect"], 1, 2, 3)
+
+	rs.Retract(context.TODO(), rs.GetAssertedTuple(t3Key("t3_a")))
+	expectValues(t, fired["min"], 10, 10, 10, 20)
+
+	//t3_b goes from 30 to 5 in an action
+	t2, _ := model.NewTupleWithKeyValues("t2", "t2_a")
+	rs.Assert(context.TODO(), t2)
+	expectValues(t, fired["count"], 1, 2, 3, 2, 2)
+	expectValues(t, fired["min"], 10, 10, 10, 20, 5)
+	expectValues(t, fired["max"], 10, 30, 30, 30, 20)
+	expectValues(t, fired["avg"], 10, 20, 20, 25, 12.5)
+
+	rs.Unregister()
+}
+
+//Accumulates from the JSON config, and errors
+func Test_Accumulate_3(t *testing.T) {
+
+	accumulateFired = map[string][]float64{}
+	config.RegisterActionFunction("accumulateConfigAction", accumulateConfigAction)
+	createRuleSession()
+
+	rs, err := ruleapi.GetOrCreateRuleSessionFromConfig("test", `{
+		"rules": [{
+			"name": "countT3",
+			"conditions": [{"name": "c1", "expression": "$.t3Count.value >= 2"}],
+			"accumulates": [{
+				"name": "t3Count",
+				"function": "count",
+				"over": "t3",
+				"groupBy": "t3.p3",
+				"conditions": [{"name": "c2", "expression": "$.t3.p1 > 0"}]
+			}],
+			"actionFunction": "accumulateConfigAction"
+		}]
+	}`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	rs.Start(nil)
+
+	for i, id := range []string{"t3_a", "t3_b", "t3_c", "t3_d"} {
+		t3, _ := model.NewTupleWithKeyValues("t3", id)
+		t3.SetInt(context.TODO(), "p1", i)
+		t3.SetString(context.TODO(), "p3", "a")
+		rs.Assert(context.TODO(), t3)
+	}
+	expectValues(t, accumulateFired["countT3"], 2, 3)
+	rs.Unregister()
+
+	r1 := ruleapi.NewRule("badAccumulate")
+	if _, err := r1.AddAccumulate("t3Median", "median", "t3.p2", ""); err == nil {
+		t.Errorf("Expected an error for an unknown function")
+	}
+	if _, err := r1.AddAccumulate("t3Sum", model.SumFunction, "t3", ""); err == nil {
+		t.Errorf("Expected an error for a sum without a property")
+	}
+	if _, err := r1.AddAccumulate("t3Sum", model.SumFunction, "t3.p2", "t1.p3"); err == nil {
+		t.Errorf("Expected an error for grouping by another tuple type")
+	}
+	if _, err := r1.AddAccumulate("t1", model.SumFunction, "t3.p2", ""); err == nil {
+		t.Errorf("Expected an error for a result type clashing with a tuple type")
+	}
+	acc, _ := r1.AddAccumulate("t3Sum", model.SumFunction, "t3.p2", "")
+	if acc.AddExprCondition("c1", "$.t1.p1 > 0", nil) == nil {
+		t.Errorf("Expected an error for a condition on another tuple type")
+	}
+}
+
+func expectValues(t *testing.T, got []float64, expected ...float64) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Errorf("Expected %v, got %v\n", expected, got)
+		return
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("Expected %v, got %v\n", expected, got)
+			return
+		}
+	}
+}
+
+func t3Key(id string) model.TupleKey {
+	key, _ := model.NewTupleKeyWithKeyValues("t3", id)
+	return key
+}
+
+//The type of an accumulate's result tuples is registered as its rule is added, a rule never added
+//leaves none behind
+func Test_Accumulate_4(t *testing.T) {
+
+	rs, _ := createRuleSession()
+
+	r1 := ruleapi.NewRule("notAdded")
+	if _, err := r1.AddAccumulate("t3Result", model.CountFunction, "t3", ""); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := r1.AddExprCondition("c1", "$.t3Result.value > 1", nil); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if model.GetTupleDescriptor("t3Result") != nil {
+		t.Errorf("Expected [t3Result] not registered before its rule is added")
+	}
+
+	r2 := ruleapi.NewRule("added")
+	if _, err := r2.AddAccumulate("t3Result", model.SumFunction, "t3.p2", ""); err != nil {
+		t.Fatalf("%s", err)
+	}
+	r2.AddExprCondition("c1", "$.t3Result.value > 1", nil)
+	r2.SetAction(emptyAction)
+	if err := rs.AddRule(r2); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if model.GetTupleDescriptor("t3Result") == nil {
+		t.Errorf("Expected [t3Result] registered")
+	}
+	r1.SetAction(emptyAction)
+	if rs.AddRule(r1) == nil {
+		t.Errorf("Expected an error adding a rule counting into [t3Result] summed into by another")
+	}
+
+	rs.Unregister()
+}
+
+//records the value of each accumulate result, or the number of tuples collected
+func accumulateAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	fired := ruleCtx.(map[string][]float64)
+	for tupleType, tuple := range tuples {
+		if tupleType == "t1" {
+			continue
+		}
+		if tupleType == "t3collect" {
+			fired[ruleName] = append(fired[ruleName], float64(len(tuple.GetMap()["value"].([]interface{}))))
+			continue
+		}
+		value, _ := tuple.GetDouble("value")
+		fired[ruleName] = append(fired[ruleName], value)
+	}
+}
+
+func modifyT3Action(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	t3 := rs.GetAssertedTuple(t3Key("t3_b")).(model.MutableTuple)
+	t3.SetDouble(ctx, "p2", 5.0)
+}
+
+var accumulateFired map[string][]float64
+
+func accumulateConfigAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	value, _ := tuples["t3Count"].GetDouble("value")
+	accumulateFired[ruleName] = append(accumulateFired[ruleName], value)
+}
@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/config"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//validate -> enrich -> decide, the focus set by an action. The later group waits for its focus
+func Test_AgendaGroup_1(t *testing.T) {
+
+	fired := []string{}
+	rs, _ := createRuleSession()
+
+	addT2Trigger(rs, focusAction)
+	for _, agendaGroup := range []string{"validate", "enrich", "", "later"} {
+		name := agendaGroup
+		if name == "" {
+			name = "decide"
+		}
+		r := ruleapi.NewRule(name)
+		r.AddCondition("c1", []string{"t1"}, trueCondition, nil)
+		r.SetAgendaGroup(agendaGroup)
+		r.SetAction(firedAction)
+		r.SetContext(&fired)
+		rs.AddRule(r)
+	}
+	rs.Start(nil)
+
+	t2, _ := model.NewTupleWithKeyValues("t2", "t2_a")
+	rs.Assert(context.TODO(), t2)
+	expectFired(t, fired, "validate enrich decide")
+	if rs.GetFocus(context.TODO()) != model.MainAgendaGroup {
+		t.Errorf("Expected focus [%s], got [%s]\n", model.MainAgendaGroup, rs.GetFocus(context.TODO()))
+	}
+
+	//the pending activation fires as its group gets the focus
+	rs.SetFocus(context.TODO(), "later")
+	expectFired(t, fired, "validate enrich decide later")
+	if rs.GetFocus(context.TODO()) != model.MainAgendaGroup {
+		t.Errorf("Expected focus [%s], got [%s]\n", model.MainAgendaGroup, rs.GetFocus(context.TODO()))
+	}
+
+	rs.Unregister()
+}
+
+//The first rule of an activation group to fire cancels the others
+func Test_AgendaGroup_2(t *testing.T) {
+
+	fired := []string{}
+	rs, _ := createRuleSession()
+
+	for i, name := range []string{"bestDiscount", "otherDiscount", "notify"} {
+		r := ruleapi.NewRule(name)
+		r.AddCondition("c1", []string{"t1"}, trueCondition, nil)
+		if name != "notify" {
+			r.SetActivationGroup("discount")
+		}
+		r.SetPriority(i + 1)
+		r.SetAction(firedAction)
+		r.SetContext(&fired)
+		rs.AddRule(r)
+	}
+	rs.Start(nil)
+
+	for _, id := range []string{"t1_a", "t1_b"} {
+		t1, _ := model.NewTupleWithKeyValues("t1", id)
+		rs.Assert(context.TODO(), t1)
+	}
+	expectFired(t, fired, "bestDiscount notify bestDiscount notify")
+
+	rs.Unregister()
+}
+
+//Agenda and activation groups from the JSON config
+func Test_AgendaGroup_3(t *testing.T) {
+
+	agendaGroupFired = []string{}
+	config.RegisterActionFunction("agendaGroupAction", agendaGroupAction)
+	createRuleSession()
+
+	rs, err := ruleapi.GetOrCreateRuleSessionFromConfig("test", `{
+		"rules": [{
+			"name": "first",
+			"conditions": [{"name": "c1", "expression": "$.t1.p1 > 0"}],
+			"activationGroup": "g1",
+			"priority": 1,
+			"actionFunction": "agendaGroupAction"
+		}, {
+			"name": "second",
+			"conditions": [{"name": "c1", "expression": "$.t1.p1 > 0"}],
+			"activationGroup": "g1",
+			"priority": 2,
+			"actionFunction": "agendaGroupAction"
+		}, {
+			"name": "phase",
+			"conditions": [{"name": "c1", "expression": "$.t1.p1 > 0"}],
+			"agendaGroup": "later",
+			"actionFunction": "agendaGroupAction"
+		}]
+	}`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	rs.Start(nil)
+
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	t1.SetInt(context.TODO(), "p1", 1)
+	rs.Assert(context.TODO(), t1)
+	expectFired(t, agendaGroupFired, "first")
+
+	rs.SetFocus(context.TODO(), "later")
+	expectFired(t, agendaGroupFired, "first phase")
+
+	rs.Unregister()
+}
+
+//A retracted tuple takes its pending activations off the agenda, they do not fire once their group gets the focus
+func Test_AgendaGroup_4(t *testing.T) {
+
+	fired := []string{}
+	rs, _ := createRuleSession()
+
+	r := ruleapi.NewRule("later")
+	r.AddCondition("c1", []string{"t1"}, trueCondition, nil)
+	r.SetAgendaGroup("later")
+	r.SetAction(firedKeyAction)
+	r.SetContext(&fired)
+	rs.AddRule(r)
+	rs.Start(nil)
+
+	t1a, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	rs.Assert(context.TODO(), t1a)
+	t1b, _ := model.NewTupleWithKeyValues("t1", "t1_b")
+	rs.Assert(context.TODO(), t1b)
+	rs.Retract(context.TODO(), t1a)
+
+	rs.SetFocus(context.TODO(), "later")
+	expectFired(t, fired, "t1_b")
+
+	rs.Unregister()
+}
+
+func expectFired(t *testing.T, fired []string, expected string) {
+	t.Helper()
+	if strings.Join(fired, " ") != expected {
+		t.Errorf("Expected [%s], got [%s]\n", expected, strings.Join(fired, " "))
+	}
+}
+
+func focusAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	rs.SetFocus(ctx, "enrich")
+	rs.SetFocus(ctx, "validate")
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	rs.Assert(ctx, t1)
+}
+
+var agendaGroupFired []string
+
+func agendaGroupAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	agendaGroupFired = append(agendaGroupFired, ruleName)
+}
+
+func firedKeyAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	fired := ruleCtx.(*[]string)
+	id, _ := tuples["t1"].GetString("id")
+	*fired = append(*fired, id)
+}
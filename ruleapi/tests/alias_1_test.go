@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/config"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//Pairs of t1 with the same p1, a t1 is not paired with itself unless the rule asks for it
+func Test_Alias_1(t *testing.T) {
+
+	fired := map[string][]string{}
+	rs, _ := createRuleSession()
+
+	r1 := ruleapi.NewRule("sameP1")
+	err := r1.AddEquiJoinCondition("c1", "o1:t1.p1", "o2:t1.p1", nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r1.SetAction(aliasAction)
+	r1.SetContext(fired)
+	rs.AddRule(r1)
+
+	r2 := ruleapi.NewRule("sameP1SelfMatch")
+	r2.AddIdrsToRule([]model.TupleType{"o1:t1", "o2:t1"})
+	err = r2.AddExprCondition("c1", "$.o1.p1 == $.o2.p1", nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r2.SetSelfMatch(true)
+	r2.SetAction(aliasAction)
+	r2.SetContext(fired)
+	rs.AddRule(r2)
+
+	//the t1 with the highest p1
+	r3 := ruleapi.NewRule("highestP1")
+	r3.AddCondition("c1", []string{"o1:t1"}, trueCondition, nil)
+	g, err := r3.AddNotGroup("g1", []string{"o2:t1"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = g.AddExprCondition("c2", "$.o2.p1 > $.o1.p1", nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r3.SetAction(aliasAction)
+	r3.SetContext(fired)
+	rs.AddRule(r3)
+
+	rs.Start(nil)
+
+	for i, id := range []string{"t1_a", "t1_b", "t1_c"} {
+		t1, _ := model.NewTupleWithKeyValues("t1", id)
+		t1.SetInt(context.TODO(), "p1", i/2+1)
+		rs.Assert(context.TODO(), t1)
+	}
+
+	expectPairs(t, fired["sameP1"], "t1_a-t1_b", "t1_b-t1_a")
+	expectPairs(t, fired["sameP1SelfMatch"], "t1_a-t1_a", "t1_a-t1_b", "t1_b-t1_a", "t1_b-t1_b", "t1_c-t1_c")
+	expectPairs(t, fired["highestP1"], "t1_a", "t1_b", "t1_c")
+
+	rs.Unregister()
+}
+
+//Aliases from the JSON config, and errors
+func Test_Alias_2(t *testing.T) {
+
+	aliasFired = map[string][]string{}
+	config.RegisterActionFunction("aliasConfigAction", aliasConfigAction)
+	createRuleSession()
+
+	rs, err := ruleapi.GetOrCreateRuleSessionFromConfig("test", `{
+		"rules": [{
+			"name": "lowerP1",
+			"identifiers": ["o1:t1", "o2:t1"],
+			"conditions": [{"name": "c1", "expression": "$.o1.p1 < $.o2.p1"}],
+			"actionFunction": "aliasConfigAction"
+		}, {
+			"name": "sameP3",
+			"identifiers": ["o1:t1", "o2:t1"],
+			"conditions": [{"name": "c1", "expression": "$.o1.p3 == $.o2.p3"}],
+			"selfMatch": true,
+			"actionFunction": "aliasConfigAction"
+		}]
+	}`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	rs.Start(nil)
+
+	for i, id := range []string{"t1_a", "t1_b"} {
+		t1, _ := model.NewTupleWithKeyValues("t1", id)
+		t1.SetInt(context.TODO(), "p1", i)
+		t1.SetString(context.TODO(), "p3", "x")
+		rs.Assert(context.TODO(), t1)
+	}
+	expectPairs(t, aliasFired["lowerP1"], "t1_a-t1_b")
+	expectPairs(t, aliasFired["sameP3"], "t1_a-t1_a", "t1_a-t1_b", "t1_b-t1_a", "t1_b-t1_b")
+	rs.Unregister()
+
+	r1 := ruleapi.NewRule("badAlias")
+	if r1.AddCondition("c1", []string{"o1:t1"}, trueCondition, nil) != nil {
+		t.Errorf("Expected no error declaring an alias")
+	}
+	if r1.AddCondition("c2", []string{"o1:t3"}, trueCondition, nil) == nil {
+		t.Errorf("Expected an error rebinding an alias to another tuple type")
+	}
+	if r1.AddCondition("c3", []string{"t3:t1"}, trueCondition, nil) == nil {
+		t.Errorf("Expected an error for an alias clashing with a tuple type")
+	}
+	if r1.AddExprCondition("c4", "$.o3.p1 > 0", nil) == nil {
+		t.Errorf("Expected an error for an undeclared alias")
+	}
+	if r1.AddEquiJoinCondition("c5", "o1.p1", "o1:t1.p1", nil) == nil {
+		t.Errorf("Expected an error for an equi-join on a single identifier")
+	}
+	if r1.AddIdrsToRule([]model.TupleType{"t3", "o1:t3"}) == nil {
+		t.Errorf("Expected an error adding an alias rebound to another tuple type")
+	}
+	if len(r1.GetIdentifiers()) != 1 || len(r1.GetConditions()) != 1 {
+		t.Errorf("Expected only o1 and c1 in the rule, got %v and %d conditions\n", r1.GetIdentifiers(), len(r1.GetConditions()))
+	}
+}
+
+func expectPairs(t *testing.T, got []string, expected ...string) {
+	t.Helper()
+	sort.Strings(got)
+	if len(got) != len(expected) {
+		t.Errorf("Expected %v, got %v\n", expected, got)
+		return
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("Expected %v, got %v\n", expected, got)
+			return
+		}
+	}
+}
+
+//aliasPair is the ids of the tuples bound to o1 and o2
+func aliasPair(tuples map[model.TupleType]model.Tuple) string {
+	id1, _ := tuples["o1"].GetString("id")
+	if tuples["o2"] == nil {
+		return id1
+	}
+	id2, _ := tuples["o2"].GetString("id")
+	return id1 + "-" + id2
+}
+
+func aliasAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	fired := ruleCtx.(map[string][]string)
+	fired[ruleName] = append(fired[ruleName], aliasPair(tuples))
+}
+
+var aliasFired map[string][]string
+
+func aliasConfigAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	aliasFired[ruleName] = append(aliasFired[ruleName], aliasPair(tuples))
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//TTLs, scheduled asserts and windows follow a pseudo clock
+func Test_Clock_1(t *testing.T) {
+
+	rs, _ := createRuleSession()
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := ruleapi.NewPseudoClock(start)
+	rs.SetClock(clock)
+	addWindowRules(rs, "t1", "t7", "t8")
+	rs.Start(nil)
+
+	//t8 lives for a second
+	t8, _ := model.NewTupleWithKeyValues("t8", "t8_a")
+	rs.Assert(context.TODO(), t8)
+	clock.Advance(999 * time.Millisecond)
+	if rs.GetAssertedTuple(t8.GetKey()) == nil {
+		t.Errorf("Expected t8_a asserted")
+	}
+	clock.Advance(time.Millisecond)
+	if rs.GetAssertedTuple(t8.GetKey()) != nil {
+		t.Errorf("Expected t8_a expired")
+	}
+
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	rs.ScheduleAssert(context.TODO(), 500, "t1_a", t1)
+	clock.Advance(499 * time.Millisecond)
+	if rs.GetAssertedTuple(t1.GetKey()) != nil {
+		t.Errorf("Expected t1_a not yet asserted")
+	}
+	clock.Advance(time.Millisecond)
+	if rs.GetAssertedTuple(t1.GetKey()) == nil {
+		t.Errorf("Expected t1_a asserted")
+	}
+
+	t1b, _ := model.NewTupleWithKeyValues("t1", "t1_b")
+	rs.ScheduleAssert(context.TODO(), 500, "t1_b", t1b)
+	rs.CancelScheduledAssert(context.TODO(), "t1_b")
+	clock.Advance(time.Second)
+	if rs.GetAssertedTuple(t1b.GetKey()) != nil {
+		t.Errorf("Expected t1_b cancelled")
+	}
+
+	//t7 leaves its window after 100ms, timed from its assertion
+	t7, _ := model.NewTupleWithKeyValues("t7", "t7_a")
+	rs.Assert(context.TODO(), t7)
+	if at := model.GetEventTime(t7).Start; !at.Equal(clock.Now()) {
+		t.Errorf("Expected asserted at [%s], got [%s]\n", clock.Now(), at)
+	}
+	clock.Advance(99 * time.Millisecond)
+	if rs.GetAssertedTuple(t7.GetKey()) == nil {
+		t.Errorf("Expected t7_a in the window")
+	}
+	clock.Advance(time.Millisecond)
+	if rs.GetAssertedTuple(t7.GetKey()) != nil {
+		t.Errorf("Expected t7_a out of the window")
+	}
+
+	rs.Unregister()
+}
+
+//The clock cannot change once it times the tuples or jobs of a session
+func Test_Clock_2(t *testing.T) {
+
+	rs, _ := createRuleSession()
+	clock := ruleapi.NewPseudoClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err := rs.SetClock(clock); err != nil {
+		t.Fatalf("%s", err)
+	}
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	rs.ScheduleAssert(context.TODO(), 500, "t1_a", t1)
+	if rs.SetClock(ruleapi.NewRealtimeClock()) == nil {
+		t.Errorf("Expected an error changing the clock of scheduled jobs")
+	}
+	rs.CancelScheduledAssert(context.TODO(), "t1_a")
+	rs.Start(nil)
+	if rs.SetClock(ruleapi.NewRealtimeClock()) == nil {
+		t.Errorf("Expected an error changing the clock of a started session")
+	}
+	if rs.GetClock() != clock {
+		t.Errorf("Expected the pseudo clock kept")
+	}
+	rs.Unregister()
+}
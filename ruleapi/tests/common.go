@@ -8,11 +8,24 @@ import (
 
 	"github.com/project-flogo/rules/common"
 	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/rete"
 	"github.com/project-flogo/rules/ruleapi"
 )
 
+//newStore, when set, returns the working memory store of the sessions created
+var newStore func() (rete.WorkingMemoryStore, error)
+
 func createRuleSession() (model.RuleSession, error) {
 	rs, _ := ruleapi.GetOrCreateRuleSession("test")
+	if newStore != nil {
+		store, err := newStore()
+		if err != nil {
+			return nil, err
+		}
+		if ruleapi.SetWorkingMemoryStore(rs, store) != nil {
+			store.Close()
+		}
+	}
 
 	tupleDescFileAbsPath := common.GetAbsPathForResource("src/github.com/project-flogo/rules/ruleapi/tests/tests.json")
 
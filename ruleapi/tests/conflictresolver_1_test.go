@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//t1Only, t3Only and t1AndT3 get activated in one RTC, the t3 asserted after the t1
+func Test_ConflictResolver_1(t *testing.T) {
+
+	for resolver, expected := range map[string]string{
+		ruleapi.SalienceResolver: "t1AndT3 t3Only t1Only",
+		ruleapi.LexResolver:      "t1AndT3 t3Only t1Only",
+		ruleapi.MeaResolver:      "t3Only t1AndT3 t1Only",
+		ruleapi.FifoResolver:     "t1Only t3Only t1AndT3",
+		ruleapi.LifoResolver:     "t1AndT3 t3Only t1Only",
+	} {
+		fired := []string{}
+		rs, _ := createRuleSession()
+		rs.SetConflictResolver(ruleapi.GetConflictResolver(resolver))
+
+		addT2Trigger(rs, assertT1T3Action)
+		for _, ruleIdrs := range [][]string{{"t1"}, {"t3"}, {"t1", "t3"}} {
+			name := ruleIdrs[0] + "Only"
+			if len(ruleIdrs) == 2 {
+				name = "t1AndT3"
+			}
+			r := ruleapi.NewRule(name)
+			r.AddCondition("c1", ruleIdrs, trueCondition, nil)
+			r.SetAction(firedAction)
+			r.SetContext(&fired)
+			rs.AddRule(r)
+		}
+		rs.Start(nil)
+
+		t2, _ := model.NewTupleWithKeyValues("t2", "t2_a")
+		rs.Assert(context.TODO(), t2)
+		if strings.Join(fired, " ") != expected {
+			t.Errorf("%s: expected [%s], got [%s]\n", resolver, expected, strings.Join(fired, " "))
+		}
+		rs.Unregister()
+	}
+}
+
+//Priorities come first but for FIFO and LIFO, specificity prefers the rule with more conditions
+func Test_ConflictResolver_2(t *testing.T) {
+
+	for resolver, expected := range map[string]string{
+		ruleapi.SalienceResolver:    "important oneCondition twoConditions",
+		ruleapi.SpecificityResolver: "important twoConditions oneCondition",
+		ruleapi.FifoResolver:        "twoConditions oneCondition important",
+	} {
+		fired := []string{}
+		rs, _ := createRuleSession()
+		rs.SetConflictResolver(ruleapi.GetConflictResolver(resolver))
+
+		r1 := ruleapi.NewRule("oneCondition")
+		r1.AddCondition("c1", []string{"t1"}, trueCondition, nil)
+		r2 := ruleapi.NewRule("twoConditions")
+		r2.AddCondition("c1", []string{"t1"}, trueCondition, nil)
+		r2.AddCondition("c2", []string{"t1"}, trueCondition, nil)
+		r3 := ruleapi.NewRule("important")
+		r3.AddCondition("c1", []string{"t1"}, trueCondition, nil)
+		r3.SetPriority(-1)
+		for _, r := range []model.MutableRule{r2, r1, r3} {
+			r.SetAction(firedAction)
+			r.SetContext(&fired)
+			rs.AddRule(r)
+		}
+		rs.Start(nil)
+
+		t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+		rs.Assert(context.TODO(), t1)
+		if strings.Join(fired, " ") != expected {
+			t.Errorf("%s: expected [%s], got [%s]\n", resolver, expected, strings.Join(fired, " "))
+		}
+		rs.Unregister()
+	}
+}
+
+//The resolver from the JSON config, and errors
+func Test_ConflictResolver_3(t *testing.T) {
+
+	createRuleSession()
+	rs, err := ruleapi.GetOrCreateRuleSessionFromConfig("test", `{"conflictResolver": "lex", "rules": []}`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rs.GetConflictResolver().GetName() != ruleapi.LexResolver {
+		t.Errorf("Expected [%s], got [%s]\n", ruleapi.LexResolver, rs.GetConflictResolver().GetName())
+	}
+	rs.Unregister()
+
+	createRuleSession()
+	_, err = ruleapi.GetOrCreateRuleSessionFromConfig("test", `{"conflictResolver": "random", "rules": []}`)
+	if err == nil {
+		t.Errorf("Expected an error for an unknown conflict resolver")
+	}
+	rs, _ = ruleapi.GetOrCreateRuleSession("test")
+	rs.Unregister()
+
+	if ruleapi.RegisterConflictResolver(ruleapi.GetConflictResolver(ruleapi.FifoResolver)) == nil {
+		t.Errorf("Expected an error registering a resolver twice")
+	}
+}
+
+func addT2Trigger(rs model.RuleSession, actionFn model.ActionFunction) {
+	r := ruleapi.NewRule("t2Trigger")
+	r.AddCondition("c1", []string{"t2"}, trueCondition, nil)
+	r.SetAction(actionFn)
+	rs.AddRule(r)
+}
+
+func assertT1T3Action(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	rs.Assert(ctx, t1)
+	t3, _ := model.NewTupleWithKeyValues("t3", "t3_a")
+	rs.Assert(ctx, t3)
+}
+
+func firedAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	fired := ruleCtx.(*[]string)
+	*fired = append(*fired, ruleName)
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//Why the vip rule, joining an order t1 to its customer t3, did not fire for some orders
+func Test_Diagnose_1(t *testing.T) {
+
+	rs, _ := createRuleSession()
+	r := ruleapi.NewRule("vip")
+	r.AddExprCondition("c1", "$.t1.p1 > 100", nil)
+	r.AddExprCondition("c2", "$.t1.p3 == $.t3.p3", nil)
+	r.SetActivationGroup("discount")
+	r.SetPriority(2)
+	r.SetAction(emptyAction)
+	rs.AddRule(r)
+	r = ruleapi.NewRule("regular")
+	r.AddExprCondition("c3", "$.t1.p1 > 0", nil)
+	r.SetActivationGroup("discount")
+	r.SetPriority(1)
+	r.SetAction(emptyAction)
+	rs.AddRule(r)
+	rs.Start(nil)
+
+	assertOrder := func(id string, amount int, customer string) model.TupleKey {
+		t1, _ := model.NewTupleWithKeyValues("t1", id)
+		t1.SetInt(context.TODO(), "p1", amount)
+		t1.SetString(context.TODO(), "p3", customer)
+		rs.Assert(context.TODO(), t1)
+		return t1.GetKey()
+	}
+	assertCustomer := func(id string, customer string) model.TupleKey {
+		t3, _ := model.NewTupleWithKeyValues("t3", id)
+		t3.SetString(context.TODO(), "p3", customer)
+		rs.Assert(context.TODO(), t3)
+		return t3.GetKey()
+	}
+
+	//the order is too small
+	d, err := rs.Diagnose("vip", []model.TupleKey{assertCustomer("t3_a", "a"), assertOrder("t1_a", 50, "a")})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	c1, c2 := d.Conditions[0], d.Conditions[1]
+	if d.Tuples["t1"] == "" || d.Tuples["t3"] == "" || len(d.Unmatched) != 0 || d.Activation != model.ActivationNone {
+		t.Errorf("Expected t1 and t3 bound and no activation, got %v\n", d)
+	}
+	if c1.Name != "c1" || !c1.Evaluated || c1.Passed || c1.Values["t1"]["p1"] != 50 || c2.Name != "c2" || !c2.Evaluated || !c2.Passed {
+		t.Errorf("Expected c1 failed and c2 passed, got %v\n", d.Conditions)
+	}
+
+	//the customer is not asserted
+	t3b, _ := model.NewTupleKeyWithKeyValues("t3", "t3_b")
+	d, _ = rs.Diagnose("vip", []model.TupleKey{assertOrder("t1_b", 500, "b"), t3b})
+	c1, c2 = d.Conditions[0], d.Conditions[1]
+	if len(d.NotAsserted) != 1 || d.NotAsserted[0] != t3b.String() || len(d.Unmatched) != 1 || d.Unmatched[0] != "t3" {
+		t.Errorf("Expected t3 unmatched, got %v\n", d)
+	}
+	if !c1.Evaluated || !c1.Passed || c2.Evaluated {
+		t.Errorf("Expected c1 passed and c2 not evaluated, got %v\n", d.Conditions)
+	}
+
+	//the regular discount fired first
+	keys := []model.TupleKey{assertCustomer("t3_c", "c"), assertOrder("t1_c", 500, "c")}
+	d, _ = rs.Diagnose("vip", keys)
+	if d.Activation != model.ActivationCancelled || d.CancelReason != model.CancelActivationGroup {
+		t.Errorf("Expected the activation cancelled by its activation group, got %v\n", d)
+	}
+	d, _ = rs.Diagnose("regular", keys[1:])
+	if d.Activation != model.ActivationFired || len(d.Conditions) != 1 || !d.Conditions[0].Passed {
+		t.Errorf("Expected regular fired, got %v\n", d)
+	}
+
+	if _, err = rs.Diagnose("unknown", keys); err == nil {
+		t.Errorf("Expected an error for an unknown rule\n")
+	}
+	rs.Unregister()
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//Equi-join conditions, both Go and expression, only fire for tuples with equal join properties
+func Test_EquiJoin_1(t *testing.T) {
+
+	fired := map[string]int{}
+	rs, _ := createRuleSession()
+
+	r1 := ruleapi.NewRule("goEquiJoin")
+	err := r1.AddEquiJoinCondition("c1", "t1.p1", "t3.p1", nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r1.SetAction(equiJoinAction)
+	r1.SetContext(fired)
+	rs.AddRule(r1)
+
+	r2 := ruleapi.NewRule("exprEquiJoin")
+	err = r2.AddExprCondition("c1", "($.t1.p3 == $.t3.p3) && $.t1.p2 > 0", nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r2.SetAction(equiJoinAction)
+	r2.SetContext(fired)
+	rs.AddRule(r2)
+
+	rs.Start(nil)
+
+	for i := 0; i < 10; i++ {
+		t1, _ := model.NewTupleWithKeyValues("t1", "t1_"+string(rune('a'+i)))
+		t1.SetInt(context.TODO(), "p1", i)
+		t1.SetDouble(context.TODO(), "p2", 1.0)
+		t1.SetString(context.TODO(), "p3", string(rune('a'+i%5)))
+		rs.Assert(context.TODO(), t1)
+	}
+
+	t3, _ := model.NewTupleWithKeyValues("t3", "t3_a")
+	t3.SetLong(context.TODO(), "p1", 3)
+	t3.SetString(context.TODO(), "p3", "b")
+	rs.Assert(context.TODO(), t3)
+
+	if fired["goEquiJoin"] != 1 {
+		t.Errorf("goEquiJoin: expected [%d], got [%d]\n", 1, fired["goEquiJoin"])
+	}
+	if fired["exprEquiJoin"] != 2 {
+		t.Errorf("exprEquiJoin: expected [%d], got [%d]\n", 2, fired["exprEquiJoin"])
+	}
+
+	//a new t1 probes the t3 index
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_z")
+	t1.SetInt(context.TODO(), "p1", 3)
+	t1.SetDouble(context.TODO(), "p2", 1.0)
+	t1.SetString(context.TODO(), "p3", "z")
+	rs.Assert(context.TODO(), t1)
+
+	if fired["goEquiJoin"] != 2 {
+		t.Errorf("goEquiJoin: expected [%d], got [%d]\n", 2, fired["goEquiJoin"])
+	}
+	if fired["exprEquiJoin"] != 2 {
+		t.Errorf("exprEquiJoin: expected [%d], got [%d]\n", 2, fired["exprEquiJoin"])
+	}
+
+	rs.Unregister()
+}
+
+func Test_EquiJoin_2(t *testing.T) {
+
+	r1 := ruleapi.NewRule("badEquiJoin")
+	if r1.AddEquiJoinCondition("c1", "t1.p1", "t1.p2", nil) == nil {
+		t.Errorf("Expected an error for an equi-join within the same identifier")
+	}
+	if r1.AddEquiJoinCondition("c1", "t1", "t3.p1", nil) == nil {
+		t.Errorf("Expected an error for a missing property")
+	}
+}
+
+func equiJoinAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	fired := ruleCtx.(map[string]int)
+	fired[ruleName]++
+}
@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/config"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//An exists group fires once for a t1 however many t3 with the same p1 are asserted
+func Test_Exists_1(t *testing.T) {
+
+	fired := map[string]int{}
+	rs, _ := createRuleSession()
+
+	r1 := ruleapi.NewRule("anyT3")
+	r1.AddCondition("c1", []string{"t1"}, trueCondition, nil)
+	g, err := r1.AddExistsGroup("g1", []string{"t3"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = g.AddEquiJoinCondition("c2", "t1.p1", "t3.p1", nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r1.SetAction(notAction)
+	r1.SetContext(fired)
+	err = rs.AddRule(r1)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	rs.Start(nil)
+
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	t1.SetInt(context.TODO(), "p1", 1)
+	rs.Assert(context.TODO(), t1)
+	if fired["t1_a"] != 0 {
+		t.Errorf("t1_a: expected [%d], got [%d]\n", 0, fired["t1_a"])
+	}
+
+	t3s := []model.Tuple{}
+	for _, id := range []string{"t3_a", "t3_b", "t3_c"} {
+		t3, _ := model.NewTupleWithKeyValues("t3", id)
+		t3.SetInt(context.TODO(), "p1", 1)
+		rs.Assert(context.TODO(), t3)
+		t3s = append(t3s, t3)
+	}
+	if fired["t1_a"] != 1 {
+		t.Errorf("t1_a: expected [%d], got [%d]\n", 1, fired["t1_a"])
+	}
+
+	//the match is withdrawn with the last t3, and made again with a new one
+	for _, t3 := range t3s {
+		rs.Retract(context.TODO(), t3)
+	}
+	t3, _ := model.NewTupleWithKeyValues("t3", "t3_d")
+	t3.SetInt(context.TODO(), "p1", 1)
+	rs.Assert(context.TODO(), t3)
+	if fired["t1_a"] != 2 {
+		t.Errorf("t1_a: expected [%d], got [%d]\n", 2, fired["t1_a"])
+	}
+
+	rs.Unregister()
+}
+
+//Condition groups from the JSON config
+func Test_Exists_2(t *testing.T) {
+
+	existsFired = map[string]int{}
+	config.RegisterActionFunction("existsAction", existsAction)
+	createRuleSession()
+
+	rs, err := ruleapi.GetOrCreateRuleSessionFromConfig("test", `{
+		"rules": [{
+			"name": "anyT3",
+			"conditions": [{"name": "c1", "expression": "$.t1.p1 > 0"}],
+			"groups": [{
+				"name": "g1",
+				"type": "exists",
+				"identifiers": ["t3"],
+				"conditions": [{"name": "c2", "expression": "$.t1.p1 == $.t3.p1"}]
+			}],
+			"actionFunction": "existsAction"
+		}, {
+			"name": "noT3",
+			"conditions": [{"name": "c1", "expression": "$.t1.p1 > 0"}],
+			"groups": [{
+				"name": "g1",
+				"type": "not",
+				"identifiers": ["t3"],
+				"conditions": [{"name": "c2", "expression": "$.t1.p1 == $.t3.p1"}]
+			}],
+			"actionFunction": "existsAction"
+		}]
+	}`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	rs.Start(nil)
+
+	t3, _ := model.NewTupleWithKeyValues("t3", "t3_a")
+	t3.SetInt(context.TODO(), "p1", 1)
+	rs.Assert(context.TODO(), t3)
+	for i, id := range []string{"t1_a", "t1_b"} {
+		t1, _ := model.NewTupleWithKeyValues("t1", id)
+		t1.SetInt(context.TODO(), "p1", i+1)
+		rs.Assert(context.TODO(), t1)
+	}
+
+	if existsFired["anyT3"] != 1 {
+		t.Errorf("anyT3: expected [%d], got [%d]\n", 1, existsFired["anyT3"])
+	}
+	if existsFired["noT3"] != 1 {
+		t.Errorf("noT3: expected [%d], got [%d]\n", 1, existsFired["noT3"])
+	}
+
+	_, err = ruleapi.GetOrCreateRuleSessionFromConfig("test", `{"rules": [{"name": "badT3",
+		"conditions": [{"name": "c1", "expression": "$.t1.p1 > 0"}],
+		"groups": [{"name": "g1", "type": "forall", "identifiers": ["t3"]}]}]}`)
+	if err == nil {
+		t.Errorf("Expected an error for an unknown condition group type")
+	}
+
+	rs.Unregister()
+}
+
+var existsFired map[string]int
+
+func existsAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	existsFired[ruleName]++
+}
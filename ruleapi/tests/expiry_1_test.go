@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//t8 tuples due together expire in a single RTC, retracted ones do not
+func Test_Expiry_1(t *testing.T) {
+
+	txns := []map[string]bool{}
+	rs, _ := createRuleSession()
+	clock := ruleapi.NewPseudoClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	rs.SetClock(clock)
+	rs.RegisterRtcTransactionHandler(func(ctx context.Context, rs model.RuleSession, rtxn model.RtcTxn, handlerCtx interface{}) {
+		if len(rtxn.GetRtcDeleted()) > 0 {
+			deleted := map[string]bool{}
+			tupleDeletedHandler(ctx, rs, rtxn, deleted)
+			txns = append(txns, deleted)
+		}
+	}, nil)
+	addWindowRules(rs, "t8")
+	rs.Start(nil)
+
+	for _, id := range []string{"t8_a", "t8_b", "t8_c"} {
+		t8, _ := model.NewTupleWithKeyValues("t8", id)
+		rs.Assert(context.TODO(), t8)
+	}
+	t8b, _ := model.NewTupleWithKeyValues("t8", "t8_b")
+	rs.Retract(context.TODO(), rs.GetAssertedTuple(t8b.GetKey()))
+	clock.Advance(500 * time.Millisecond)
+	t8d, _ := model.NewTupleWithKeyValues("t8", "t8_d")
+	rs.Assert(context.TODO(), t8d)
+
+	clock.Advance(500 * time.Millisecond)
+	if len(txns) != 1 || len(txns[0]) != 2 || !txns[0]["t8_a"] || !txns[0]["t8_c"] {
+		t.Errorf("Expected t8_a and t8_c deleted together, got %v\n", txns)
+	}
+	if rs.GetAssertedTuple(t8d.GetKey()) == nil {
+		t.Errorf("Expected t8_d asserted")
+	}
+
+	//a closed session stops expiring its tuples
+	rs.Unregister()
+	clock.Advance(time.Second)
+	if len(txns) != 1 || rs.GetAssertedTuple(t8d.GetKey()) == nil {
+		t.Errorf("Expected t8_d kept once the session is closed")
+	}
+}
+
+//A rule matches t1 tuples expiring, asserted with TTLs of their own
+func Test_Expiry_2(t *testing.T) {
+
+	expired := map[string]bool{}
+	rs, _ := createRuleSession()
+	clock := ruleapi.NewPseudoClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	rs.SetClock(clock)
+	addWindowRules(rs, "t1")
+	r := ruleapi.NewRule("timeout")
+	err := r.AddExprCondition("c1", `$.tupleExpired.tupleType == "t1"`, nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r.SetAction(expiredAction)
+	r.SetContext(expired)
+	rs.AddRule(r)
+	rs.Start(nil)
+
+	t1a, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	rs.AssertWithTTL(context.TODO(), t1a, 2*time.Second)
+	t1b, _ := model.NewTupleWithKeyValues("t1", "t1_b")
+	rs.Assert(context.TODO(), t1b)
+	t1c, _ := model.NewTupleWithKeyValues("t1", "t1_c")
+	rs.AssertWithTTL(context.TODO(), t1c, 2*time.Second)
+	rs.Retract(context.TODO(), t1c)
+
+	clock.Advance(2 * time.Second)
+	if len(expired) != 1 || !expired["t1_a"] {
+		t.Errorf("Expected t1_a expired, got %v\n", expired)
+	}
+	if rs.GetAssertedTuple(t1a.GetKey()) != nil || rs.GetAssertedTuple(t1b.GetKey()) == nil {
+		t.Errorf("Expected t1_a deleted, t1_b asserted")
+	}
+	key, _ := model.NewTupleKeyWithKeyValues(model.ExpiredTupleType, t1a.GetKey().String())
+	if rs.GetAssertedTuple(key) != nil {
+		t.Errorf("Expected the expiry of t1_a retracted")
+	}
+
+	rs.Unregister()
+}
+
+func expiredAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	tuple := tuples[model.ExpiredTupleType].GetMap()["tuple"].(model.Tuple)
+	id, _ := tuple.GetString("id")
+	ruleCtx.(map[string]bool)[id] = true
+}
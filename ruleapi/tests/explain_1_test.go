@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/rete"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//The explanation of a join, from the action's context and the RTC's transaction
+func Test_Explain_1(t *testing.T) {
+
+	var fromAction *model.Explanation
+	var fromTxn []model.Explanation
+	rs, _ := createRuleSession()
+	r := ruleapi.NewRule("approve")
+	r.AddExprCondition("c1", "$.t1.p1 > 0", nil)
+	r.AddExprCondition("c2", "$.t1.p3 == $.t3.p3", nil)
+	r.AddCondition("c3", []string{"t3.p2"}, trueCondition, nil)
+	r.SetAction(func(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+		fromAction = model.GetExplanation(ctx)
+	})
+	rs.AddRule(r)
+	rs.RegisterRtcTransactionHandler(func(ctx context.Context, rs model.RuleSession, txn model.RtcTxn, handlerCtx interface{}) {
+		fromTxn = append(fromTxn, txn.GetExplanations()...)
+	}, nil)
+	rs.SetExplain(true)
+	rs.Start(nil)
+
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	t1.SetInt(context.TODO(), "p1", 5)
+	t1.SetString(context.TODO(), "p3", "gold")
+	rs.Assert(context.TODO(), t1)
+	t3, _ := model.NewTupleWithKeyValues("t3", "t3_a")
+	t3.SetString(context.TODO(), "p3", "gold")
+	t3.SetDouble(context.TODO(), "p2", 1.5)
+	rs.Assert(context.TODO(), t3)
+
+	if fromAction == nil || len(fromTxn) != 1 || fromTxn[0].RtcID != fromAction.RtcID || fromAction.RtcID == 0 {
+		t.Fatalf("Expected the explanation in the action and the transaction, got %v %v\n", fromAction, fromTxn)
+	}
+	e := fromAction
+	if e.Rule != "approve" || e.Tuples["t1"] != t1.GetKey().String() || e.Tuples["t3"] != t3.GetKey().String() {
+		t.Errorf("Unexpected rule or tuples %v\n", e)
+	}
+	nodes := map[string]model.ExplanationNode{}
+	conditions := map[string]model.ConditionExplanation{}
+	for _, n := range e.Path {
+		nodes[n.ID] = n
+		for _, c := range n.Conditions {
+			conditions[c.Name] = c
+		}
+	}
+	if e.Path[0].ID != "class:t1" || e.Path[1].ID != "class:t3" || e.Path[len(e.Path)-1].Type != rete.RuleGraphNode {
+		t.Errorf("Expected the class nodes first and the rule node last, got %v\n", e.Path)
+	}
+	//c1 and c3 filter the tuples of each type, the join of c2 takes them as its inputs
+	var join model.ExplanationNode
+	for _, n := range e.Path {
+		if n.Type == rete.JoinGraphNode {
+			join = n
+		}
+	}
+	if len(join.Inputs) != 2 || nodes[join.Inputs[0]].Type != rete.FilterGraphNode || nodes[join.Inputs[1]].Type != rete.FilterGraphNode ||
+		len(join.Conditions) != 1 || join.Conditions[0].Name != "c2" {
+		t.Errorf("Expected the join of c2 on two filters, got %v\n", join)
+	}
+	if conditions["c1"].Values["t1"]["p1"] != 5 || len(conditions["c1"].Values["t1"]) != 1 {
+		t.Errorf("Expected c1 read p1, got %v\n", conditions["c1"])
+	}
+	if conditions["c2"].Values["t1"]["p3"] != "gold" || conditions["c2"].Values["t3"]["p3"] != "gold" {
+		t.Errorf("Expected c2 read p3 of both, got %v\n", conditions["c2"])
+	}
+	//a function condition reads the properties the rule depends on
+	if conditions["c3"].Values["t3"]["p2"] != 1.5 {
+		t.Errorf("Expected c3 read p2, got %v\n", conditions["c3"])
+	}
+
+	//nothing is recorded unless asked for
+	rs.SetExplain(false)
+	fromAction, fromTxn = nil, nil
+	t1b, _ := model.NewTupleWithKeyValues("t1", "t1_b")
+	t1b.SetInt(context.TODO(), "p1", 5)
+	t1b.SetString(context.TODO(), "p3", "gold")
+	rs.Assert(context.TODO(), t1b)
+	if fromAction != nil || len(fromTxn) != 0 {
+		t.Errorf("Expected no explanation, got %v %v\n", fromAction, fromTxn)
+	}
+	rs.Unregister()
+}
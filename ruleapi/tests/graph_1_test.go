@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/rete"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//The graph of a rule joining t1 and t3, with the rows of its join tables
+func Test_Graph_1(t *testing.T) {
+
+	rs, _ := createRuleSession()
+	r := ruleapi.NewRule("r1")
+	r.AddExprCondition("c1", "$.t1.p1 > 0", nil)
+	r.AddExprCondition("c2", "$.t1.p3 == $.t3.p3", nil)
+	r.SetAction(emptyAction)
+	rs.AddRule(r)
+	//an action reads the graph of its session, locked as it runs
+	var actionGraph rete.Graph
+	r = ruleapi.NewRule("r2")
+	r.AddExprCondition("c3", "$.t3.p1 == 1", nil)
+	r.SetAction(func(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+		actionGraph, _ = ruleapi.GetNetworkGraph(ctx, rs, true)
+	})
+	rs.AddRule(r)
+	rs.Start(nil)
+
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1")
+	t1.SetInt(context.TODO(), "p1", 1)
+	rs.Assert(context.TODO(), t1)
+	for _, id := range []string{"t3_a", "t3_b"} {
+		t3, _ := model.NewTupleWithKeyValues("t3", id)
+		rs.Assert(context.TODO(), t3)
+	}
+
+	g, err := ruleapi.GetNetworkGraph(context.TODO(), rs, true)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	nodes := map[string]rete.GraphNode{}
+	var join rete.GraphNode
+	for _, n := range g.Nodes {
+		nodes[n.ID] = n
+		if n.Type == rete.JoinGraphNode {
+			join = n
+		}
+	}
+	if nodes["class:t1"].Type != rete.ClassGraphNode || nodes["class:t3"].Type != rete.ClassGraphNode {
+		t.Errorf("Expected class nodes of t1 and t3, got %v\n", g.Nodes)
+	}
+	if len(join.LeftIdentifiers) != 1 || join.LeftIdentifiers[0] != "t1" || len(join.RightIdentifiers) != 1 ||
+		join.RightIdentifiers[0] != "t3" || len(join.Conditions) != 1 || !strings.Contains(join.Conditions[0], "$.t1.p3 == $.t3.p3") {
+		t.Errorf("Expected a join of t1 and t3 on c2, got %v\n", join)
+	}
+	if join.LeftRows == nil || *join.LeftRows != 1 || join.RightRows == nil || *join.RightRows != 2 {
+		t.Errorf("Expected 1 and 2 rows, got %v\n", join)
+	}
+	//class t1 -> filter -> join -> rule
+	links := map[string]string{}
+	for _, l := range g.Links {
+		links[l.From] = l.To
+	}
+	filter := nodes[links["class:t1"]]
+	if filter.Type != rete.FilterGraphNode || links[filter.ID] != join.ID || nodes[links[join.ID]].Type != rete.RuleGraphNode {
+		t.Errorf("Expected class t1 linked to a filter, the join and the rule, got %v\n", g.Links)
+	}
+
+	buf := bytes.Buffer{}
+	g.WriteJSON(&buf)
+	parsed := rete.Graph{}
+	if err = json.Unmarshal(buf.Bytes(), &parsed); err != nil || len(parsed.Nodes) != len(g.Nodes) || len(parsed.Links) != len(g.Links) {
+		t.Errorf("Expected the graph back from its JSON, got %v\n", err)
+	}
+	buf.Reset()
+	g.WriteDOT(&buf)
+	dot := buf.String()
+	if !strings.HasPrefix(dot, "digraph rete {") || !strings.Contains(dot, `"class:t1" -> "`+filter.ID+`" [label="t1"]`) {
+		t.Errorf("Unexpected DOT\n%s", dot)
+	}
+
+	t3, _ := model.NewTupleWithKeyValues("t3", "t3_c")
+	t3.SetInt(context.TODO(), "p1", 1)
+	rs.Assert(context.TODO(), t3)
+	if len(actionGraph.Nodes) != len(g.Nodes) {
+		t.Errorf("Expected the action to get the graph, got %v\n", actionGraph.Nodes)
+	}
+
+	//no rows unless asked for
+	g, _ = ruleapi.GetNetworkGraph(context.TODO(), rs, false)
+	for _, n := range g.Nodes {
+		if n.LeftRows != nil || n.RightRows != nil {
+			t.Errorf("Expected no rows, got %v\n", n)
+		}
+	}
+	rs.Unregister()
+}
@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//The events of an action modifying its tuple, cancelling another activation, then of a retract and an expiry
+func Test_Listener_1(t *testing.T) {
+
+	rs, _ := createRuleSession()
+	clock := ruleapi.NewPseudoClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	rs.SetClock(clock)
+	r := ruleapi.NewRule("r1")
+	r.AddExprCondition("c1", "$.t1.p1 == 0", nil)
+	r.SetPriority(1)
+	r.SetAction(func(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+		tuples["t1"].(model.MutableTuple).SetInt(ctx, "p1", 1)
+	})
+	rs.AddRule(r)
+	r = ruleapi.NewRule("r2")
+	r.AddExprCondition("c1", "$.t1.p1 == 0", nil)
+	r.SetPriority(2)
+	r.SetAction(emptyAction)
+	rs.AddRule(r)
+	//t8 tuples expire once a rule uses them
+	r = ruleapi.NewRule("r3")
+	r.AddCondition("c1", []string{"t8"}, trueCondition, nil)
+	r.SetAction(emptyAction)
+	rs.AddRule(r)
+	l := &recordingListener{}
+	rs.AddListener(l)
+	rs.Start(nil)
+
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	t1.SetInt(context.TODO(), "p1", 0)
+	rs.Assert(context.TODO(), t1)
+	expectEvents(t, l, "rtc start", "asserted t1_a", "created r1 t1_a", "created r2 t1_a", "before r1 t1_a",
+		"modified t1_a [p1] by r1", "cancelled r2 t1_a", "after r1 t1_a", "rtc end")
+
+	rs.Retract(context.TODO(), t1)
+	expectEvents(t, l, "rtc start", "retracted t1_a", "rtc end")
+
+	t8, _ := model.NewTupleWithKeyValues("t8", "t8_a")
+	rs.Assert(context.TODO(), t8)
+	expectEvents(t, l, "rtc start", "asserted t8_a", "created r3 t8_a", "before r3 t8_a", "after r3 t8_a", "rtc end")
+	clock.Advance(time.Second)
+	if len(l.events) < 4 || l.events[1] != "expired t8_a" || l.events[2] != "deleted t8_a" {
+		t.Errorf("Expected t8_a expired then deleted, got %v\n", l.events)
+	}
+
+	//the RTC ids tell the RTCs apart
+	if len(l.rtcIDs) != 8 || l.rtcIDs[0] != l.rtcIDs[1] || l.rtcIDs[1] == l.rtcIDs[2] {
+		t.Errorf("Expected an id per RTC, got %v\n", l.rtcIDs)
+	}
+	rs.RemoveListener(l)
+	l.events = nil
+	rs.Delete(context.TODO(), t8)
+	if len(l.events) != 0 {
+		t.Errorf("Expected no events once removed, got %v\n", l.events)
+	}
+	rs.Unregister()
+}
+
+func expectEvents(t *testing.T, l *recordingListener, expected ...string) {
+	t.Helper()
+	if !reflect.DeepEqual(l.events, expected) {
+		t.Errorf("Expected events %v\ngot %v\n", expected, l.events)
+	}
+	l.events = nil
+}
+
+//recordingListener records the events as strings, and the ids of the RTCs starting and ending
+type recordingListener struct {
+	model.BaseRuleSessionListener
+	events []string
+	rtcIDs []int64
+}
+
+func (l *recordingListener) OnRtcStart(ctx context.Context, rs model.RuleSession, event model.RtcEvent) {
+	l.events = append(l.events, "rtc start")
+	l.rtcIDs = append(l.rtcIDs, event.RtcID)
+}
+
+func (l *recordingListener) OnRtcEnd(ctx context.Context, rs model.RuleSession, event model.RtcEvent) {
+	l.events = append(l.events, "rtc end")
+	l.rtcIDs = append(l.rtcIDs, event.RtcID)
+}
+
+func (l *recordingListener) OnTupleAsserted(ctx context.Context, rs model.RuleSession, event model.TupleEvent) {
+	l.events = append(l.events, "asserted "+tupleID(event.Tuple))
+}
+
+func (l *recordingListener) OnTupleRetracted(ctx context.Context, rs model.RuleSession, event model.TupleEvent) {
+	if event.Deleted {
+		l.events = append(l.events, "deleted "+tupleID(event.Tuple))
+	} else {
+		l.events = append(l.events, "retracted "+tupleID(event.Tuple))
+	}
+}
+
+func (l *recordingListener) OnTupleModified(ctx context.Context, rs model.RuleSession, event model.TupleEvent) {
+	props := []string{}
+	for prop := range event.ModifiedProps {
+		props = append(props, prop)
+	}
+	l.events = append(l.events, fmt.Sprintf("modified %s %v by %s", tupleID(event.Tuple), props, event.RuleName))
+}
+
+func (l *recordingListener) OnTupleExpired(ctx context.Context, rs model.RuleSession, event model.TupleEvent) {
+	l.events = append(l.events, "expired "+tupleID(event.Tuple))
+}
+
+func (l *recordingListener) OnActivationCreated(ctx context.Context, rs model.RuleSession, event model.ActivationEvent) {
+	l.events = append(l.events, "created "+activationString(event))
+}
+
+func (l *recordingListener) OnActivationCancelled(ctx context.Context, rs model.RuleSession, event model.ActivationEvent) {
+	l.events = append(l.events, "cancelled "+activationString(event))
+}
+
+func (l *recordingListener) OnBeforeAction(ctx context.Context, rs model.RuleSession, event model.ActivationEvent) {
+	l.events = append(l.events, "before "+activationString(event))
+}
+
+func (l *recordingListener) OnAfterAction(ctx context.Context, rs model.RuleSession, event model.ActivationEvent) {
+	l.events = append(l.events, "after "+activationString(event))
+}
+
+func activationString(event model.ActivationEvent) string {
+	keys := []string{}
+	for _, tuple := range event.Tuples {
+		keys = append(keys, tupleID(tuple))
+	}
+	return event.RuleName + " " + strings.Join(keys, ",")
+}
+
+func tupleID(tuple model.Tuple) string {
+	id, _ := tuple.GetString("id")
+	return id
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//A live query of the delayed packages t1, by state p3, as actions change the states and packages are retracted
+func Test_LiveQuery_1(t *testing.T) {
+
+	rs, _ := createRuleSession()
+	//the action of a command t3 sets the state of the package t3.p3 to t3.p1, or its property p2 if p1 is 0
+	r := ruleapi.NewRule("command")
+	r.AddExprCondition("c1", "$.t1.id == $.t3.p3", nil)
+	r.SetAction(func(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+		t1 := tuples["t1"].(model.MutableTuple)
+		switch state, _ := tuples["t3"].GetInt("p1"); state {
+		case 0:
+			t1.SetDouble(ctx, "p2", 1.5)
+		case 1:
+			t1.SetString(ctx, "p3", "delayed")
+		default:
+			t1.SetString(ctx, "p3", "ontime")
+		}
+	})
+	rs.AddRule(r)
+	rs.Start(nil)
+
+	assertPackage := func(id string, state string) model.Tuple {
+		t1, _ := model.NewTupleWithKeyValues("t1", id)
+		t1.SetString(context.TODO(), "p3", state)
+		rs.Assert(context.TODO(), t1)
+		return t1
+	}
+	command := func(id string, pkg string, state int) {
+		t3, _ := model.NewTupleWithKeyValues("t3", id)
+		t3.SetInt(context.TODO(), "p1", state)
+		t3.SetString(context.TODO(), "p3", pkg)
+		rs.Assert(context.TODO(), t3)
+	}
+
+	assertPackage("t1_a", "delayed")
+	q := ruleapi.NewQuery("delayed")
+	q.AddExprCondition("c2", "$.t1.p3 == $param[state]", nil)
+	l := &liveQueryListener{}
+	if err := rs.AddLiveQuery(context.TODO(), q, map[string]interface{}{"state": "delayed"}, l); err != nil {
+		t.Fatalf("%s", err)
+	}
+	expectResults(t, l, "added t1_a")
+	if err := rs.AddLiveQuery(context.TODO(), q, nil, l); err == nil {
+		t.Errorf("Expected an error adding a live query twice\n")
+	}
+
+	t1b := assertPackage("t1_b", "ontime")
+	expectResults(t, l)
+	command("t3_a", "t1_b", 1)
+	expectResults(t, l, "added t1_b")
+	command("t3_b", "t1_a", 0)
+	expectResults(t, l, "updated t1_a")
+	command("t3_c", "t1_a", 2)
+	expectResults(t, l, "removed t1_a")
+	rs.Retract(context.TODO(), t1b)
+	expectResults(t, l, "removed t1_b")
+
+	g, _ := ruleapi.GetNetworkGraph(context.TODO(), rs, false)
+	found := false
+	for _, n := range g.Nodes {
+		found = found || (n.Type == "query" && n.Name == "delayed")
+	}
+	if !found {
+		t.Errorf("Expected the query node in the graph, got %v\n", g.Nodes)
+	}
+
+	rs.RemoveLiveQuery("delayed")
+	assertPackage("t1_c", "delayed")
+	expectResults(t, l)
+	rs.Unregister()
+}
+
+func expectResults(t *testing.T, l *liveQueryListener, expected ...string) {
+	t.Helper()
+	if !reflect.DeepEqual(l.events, expected) {
+		t.Errorf("Expected results %v\ngot %v\n", expected, l.events)
+	}
+	l.events = nil
+}
+
+//liveQueryListener records the changes of the results of a live query over t1 as strings
+type liveQueryListener struct {
+	events []string
+}
+
+func (l *liveQueryListener) OnResultAdded(ctx context.Context, rs model.RuleSession, event model.LiveQueryEvent) {
+	l.events = append(l.events, "added "+tupleID(event.Result["t1"]))
+}
+
+func (l *liveQueryListener) OnResultUpdated(ctx context.Context, rs model.RuleSession, event model.LiveQueryEvent) {
+	l.events = append(l.events, "updated "+tupleID(event.Result["t1"]))
+}
+
+func (l *liveQueryListener) OnResultRemoved(ctx context.Context, rs model.RuleSession, event model.LiveQueryEvent) {
+	l.events = append(l.events, "removed "+tupleID(event.Result["t1"]))
+}
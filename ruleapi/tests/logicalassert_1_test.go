@@ -0,0 +1,260 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//A tuple logically asserted by two activations is deleted once neither matches any more
+func Test_LogicalAssert_1(t *testing.T) {
+
+	deleted := map[string]bool{}
+	rs, _ := createRuleSession()
+	rs.RegisterRtcTransactionHandler(deletedHandler, deleted)
+
+	r1 := ruleapi.NewRule("positive")
+	r1.AddExprCondition("c1", "$.t1.p1 > 0", nil)
+	r1.SetAction(logicalAssertAction)
+	r1.SetContext("positive")
+	rs.AddRule(r1)
+
+	//t2 resets the p1 of the t1
+	r2 := ruleapi.NewRule("reset")
+	r2.AddCondition("c1", []string{"t1", "t2"}, trueCondition, nil)
+	r2.SetAction(resetP1Action)
+	rs.AddRule(r2)
+	addT3Rule(rs)
+	rs.Start(nil)
+
+	t1a, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	t1a.SetInt(context.TODO(), "p1", 1)
+	rs.Assert(context.TODO(), t1a)
+	t1b, _ := model.NewTupleWithKeyValues("t1", "t1_b")
+	t1b.SetInt(context.TODO(), "p1", 1)
+	rs.Assert(context.TODO(), t1b)
+	expectAsserted(t, rs, "positive", true)
+
+	rs.Retract(context.TODO(), t1a)
+	expectAsserted(t, rs, "positive", true)
+	if deleted["positive"] {
+		t.Errorf("Expected t3 [positive] not deleted yet")
+	}
+
+	t2, _ := model.NewTupleWithKeyValues("t2", "t2_a")
+	rs.Assert(context.TODO(), t2)
+	expectAsserted(t, rs, "positive", false)
+	if !deleted["positive"] {
+		t.Errorf("Expected t3 [positive] in the RTC delete set")
+	}
+
+	rs.Unregister()
+}
+
+//A tuple logically asserted for the t1 with the highest p1 follows it
+func Test_LogicalAssert_2(t *testing.T) {
+
+	deleted := map[string]bool{}
+	rs, _ := createRuleSession()
+	rs.RegisterRtcTransactionHandler(deletedHandler, deleted)
+
+	r1 := ruleapi.NewRule("highest")
+	r1.AddCondition("c1", []string{"o1:t1"}, trueCondition, nil)
+	g, _ := r1.AddNotGroup("g1", []string{"o2:t1"})
+	g.AddExprCondition("c2", "$.o2.p1 > $.o1.p1", nil)
+	r1.SetAction(logicalAssertAction)
+	rs.AddRule(r1)
+	addT3Rule(rs)
+	rs.Start(nil)
+
+	for i, id := range []string{"t1_a", "t1_b"} {
+		t1, _ := model.NewTupleWithKeyValues("t1", id)
+		t1.SetInt(context.TODO(), "p1", i+1)
+		rs.Assert(context.TODO(), t1)
+	}
+	expectAsserted(t, rs, "highest_t1_a", false)
+	expectAsserted(t, rs, "highest_t1_b", true)
+	if !deleted["highest_t1_a"] {
+		t.Errorf("Expected t3 [highest_t1_a] in the RTC delete set")
+	}
+
+	rs.Unregister()
+}
+
+//Logically asserting outside of an action, or a tuple already asserted
+func Test_LogicalAssert_3(t *testing.T) {
+
+	rs, _ := createRuleSession()
+	errs := map[string]error{}
+
+	r1 := ruleapi.NewRule("stated")
+	r1.AddCondition("c1", []string{"t1"}, trueCondition, nil)
+	r1.SetAction(logicalAssertErrAction)
+	r1.SetContext(errs)
+	rs.AddRule(r1)
+	addT3Rule(rs)
+	rs.Start(nil)
+
+	t3, _ := model.NewTupleWithKeyValues("t3", "stated")
+	if rs.LogicalAssert(context.TODO(), t3) == nil {
+		t.Errorf("Expected an error outside of an action")
+	}
+	rs.Assert(context.TODO(), t3)
+
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	rs.Assert(context.TODO(), t1)
+	if errs["stated"] == nil {
+		t.Errorf("Expected an error logically asserting a tuple already asserted")
+	}
+
+	rs.Unregister()
+}
+
+//A tuple logically asserted before a snapshot is deleted once its activation no longer matches after
+//a restore
+func Test_LogicalAssert_4(t *testing.T) {
+
+	addRules := func(rs model.RuleSession) {
+		r1 := ruleapi.NewRule("positive")
+		r1.AddExprCondition("c1", "$.t1.p1 > 0", nil)
+		r1.SetAction(logicalAssertAction)
+		r1.SetContext("positive")
+		rs.AddRule(r1)
+		addT3Rule(rs)
+	}
+	rs, _ := createRuleSession()
+	addRules(rs)
+	rs.Start(nil)
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	t1.SetInt(context.TODO(), "p1", 1)
+	rs.Assert(context.TODO(), t1)
+	expectAsserted(t, rs, "positive", true)
+	buf := bytes.Buffer{}
+	if err := rs.Snapshot(&buf); err != nil {
+		t.Fatalf("%s", err)
+	}
+	rs.Unregister()
+
+	deleted := map[string]bool{}
+	rs, _ = createRuleSession()
+	rs.RegisterRtcTransactionHandler(deletedHandler, deleted)
+	addRules(rs)
+	if err := rs.Restore(&buf, false); err != nil {
+		t.Fatalf("%s", err)
+	}
+	rs.Start(nil)
+	expectAsserted(t, rs, "positive", true)
+	rs.Retract(context.TODO(), rs.GetAssertedTuple(t1.GetKey()))
+	expectAsserted(t, rs, "positive", false)
+	if !deleted["positive"] {
+		t.Errorf("Expected t3 [positive] in the RTC delete set")
+	}
+	rs.Unregister()
+}
+
+//A tuple logically asserted by an activation still matching after its tuple is modified stays, also
+//when its rule is locked on active and does not fire again
+func Test_LogicalAssert_5(t *testing.T) {
+
+	for _, lockOnActive := range []bool{false, true} {
+		changes := []string{}
+		rs, _ := createRuleSession()
+		rs.RegisterRtcTransactionHandler(t3ChangesHandler, &changes)
+
+		r1 := ruleapi.NewRule("positive")
+		r1.AddExprCondition("c1", "$.t1.p1 > 0", nil)
+		r1.SetAction(logicalAssertAction)
+		r1.SetContext("positive")
+		r1.SetLockOnActive(lockOnActive)
+		rs.AddRule(r1)
+
+		//t2 bumps the p1 of the t1
+		r2 := ruleapi.NewRule("bump")
+		r2.AddCondition("c1", []string{"t1", "t2"}, trueCondition, nil)
+		r2.SetAction(bumpP1Action)
+		rs.AddRule(r2)
+		addT3Rule(rs)
+		rs.Start(nil)
+
+		t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+		t1.SetInt(context.TODO(), "p1", 1)
+		rs.Assert(context.TODO(), t1)
+		expectAsserted(t, rs, "positive", true)
+		changes = changes[:0]
+
+		t2, _ := model.NewTupleWithKeyValues("t2", "t2_a")
+		rs.Assert(context.TODO(), t2)
+		if p1, _ := t1.GetInt("p1"); p1 != 2 {
+			t.Errorf("Expected p1 bumped to 2, got %d\n", p1)
+		}
+		expectAsserted(t, rs, "positive", true)
+		if len(changes) > 0 {
+			t.Errorf("Lock on active [%t]: expected t3 [positive] unchanged, got %v\n", lockOnActive, changes)
+		}
+
+		rs.Unregister()
+	}
+}
+
+//tuples of a type no rule uses are not kept
+func addT3Rule(rs model.RuleSession) {
+	r := ruleapi.NewRule("t3Rule")
+	r.AddCondition("c1", []string{"t3"}, trueCondition, nil)
+	r.SetAction(emptyAction)
+	rs.AddRule(r)
+}
+
+func expectAsserted(t *testing.T, rs model.RuleSession, id string, asserted bool) {
+	t.Helper()
+	key, _ := model.NewTupleKeyWithKeyValues("t3", id)
+	if (rs.GetAssertedTuple(key) != nil) != asserted {
+		t.Errorf("t3 [%s]: expected asserted [%t]\n", id, asserted)
+	}
+}
+
+func t3ChangesHandler(ctx context.Context, rs model.RuleSession, rtxn model.RtcTxn, handlerCtx interface{}) {
+	changes := handlerCtx.(*[]string)
+	for _, tuple := range rtxn.GetRtcDeleted()["t3"] {
+		id, _ := tuple.GetString("id")
+		*changes = append(*changes, "deleted "+id)
+	}
+	for _, tuple := range rtxn.GetRtcAdded()["t3"] {
+		id, _ := tuple.GetString("id")
+		*changes = append(*changes, "added "+id)
+	}
+}
+
+func deletedHandler(ctx context.Context, rs model.RuleSession, rtxn model.RtcTxn, handlerCtx interface{}) {
+	deleted := handlerCtx.(map[string]bool)
+	for _, tuple := range rtxn.GetRtcDeleted()["t3"] {
+		id, _ := tuple.GetString("id")
+		deleted[id] = true
+	}
+}
+
+//logically asserts the t3 with the id in the rule context, else highest_ and the id of the o1
+func logicalAssertAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	id, _ := ruleCtx.(string)
+	if id == "" {
+		o1, _ := tuples["o1"].GetString("id")
+		id = "highest_" + o1
+	}
+	t3, _ := model.NewTupleWithKeyValues("t3", id)
+	rs.LogicalAssert(ctx, t3)
+}
+
+func logicalAssertErrAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	t3, _ := model.NewTupleWithKeyValues("t3", "stated")
+	ruleCtx.(map[string]error)[ruleName] = rs.LogicalAssert(ctx, t3)
+}
+
+func resetP1Action(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	tuples["t1"].(model.MutableTuple).SetInt(ctx, "p1", 0)
+}
+
+func bumpP1Action(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	tuples["t1"].(model.MutableTuple).SetInt(ctx, "p1", 2)
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/project-flogo/rules/common/metrics"
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/rete"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//The counters of a join and of an activation group, and the gauges of the working memory
+func Test_Metrics_1(t *testing.T) {
+
+	rs, _ := createRuleSession()
+	r := ruleapi.NewRule("join")
+	r.AddExprCondition("c1", "$.t1.p1 > 0", nil)
+	r.AddExprCondition("c2", "$.t1.p3 == $.t3.p3", nil)
+	r.SetAction(emptyAction)
+	rs.AddRule(r)
+	for _, name := range []string{"a", "b"} {
+		r = ruleapi.NewRule(name)
+		r.AddCondition("c", []string{"t1"}, trueCondition, nil)
+		r.SetActivationGroup("g")
+		r.SetAction(emptyAction)
+		rs.AddRule(r)
+	}
+	registry := metrics.NewRegistry()
+	if err := ruleapi.EnableMetrics(rs, registry); err != nil {
+		t.Fatalf("%s", err)
+	}
+	rs.Start(nil)
+
+	for i, id := range []string{"t1_a", "t1_b"} {
+		t1, _ := model.NewTupleWithKeyValues("t1", id)
+		t1.SetInt(context.TODO(), "p1", i)
+		rs.Assert(context.TODO(), t1)
+	}
+	t3, _ := model.NewTupleWithKeyValues("t3", "t3_a")
+	rs.Assert(context.TODO(), t3)
+
+	g, _ := ruleapi.GetNetworkGraph(context.TODO(), rs, false)
+	join := ""
+	for _, n := range g.Nodes {
+		if n.Type == rete.JoinGraphNode {
+			join = n.ID
+		}
+	}
+	values := gatherValues(registry)
+	expected := map[string]float64{
+		`rules_activations_created_total{session="test",rule="join"}`:                        1,
+		`rules_activations_fired_total{session="test",rule="join"}`:                          1,
+		`rules_activations_created_total{session="test",rule="a"}`:                           2,
+		`rules_activations_created_total{session="test",rule="b"}`:                           2,
+		`rules_condition_evaluations_total{session="test",rule="join",condition="c1"}`:       2,
+		`rules_condition_passes_total{session="test",rule="join",condition="c1"}`:            1,
+		`rules_condition_evaluations_total{session="test",rule="join",condition="c2"}`:       1,
+		`rules_rtc_duration_seconds_count{session="test"}`:                                   3,
+		`rules_asserted_tuples{session="test",type="t1"}`:                                    2,
+		`rules_asserted_tuples{session="test",type="t3"}`:                                    1,
+		`rules_join_table_rows{session="test",rule="join",node="` + join + `",side="left"}`:  1,
+		`rules_join_table_rows{session="test",rule="join",node="` + join + `",side="right"}`: 1,
+	}
+	for sample, value := range expected {
+		if values[sample] != value {
+			t.Errorf("%s: expected %v, got %v\n", sample, value, values[sample])
+		}
+	}
+	//one of the activation group fires per t1, the other is cancelled
+	for _, id := range []string{"fired", "cancelled"} {
+		sum := values[`rules_activations_`+id+`_total{session="test",rule="a"}`] + values[`rules_activations_`+id+`_total{session="test",rule="b"}`]
+		if sum != 2 {
+			t.Errorf("Expected 2 activations of the group %s, got %v\n", id, sum)
+		}
+	}
+	if values[`rules_action_duration_seconds_count{session="test",rule="join"}`] != 1 {
+		t.Errorf("Expected the action of join timed once, got %v\n", values)
+	}
+
+	buf := bytes.Buffer{}
+	registry.WritePrometheus(&buf)
+	text := buf.String()
+	if !strings.Contains(text, "# TYPE rules_action_duration_seconds histogram\n") ||
+		!strings.Contains(text, `rules_action_duration_seconds_bucket{session="test",rule="join",le="+Inf"} 1`) {
+		t.Errorf("Unexpected exposition\n%s", text)
+	}
+
+	//the session's gauges go with it
+	rs.Unregister()
+	values = gatherValues(registry)
+	if _, found := values[`rules_asserted_tuples{session="test",type="t1"}`]; found {
+		t.Errorf("Expected no gauges after unregister, got %v\n", values)
+	}
+}
+
+//gatherValues returns the values of the registry by sample, as written in the exposition
+func gatherValues(registry metrics.Registry) map[string]float64 {
+	values := map[string]float64{}
+	for _, f := range registry.Gather() {
+		for _, s := range f.Samples {
+			labels := []string{}
+			for _, l := range s.Labels {
+				labels = append(labels, l.Name+`="`+l.Value+`"`)
+			}
+			values[s.Name+"{"+strings.Join(labels, ",")+"}"] = s.Value
+		}
+	}
+	return values
+}
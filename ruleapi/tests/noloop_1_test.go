@@ -0,0 +1,174 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/config"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//A rule incrementing the p1 its condition depends on loops until the condition fails, unless no-loop
+func Test_NoLoop_1(t *testing.T) {
+
+	for noLoop, expected := range map[bool]int{false: 5, true: 1} {
+		fired := map[string]int{}
+		rs, _ := createRuleSession()
+
+		r1 := ruleapi.NewRule("increment")
+		r1.AddExprCondition("c1", "$.t1.p1 < 5", nil)
+		r1.SetNoLoop(noLoop)
+		r1.SetAction(incrementP1Action)
+		r1.SetContext(fired)
+		rs.AddRule(r1)
+		rs.Start(nil)
+
+		t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+		t1.SetInt(context.TODO(), "p1", 0)
+		rs.Assert(context.TODO(), t1)
+		if fired["increment"] != expected {
+			t.Errorf("noLoop %t: expected [%d], got [%d]\n", noLoop, expected, fired["increment"])
+		}
+		rs.Unregister()
+	}
+}
+
+//A lock-on-active rule is not activated again by another rule's action while its group has the focus
+func Test_NoLoop_2(t *testing.T) {
+
+	for lockOnActive, expected := range map[bool]int{false: 2, true: 1} {
+		fired := map[string]int{}
+		rs, _ := createRuleSession()
+
+		r1 := ruleapi.NewRule("locked")
+		r1.AddExprCondition("c1", "$.t1.p2 >= 0", nil)
+		r1.SetLockOnActive(lockOnActive)
+		r1.SetPriority(1)
+		r1.SetAction(countAction)
+		r1.SetContext(fired)
+		rs.AddRule(r1)
+
+		r2 := ruleapi.NewRule("setP2")
+		r2.AddExprCondition("c1", "$.t1.p1 == 0", nil)
+		r2.SetPriority(2)
+		r2.SetAction(setP2Action)
+		r2.SetContext(fired)
+		rs.AddRule(r2)
+		rs.Start(nil)
+
+		t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+		t1.SetInt(context.TODO(), "p1", 0)
+		t1.SetDouble(context.TODO(), "p2", 0.0)
+		rs.Assert(context.TODO(), t1)
+		if fired["locked"] != expected {
+			t.Errorf("lockOnActive %t: expected [%d], got [%d]\n", lockOnActive, expected, fired["locked"])
+		}
+		rs.Unregister()
+	}
+}
+
+//With refraction, a property changed back to the value it had does not fire a rule again
+func Test_NoLoop_3(t *testing.T) {
+
+	for _, tc := range []struct {
+		refraction bool
+		p2         float64
+		expected   int
+	}{
+		{false, 0.0, 2},
+		{true, 0.0, 1},
+		{true, 1.0, 2},
+	} {
+		fired := map[string]int{}
+		rs, _ := createRuleSession()
+		rs.SetRefraction(tc.refraction)
+
+		r1 := ruleapi.NewRule("locked")
+		r1.AddExprCondition("c1", "$.t1.p2 >= 0", nil)
+		r1.SetPriority(1)
+		r1.SetAction(countAction)
+		r1.SetContext(fired)
+		rs.AddRule(r1)
+
+		r2 := ruleapi.NewRule("setP2")
+		r2.AddExprCondition("c1", "$.t1.p1 == 0", nil)
+		r2.SetPriority(2)
+		r2.SetAction(setP2Action)
+		r2.SetContext(fired)
+		rs.AddRule(r2)
+		rs.Start(nil)
+
+		t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+		t1.SetInt(context.TODO(), "p1", 0)
+		t1.SetDouble(context.TODO(), "p2", 0.0)
+		fired["p2"] = int(tc.p2)
+		rs.Assert(context.TODO(), t1)
+		if fired["locked"] != tc.expected {
+			t.Errorf("refraction %t, p2 %f: expected [%d], got [%d]\n", tc.refraction, tc.p2, tc.expected, fired["locked"])
+		}
+		rs.Unregister()
+	}
+}
+
+//No-loop and refraction from the JSON config
+func Test_NoLoop_4(t *testing.T) {
+
+	noLoopFired = map[string]int{}
+	config.RegisterActionFunction("noLoopAction", noLoopAction)
+	createRuleSession()
+
+	rs, err := ruleapi.GetOrCreateRuleSessionFromConfig("test", `{
+		"refraction": true,
+		"rules": [{
+			"name": "increment",
+			"conditions": [{"name": "c1", "expression": "$.t1.p1 < 5"}],
+			"noLoop": true,
+			"lockOnActive": true,
+			"actionFunction": "noLoopAction"
+		}]
+	}`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !rs.GetRefraction() {
+		t.Errorf("Expected refraction")
+	}
+	rule := rs.GetRules()[0]
+	if !rule.GetNoLoop() || !rule.GetLockOnActive() {
+		t.Errorf("Expected no-loop and lock-on-active")
+	}
+	rs.Start(nil)
+
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	t1.SetInt(context.TODO(), "p1", 0)
+	rs.Assert(context.TODO(), t1)
+	if noLoopFired["increment"] != 1 {
+		t.Errorf("Expected [%d], got [%d]\n", 1, noLoopFired["increment"])
+	}
+	rs.Unregister()
+}
+
+func countAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	ruleCtx.(map[string]int)[ruleName]++
+}
+
+func incrementP1Action(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	countAction(ctx, rs, ruleName, tuples, ruleCtx)
+	t1 := tuples["t1"].(model.MutableTuple)
+	p1, _ := t1.GetInt("p1")
+	t1.SetInt(ctx, "p1", p1+1)
+}
+
+//changes p2, then sets it to the value in the rule context
+func setP2Action(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	t1 := tuples["t1"].(model.MutableTuple)
+	t1.SetDouble(ctx, "p2", -1.0)
+	t1.SetDouble(ctx, "p2", float64(ruleCtx.(map[string]int)["p2"]))
+}
+
+var noLoopFired map[string]int
+
+func noLoopAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	incrementP1Action(ctx, rs, ruleName, tuples, noLoopFired)
+}
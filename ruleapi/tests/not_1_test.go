@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//A not group fires for a t1 only while no t3 with the same p1 is asserted
+func Test_Not_1(t *testing.T) {
+
+	fired := map[string]int{}
+	rs, _ := createRuleSession()
+
+	r1 := ruleapi.NewRule("noT3")
+	r1.AddCondition("c1", []string{"t1"}, trueCondition, nil)
+	g, err := r1.AddNotGroup("g1", []string{"t3"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = g.AddExprCondition("c2", "$.t1.p1 == $.t3.p1", nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r1.SetAction(notAction)
+	r1.SetContext(fired)
+	err = rs.AddRule(r1)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	rs.Start(nil)
+
+	t3, _ := model.NewTupleWithKeyValues("t3", "t3_a")
+	t3.SetInt(context.TODO(), "p1", 1)
+	rs.Assert(context.TODO(), t3)
+
+	t1a, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	t1a.SetInt(context.TODO(), "p1", 1)
+	rs.Assert(context.TODO(), t1a)
+	if fired["t1_a"] != 0 {
+		t.Errorf("t1_a: expected [%d], got [%d]\n", 0, fired["t1_a"])
+	}
+
+	t1b, _ := model.NewTupleWithKeyValues("t1", "t1_b")
+	t1b.SetInt(context.TODO(), "p1", 2)
+	rs.Assert(context.TODO(), t1b)
+	if fired["t1_b"] != 1 {
+		t.Errorf("t1_b: expected [%d], got [%d]\n", 1, fired["t1_b"])
+	}
+
+	//retracting the only matching t3 lets t1_a through
+	rs.Retract(context.TODO(), t3)
+	if fired["t1_a"] != 1 {
+		t.Errorf("t1_a: expected [%d], got [%d]\n", 1, fired["t1_a"])
+	}
+	if fired["t1_b"] != 1 {
+		t.Errorf("t1_b: expected [%d], got [%d]\n", 1, fired["t1_b"])
+	}
+
+	rs.Unregister()
+}
+
+//An activation is withdrawn when a matching t3 is asserted in the same RTC, before the rule fires
+func Test_Not_2(t *testing.T) {
+
+	fired := map[string]int{}
+	rs, _ := createRuleSession()
+
+	r1 := ruleapi.NewRule("assertT3")
+	r1.AddCondition("c1", []string{"t2"}, trueCondition, nil)
+	r1.SetAction(assertT3Action)
+	r1.SetPriority(1)
+	rs.AddRule(r1)
+
+	r2 := ruleapi.NewRule("noT3")
+	r2.AddCondition("c1", []string{"t2"}, trueCondition, nil)
+	g, _ := r2.AddNotGroup("", []string{"t3"})
+	g.AddEquiJoinCondition("c2", "t2.p1", "t3.p1", nil)
+	r2.SetAction(notAction)
+	r2.SetContext(fired)
+	r2.SetPriority(2)
+	rs.AddRule(r2)
+
+	rs.Start(nil)
+
+	t2, _ := model.NewTupleWithKeyValues("t2", "t2_a")
+	t2.SetInt(context.TODO(), "p1", 1)
+	rs.Assert(context.TODO(), t2)
+
+	if fired["t2_a"] != 0 {
+		t.Errorf("t2_a: expected [%d], got [%d]\n", 0, fired["t2_a"])
+	}
+
+	rs.Unregister()
+}
+
+func Test_Not_3(t *testing.T) {
+
+	r1 := ruleapi.NewRule("badNot")
+	r1.AddCondition("c1", []string{"t1"}, trueCondition, nil)
+	r1.AddNotGroup("g1", []string{"t1"})
+
+	rs, _ := createRuleSession()
+	if rs.AddRule(r1) == nil {
+		t.Errorf("Expected an error for a group identifier also in the rule")
+	}
+	rs.Unregister()
+
+	r2 := ruleapi.NewRule("badNot")
+	if _, err := r2.AddNotGroup("g1", []string{}); err == nil {
+		t.Errorf("Expected an error for a group without identifiers")
+	}
+}
+
+func notAction(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	fired := ruleCtx.(map[string]int)
+	for _, tuple := range tuples {
+		id, _ := tuple.GetString("id")
+		fired[id]++
+	}
+}
+
+func assertT3Action(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+	t3, _ := model.NewTupleWithKeyValues("t3", "t3_a")
+	p1, _ := tuples["t2"].GetInt("p1")
+	t3.SetInt(ctx, "p1", p1)
+	rs.Assert(ctx, t3)
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//Queries finding orders t1, by status p3 and amount p1, and joining them to their customer t3
+func Test_Query_1(t *testing.T) {
+
+	rs, _ := createRuleSession()
+	q := ruleapi.NewQuery("orders")
+	q.AddExprCondition("c1", "$.t1.p3 == $param[status] && $.t1.p1 > $param[minAmount]", nil)
+	if err := rs.AddQuery(q); err != nil {
+		t.Fatalf("%s", err)
+	}
+	q = ruleapi.NewQuery("customerOrders")
+	q.AddCondition("c2", []string{"t3.p3"}, func(condName string, ruleNm string, tuples map[model.TupleType]model.Tuple, ctx model.RuleContext) bool {
+		customer, _ := tuples["t3"].GetString("p3")
+		return customer == model.GetQueryParams(ctx)["customer"]
+	}, nil)
+	q.AddExprCondition("c3", "$.t1.id == $.t3.p3", nil)
+	rs.AddQuery(q)
+	q = ruleapi.NewQuery("all")
+	q.AddIdentifier("t1")
+	rs.AddQuery(q)
+	if err := rs.AddQuery(q); err == nil {
+		t.Errorf("Expected an error adding a query twice\n")
+	}
+
+	//an action finds the orders the rule's t3 refers to
+	found := 0
+	r := ruleapi.NewRule("r1")
+	r.AddExprCondition("c4", "$.t3.p1 == 1", nil)
+	r.SetAction(func(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
+		customer, _ := tuples["t3"].GetString("p3")
+		results, err := rs.RunQuery(ctx, "customerOrders", map[string]interface{}{"customer": customer})
+		if err != nil {
+			t.Errorf("%s", err)
+		}
+		found = len(results)
+	})
+	rs.AddRule(r)
+	rs.Start(nil)
+
+	assertOrder := func(id string, amount int, status string) {
+		t1, _ := model.NewTupleWithKeyValues("t1", id)
+		t1.SetInt(context.TODO(), "p1", amount)
+		t1.SetString(context.TODO(), "p3", status)
+		rs.Assert(context.TODO(), t1)
+	}
+	assertOrder("t1_a", 50, "pending")
+	assertOrder("t1_b", 150, "pending")
+	assertOrder("t1_c", 250, "pending")
+	assertOrder("t1_d", 500, "shipped")
+
+	results, err := rs.RunQuery(context.TODO(), "orders", map[string]interface{}{"status": "pending", "minAmount": 100})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(results) != 2 || results[0]["t1"].GetKey().String() != "t1:id:t1_b" || results[1]["t1"].GetKey().String() != "t1:id:t1_c" {
+		t.Errorf("Expected t1_b and t1_c, got %v\n", results)
+	}
+	if results, _ = rs.RunQuery(context.TODO(), "all", nil); len(results) != 4 {
+		t.Errorf("Expected all four orders, got %d\n", len(results))
+	}
+	if _, err = rs.RunQuery(context.TODO(), "orders", nil); err == nil {
+		t.Errorf("Expected an error for missing parameters\n")
+	}
+	if _, err = rs.RunQuery(context.TODO(), "unknown", nil); err == nil {
+		t.Errorf("Expected an error for an unknown query\n")
+	}
+
+	t3, _ := model.NewTupleWithKeyValues("t3", "t3_a")
+	t3.SetInt(context.TODO(), "p1", 1)
+	t3.SetString(context.TODO(), "p3", "t1_d")
+	rs.Assert(context.TODO(), t3)
+	if found != 1 {
+		t.Errorf("Expected the action to find 1 order, got %d\n", found)
+	}
+
+	rs.DeleteQuery("all")
+	if len(rs.GetQueries()) != 2 {
+		t.Errorf("Expected 2 queries, got %d\n", len(rs.GetQueries()))
+	}
+	rs.Unregister()
+}
@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -9,7 +10,8 @@ import (
 	"github.com/project-flogo/rules/ruleapi"
 )
 
-var actionCnt int
+//the action runs on the timer of the scheduled assert
+var actionCnt int32
 
 //1 rtc->Scheduled assert, Action should be fired after the delay time.
 func Test_T15(t *testing.T) {
@@ -28,14 +30,14 @@ func Test_T15(t *testing.T) {
 	t1, _ := model.NewTupleWithKeyValues("t1", "t10")
 	rs.ScheduleAssert(context.TODO(), 1000, "1", t1)
 
-	if actionCnt != 0 {
-		t.Errorf("Expecting [0] actions, got [%d]", actionCnt)
+	if cnt := atomic.LoadInt32(&actionCnt); cnt != 0 {
+		t.Errorf("Expecting [0] actions, got [%d]", cnt)
 		t.FailNow()
 	}
 	time.Sleep(2000 * time.Millisecond)
 
-	if actionCnt != 1 {
-		t.Errorf("Expecting [1] actions, got [%d]", actionCnt)
+	if cnt := atomic.LoadInt32(&actionCnt); cnt != 1 {
+		t.Errorf("Expecting [1] actions, got [%d]", cnt)
 		t.FailNow()
 	}
 
@@ -44,5 +46,5 @@ func Test_T15(t *testing.T) {
 }
 
 func r15_action(ctx context.Context, rs model.RuleSession, ruleName string, tuples map[model.TupleType]model.Tuple, ruleCtx model.RuleContext) {
-	actionCnt++
+	atomic.AddInt32(&actionCnt, 1)
 }
@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//Jobs assert, modify and retract t1_a in turn, a cron job asserts a t8 every 5 minutes
+func Test_Scheduler_1(t *testing.T) {
+
+	fired := map[string]int{}
+	rs, _ := createRuleSession()
+	start := time.Date(2020, 1, 1, 0, 3, 0, 0, time.UTC)
+	clock := ruleapi.NewPseudoClock(start)
+	rs.SetClock(clock)
+	r1 := ruleapi.NewRule("p1is5")
+	err := r1.AddExprCondition("c1", `$.t1.p1 == 5`, nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r1.SetAction(countAction)
+	r1.SetContext(fired)
+	rs.AddRule(r1)
+	r2 := ruleapi.NewRule("t8")
+	r2.AddCondition("c1", []string{"t8"}, trueCondition, nil)
+	r2.SetAction(countAction)
+	r2.SetContext(fired)
+	rs.AddRule(r2)
+	rs.Start(nil)
+
+	for _, job := range []model.Job{
+		{Key: "retract", Operation: model.RetractJob, Values: map[string]interface{}{"id": "t1_a"}, At: start.Add(3 * time.Minute)},
+		{Key: "modify", Operation: model.ModifyJob, Values: map[string]interface{}{"id": "t1_a", "p1": 5}, At: start.Add(2 * time.Minute)},
+		{Key: "assert", Operation: model.AssertJob, Values: map[string]interface{}{"id": "t1_a", "p1": 1}, At: start.Add(time.Minute)},
+	} {
+		job.TupleType = "t1"
+		if err := rs.ScheduleJob(context.TODO(), job); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	err = rs.ScheduleJob(context.TODO(), model.Job{Key: "cron", Operation: model.AssertJob, TupleType: "t8",
+		Values: map[string]interface{}{"id": "t8_a"}, Cron: "*/5 * * * *"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	//the cron job runs first at 00:05
+	expectJobs(t, rs, "assert", "cron", "modify", "retract")
+
+	key, _ := model.NewTupleKeyWithKeyValues("t1", "t1_a")
+	clock.Advance(time.Minute)
+	if rs.GetAssertedTuple(key) == nil {
+		t.Errorf("Expected t1_a asserted")
+	}
+	clock.Advance(time.Minute)
+	if fired["p1is5"] != 1 || fired["t8"] != 1 {
+		t.Errorf("Expected t1_a modified and a t8 asserted, got %v\n", fired)
+	}
+	clock.Advance(time.Minute)
+	if rs.GetAssertedTuple(key) != nil {
+		t.Errorf("Expected t1_a retracted")
+	}
+
+	//the t8 expires within a second, the next are asserted at 00:10 and 00:15
+	clock.Advance(9 * time.Minute)
+	if fired["t8"] != 3 {
+		t.Errorf("Expected [%d], got [%d]\n", 3, fired["t8"])
+	}
+	jobs := rs.GetScheduledJobs()
+	if len(jobs) != 1 || !jobs[0].At.Equal(start.Add(17*time.Minute)) {
+		t.Errorf("Expected the cron job at 00:20, got %v\n", jobs)
+	}
+	rs.CancelScheduledJob(context.TODO(), "cron")
+	expectJobs(t, rs)
+
+	if rs.ScheduleJob(context.TODO(), model.Job{Key: "bad", Operation: model.AssertJob, TupleType: "t8",
+		Values: map[string]interface{}{"id": "t8_b"}, Cron: "61 * * * *"}) == nil {
+		t.Errorf("Expected an error for an invalid cron")
+	}
+
+	rs.Unregister()
+}
+
+//Jobs in a file store survive the session
+func Test_Scheduler_2(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "jobs")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jobs.json")
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rs, _ := createRuleSession()
+	rs.SetClock(ruleapi.NewPseudoClock(start))
+	store, err := ruleapi.NewFileJobStore(path)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	rs.SetJobStore(store)
+	addWindowRules(rs, "t1")
+	rs.Start(nil)
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_b")
+	t1.SetInt(context.TODO(), "p1", 3)
+	rs.ScheduleAssert(context.TODO(), 60000, "t1_b", t1)
+	rs.ScheduleAssert(context.TODO(), 60000, "t1_c", t1)
+	rs.CancelScheduledAssert(context.TODO(), "t1_c")
+	rs.Unregister()
+
+	rs, _ = createRuleSession()
+	clock := ruleapi.NewPseudoClock(start)
+	rs.SetClock(clock)
+	store, err = ruleapi.NewFileJobStore(path)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = rs.SetJobStore(store)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	addWindowRules(rs, "t1")
+	expectJobs(t, rs, "t1_b")
+	rs.Start(nil)
+	clock.Advance(time.Minute)
+	asserted := rs.GetAssertedTuple(t1.GetKey())
+	if asserted == nil {
+		t.Fatalf("Expected t1_b asserted")
+	}
+	if p1, _ := asserted.GetInt("p1"); p1 != 3 {
+		t.Errorf("Expected [%d], got [%d]\n", 3, p1)
+	}
+	expectJobs(t, rs)
+	rs.Unregister()
+}
+
+//A cron job asserts t1_a every 5 minutes, skipping its runs while the t1_a of its last run is asserted.
+//It leaves a t1_a it did not assert alone
+func Test_Scheduler_3(t *testing.T) {
+
+	fired := map[string]int{}
+	rs, _ := createRuleSession()
+	clock := ruleapi.NewPseudoClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	rs.SetClock(clock)
+	r := ruleapi.NewRule("t1")
+	r.AddCondition("c1", []string{"t1"}, trueCondition, nil)
+	r.SetAction(countAction)
+	r.SetContext(fired)
+	rs.AddRule(r)
+	rs.Start(nil)
+
+	err := rs.ScheduleJob(context.TODO(), model.Job{Key: "cron", Operation: model.AssertJob, TupleType: "t1",
+		Values: map[string]interface{}{"id": "t1_a", "p1": 1}, Cron: "@every 5m"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	key, _ := model.NewTupleKeyWithKeyValues("t1", "t1_a")
+	for run := 1; run <= 2; run++ {
+		clock.Advance(5 * time.Minute)
+		if fired["t1"] != 1 || rs.GetAssertedTuple(key) == nil {
+			t.Errorf("Run %d: expected t1_a asserted once, got %v\n", run, fired)
+		}
+	}
+
+	rs.Retract(context.TODO(), rs.GetAssertedTuple(key))
+	clock.Advance(5 * time.Minute)
+	if fired["t1"] != 2 || rs.GetAssertedTuple(key) == nil {
+		t.Errorf("Expected t1_a asserted again, got %v\n", fired)
+	}
+
+	rs.Retract(context.TODO(), rs.GetAssertedTuple(key))
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	t1.SetInt(context.TODO(), "p1", 2)
+	rs.Assert(context.TODO(), t1)
+	clock.Advance(5 * time.Minute)
+	if rs.GetAssertedTuple(key) != t1 {
+		t.Errorf("Expected the t1_a asserted by hand kept")
+	}
+	rs.Unregister()
+}
+
+func expectJobs(t *testing.T, rs model.RuleSession, keys ...string) {
+	t.Helper()
+	jobs := rs.GetScheduledJobs()
+	if len(jobs) != len(keys) {
+		t.Fatalf("Expected jobs %v, got %v\n", keys, jobs)
+	}
+	for i, job := range jobs {
+		if job.Key != keys[i] {
+			t.Errorf("Expected jobs %v, got %v\n", keys, jobs)
+		}
+	}
+}
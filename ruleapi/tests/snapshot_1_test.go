@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//A session restored from a snapshot keeps its tuples, remaining TTLs, pending activations and jobs,
+//and fires again only when asked to
+func Test_Snapshot_1(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	fired := map[string]int{}
+	rs, _ := createRuleSession()
+	clock := ruleapi.NewPseudoClock(start)
+	rs.SetClock(clock)
+	addSnapshotRules(rs, fired)
+	rs.Start(nil)
+
+	t1a, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	rs.Assert(context.TODO(), t1a)
+	t8a, _ := model.NewTupleWithKeyValues("t8", "t8_a")
+	rs.Assert(context.TODO(), t8a)
+	t1b, _ := model.NewTupleWithKeyValues("t1", "t1_b")
+	rs.ScheduleAssert(context.TODO(), 2000, "t1_b", t1b)
+	clock.Advance(400 * time.Millisecond)
+	if fired["join"] != 1 || fired["later"] != 0 {
+		t.Fatalf("Expected join fired once, got %v\n", fired)
+	}
+
+	buf := bytes.Buffer{}
+	if err := rs.Snapshot(&buf); err != nil {
+		t.Fatalf("%s", err)
+	}
+	snap := buf.Bytes()
+	rs.Unregister()
+
+	//restored without actions, the activation of the later group is still pending
+	rs, _ = createRuleSession()
+	clock = ruleapi.NewPseudoClock(start)
+	rs.SetClock(clock)
+	addSnapshotRules(rs, fired)
+	if err := rs.Restore(bytes.NewReader(snap), false); err != nil {
+		t.Fatalf("%s", err)
+	}
+	rs.Start(nil)
+	if fired["join"] != 1 || rs.GetAssertedTuple(t1a.GetKey()) == nil || rs.GetAssertedTuple(t8a.GetKey()) == nil {
+		t.Errorf("Expected t1_a and t8_a restored without firing, got %v\n", fired)
+	}
+	if err := rs.Restore(bytes.NewReader(snap), false); err == nil {
+		t.Errorf("Expected restoring into a session with tuples to fail")
+	}
+	jobs := rs.GetScheduledJobs()
+	if len(jobs) != 1 || jobs[0].Key != "t1_b" {
+		t.Errorf("Expected the job of t1_b restored, got %v\n", jobs)
+	}
+
+	//the pending activation fires as its group gets the focus, t8_a has 600ms left
+	rs.SetFocus(context.TODO(), "later")
+	if fired["later"] != 1 {
+		t.Errorf("Expected the pending activation fired, got %v\n", fired)
+	}
+	clock.Advance(600 * time.Millisecond)
+	if rs.GetAssertedTuple(t8a.GetKey()) != nil {
+		t.Errorf("Expected t8_a expired")
+	}
+	clock.Advance(time.Second)
+	if rs.GetAssertedTuple(t1b.GetKey()) != nil {
+		t.Errorf("Expected t1_b not asserted yet")
+	}
+	clock.Advance(400 * time.Millisecond)
+	if rs.GetAssertedTuple(t1b.GetKey()) == nil {
+		t.Errorf("Expected t1_b asserted by its job")
+	}
+	rs.Unregister()
+
+	//restored with actions, the activations fire again
+	fired["join"], fired["later"] = 0, 0
+	rs, _ = createRuleSession()
+	rs.SetClock(ruleapi.NewPseudoClock(start))
+	addSnapshotRules(rs, fired)
+	rs.Start(nil)
+	if err := rs.Restore(bytes.NewReader(snap), true); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if fired["join"] != 1 || fired["later"] != 0 {
+		t.Errorf("Expected join fired again, got %v\n", fired)
+	}
+	rs.Unregister()
+}
+
+func addSnapshotRules(rs model.RuleSession, fired map[string]int) {
+	join := ruleapi.NewRule("join")
+	join.AddCondition("c1", []string{"t1", "t8"}, trueCondition, nil)
+	join.SetAction(countAction)
+	join.SetContext(fired)
+	rs.AddRule(join)
+	later := ruleapi.NewRule("later")
+	later.AddCondition("c1", []string{"t1"}, trueCondition, nil)
+	later.SetAgendaGroup("later")
+	later.SetAction(countAction)
+	later.SetContext(fired)
+	rs.AddRule(later)
+}
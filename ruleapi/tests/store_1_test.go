@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/project-flogo/rules/common/kvstore"
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/rete"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//store selects the working memory store of the sessions of the suite, "disk" to run it with
+//rete.NewDiskStore: go test ./ruleapi/tests -args -store=disk
+var store = flag.String("store", "memory", "working memory store of the sessions, memory or disk")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	dir := ""
+	switch *store {
+	case "memory":
+	case "disk":
+		var err error
+		if dir, err = ioutil.TempDir("", "store"); err != nil {
+			log.Fatal(err)
+		}
+		n := 0
+		newStore = func() (rete.WorkingMemoryStore, error) {
+			n++
+			return rete.NewDiskStore(filepath.Join(dir, fmt.Sprintf("wm%d", n)))
+		}
+	default:
+		log.Fatalf("Unknown store [%s]", *store)
+	}
+	code := m.Run()
+	if dir != "" {
+		os.RemoveAll(dir)
+	}
+	os.Exit(code)
+}
+
+//The file holds the asserted tuples, the rows of the join and the pending activations, a session opening
+//it again gets them back
+func Test_Store_2(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "store")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "wm")
+	fired := map[string]int{}
+	addRule := func(rs model.RuleSession) {
+		r := ruleapi.NewRule("join")
+		r.AddCondition("c1", []string{"t1", "t3"}, trueCondition, nil)
+		r.SetAction(countAction)
+		r.SetContext(fired)
+		rs.AddRule(r)
+	}
+	openSession := func() model.RuleSession {
+		rs, _ := createRuleSession()
+		addRule(rs)
+		store, err := rete.NewDiskStore(path)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if err = ruleapi.SetWorkingMemoryStore(rs, store); err != nil {
+			t.Fatalf("%s", err)
+		}
+		rs.Start(nil)
+		return rs
+	}
+
+	rs := openSession()
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	rs.Assert(context.TODO(), t1)
+	for _, id := range []string{"t3_a", "t3_b"} {
+		t3, _ := model.NewTupleWithKeyValues("t3", id)
+		rs.Assert(context.TODO(), t3)
+	}
+	rs.Retract(context.TODO(), t1)
+	t1b, _ := model.NewTupleWithKeyValues("t1", "t1_b")
+	t1b.SetInt(context.TODO(), "p1", 2)
+	rs.Assert(context.TODO(), t1b)
+	//on disk as each RTC ends, before the session closes the file
+	expectRecords(t, path, 3, 3)
+	rs.Unregister()
+	expectRecords(t, path, 3, 3)
+
+	//the join does not fire again for the tuples of the file, but for those asserted since
+	fired["join"] = 0
+	rs = openSession()
+	for id, asserted := range map[string]bool{"t1_a": false, "t1_b": true, "t3_a": true, "t3_b": true} {
+		key, _ := model.NewTupleKeyWithKeyValues(model.TupleType(id[:2]), id)
+		if (rs.GetAssertedTuple(key) != nil) != asserted {
+			t.Errorf("%s: expected asserted [%t]\n", id, asserted)
+		}
+	}
+	if p1, _ := rs.GetAssertedTuple(t1b.GetKey()).GetInt("p1"); p1 != 2 {
+		t.Errorf("Expected [%d], got [%d]\n", 2, p1)
+	}
+	t1c, _ := model.NewTupleWithKeyValues("t1", "t1_c")
+	rs.Assert(context.TODO(), t1c)
+	if fired["join"] != 2 {
+		t.Errorf("Expected join fired for t1_c only, got %v\n", fired)
+	}
+	rs.Unregister()
+	expectRecords(t, path, 4, 4)
+}
+
+//expectRecords checks the file holds the tuples and rows, and no pending activation
+func expectRecords(t *testing.T, path string, tuples int, rows int) {
+	t.Helper()
+	kv, err := kvstore.Open(path)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer kv.Close()
+	if keys := kv.Keys("h/"); len(keys) != tuples {
+		t.Errorf("Expected %d tuples, got %v\n", tuples, keys)
+	}
+	rowKeys := kv.Keys("r/")
+	if len(rowKeys) != rows {
+		t.Errorf("Expected %d rows, got %v\n", rows, rowKeys)
+	}
+	for _, row := range rowKeys {
+		if strings.Contains(row, "t1_a") {
+			t.Errorf("Expected the rows of t1_a removed, got [%s]\n", row)
+		}
+	}
+	if keys := kv.Keys("a/"); len(keys) != 0 {
+		t.Errorf("Expected no pending activations, got %v\n", keys)
+	}
+}
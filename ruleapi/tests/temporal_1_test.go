@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//A t4 after another ends, within 20 minutes, from a temporal condition and from an expression
+func Test_Temporal_1(t *testing.T) {
+
+	fired := map[string][]string{}
+	rs, _ := createRuleSession()
+
+	r1 := ruleapi.NewRule("condition")
+	err := r1.AddTemporalCondition("c1", "o1:t4", model.AfterOperator, "o2:t4", nil, 20*time.Minute)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r1.SetAction(aliasAction)
+	r1.SetContext(fired)
+	rs.AddRule(r1)
+
+	r2 := ruleapi.NewRule("expression")
+	r2.AddIdrsToRule([]model.TupleType{"o1:t4", "o2:t4"})
+	err = r2.AddExprCondition("c1", `temporal.after($event[o1], $event[o2], "20m")`, nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r2.SetAction(aliasAction)
+	r2.SetContext(fired)
+	rs.AddRule(r2)
+
+	//o1 lasts while o2 does
+	r3 := ruleapi.NewRule("during")
+	r3.AddIdrsToRule([]model.TupleType{"o1:t4", "o2:t4"})
+	err = r3.AddExprCondition("c1", `temporal.during($event[o1], $event[o2])`, nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r3.SetAction(aliasAction)
+	r3.SetContext(fired)
+	rs.AddRule(r3)
+	rs.Start(nil)
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, e := range []struct {
+		id       string
+		at       time.Duration
+		lastsFor time.Duration
+	}{
+		{"t4_a", 0, time.Hour},
+		{"t4_b", 5 * time.Minute, 0},
+		{"t4_c", 20 * time.Minute, 0},
+	} {
+		t4, _ := model.NewTupleWithKeyValues("t4", e.id)
+		t4.SetLong(context.TODO(), "ts", start.Add(e.at).UnixNano()/int64(time.Millisecond))
+		t4.SetLong(context.TODO(), "dur", int64(e.lastsFor/time.Millisecond))
+		rs.Assert(context.TODO(), t4)
+	}
+
+	//t4_b starts 5 minutes after t4_a starts but before it ends, t4_c 15 minutes after t4_b
+	expectPairs(t, fired["condition"], "t4_c-t4_b")
+	expectPairs(t, fired["expression"], "t4_c-t4_b")
+	expectPairs(t, fired["during"], "t4_b-t4_a", "t4_c-t4_a")
+
+	rs.Unregister()
+}
+
+//Without a timestamp property the event time is when the tuple is asserted
+func Test_Temporal_2(t *testing.T) {
+
+	fired := map[string]int{}
+	rs, _ := createRuleSession()
+
+	r1 := ruleapi.NewRule("within")
+	err := r1.AddExprCondition("c1", `temporal.within($event[t1], $event[t3], "1m")`, nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r1.SetAction(countAction)
+	r1.SetContext(fired)
+	rs.AddRule(r1)
+	rs.Start(nil)
+
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	rs.Assert(context.TODO(), t1)
+	t3, _ := model.NewTupleWithKeyValues("t3", "t3_a")
+	rs.Assert(context.TODO(), t3)
+	if fired["within"] != 1 {
+		t.Errorf("Expected [%d], got [%d]\n", 1, fired["within"])
+	}
+
+	//the timestamp property of a t4 asserted without one is set
+	t4, _ := model.NewTupleWithKeyValues("t4", "t4_a")
+	rs.Assert(context.TODO(), t4)
+	ts, _ := t4.GetLong("ts")
+	if time.Since(time.Unix(0, ts*int64(time.Millisecond))) > time.Minute {
+		t.Errorf("Expected the assertion time, got [%d]\n", ts)
+	}
+
+	rs.Unregister()
+
+	if r1.AddTemporalCondition("c2", "t1", model.WithinOperator, "t3", nil) == nil {
+		t.Errorf("Expected an error for a missing bound")
+	}
+	if r1.AddTemporalCondition("c3", "t1", model.BeforeOperator, "t1", nil) == nil {
+		t.Errorf("Expected an error for a single identifier")
+	}
+}
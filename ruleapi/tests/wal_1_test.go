@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//A session recovers from its log without firing again, and runs the operation whose RTC was not logged
+func Test_WAL_1(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "wal")
+	defer os.RemoveAll(dir)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	fired := map[string]int{}
+	rs := createWALSession(t, dir, start, fired, ruleapi.WALOptions{Sync: ruleapi.SyncAlways})
+	for _, id := range []string{"t1_a", "t1_b"} {
+		t1, _ := model.NewTupleWithKeyValues("t1", id)
+		rs.Assert(context.TODO(), t1)
+	}
+	t8a, _ := model.NewTupleWithKeyValues("t8", "t8_a")
+	rs.Assert(context.TODO(), t8a)
+	t1b, _ := model.NewTupleWithKeyValues("t1", "t1_b")
+	rs.Retract(context.TODO(), rs.GetAssertedTuple(t1b.GetKey()))
+	if fired["join"] != 2 {
+		t.Fatalf("Expected join fired twice, got %v\n", fired)
+	}
+	rs.Unregister()
+
+	rs = createWALSession(t, dir, start, fired, ruleapi.WALOptions{Sync: ruleapi.SyncNever})
+	expectRecovered(t, rs, map[string]bool{"t1_a": true, "t1_b": false, "t8_a": true})
+	if fired["join"] != 2 {
+		t.Errorf("Expected join not fired on recovery, got %v\n", fired)
+	}
+	rs.Unregister()
+
+	//a crash after logging the assert of t1_c, and in the middle of the next record
+	segments, _ := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	f, _ := os.OpenFile(segments[len(segments)-1], os.O_WRONLY|os.O_APPEND, 0644)
+	f.WriteString(`{"op":"assert","seq":100,"time":"2020-01-01T00:00:00Z","tuple":{"type":"t1","values":{"id":"t1_c"}}}` + "\n")
+	f.WriteString(`{"op":"com`)
+	f.Close()
+	rs = createWALSession(t, dir, start, fired, ruleapi.WALOptions{Sync: ruleapi.SyncInterval, SyncInterval: time.Millisecond})
+	expectRecovered(t, rs, map[string]bool{"t1_a": true, "t1_c": true, "t8_a": true})
+	if fired["join"] != 3 {
+		t.Errorf("Expected join fired for t1_c, got %v\n", fired)
+	}
+
+	if err := ruleapi.CompactWAL(rs); err != nil {
+		t.Fatalf("%s", err)
+	}
+	snapshots, _ := filepath.Glob(filepath.Join(dir, "snapshot-*.json"))
+	segments, _ = filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	if len(snapshots) != 1 || len(segments) != 1 {
+		t.Errorf("Expected a snapshot and a segment, got %v %v\n", snapshots, segments)
+	}
+	rs.Unregister()
+
+	//the TTL of t8_a still runs from its assert
+	rs = createWALSession(t, dir, start, fired, ruleapi.WALOptions{})
+	expectRecovered(t, rs, map[string]bool{"t1_a": true, "t1_c": true, "t8_a": true})
+	rs.GetClock().(ruleapi.PseudoClock).Advance(time.Second)
+	expectRecovered(t, rs, map[string]bool{"t8_a": false})
+	rs.Unregister()
+	rs = createWALSession(t, dir, start, fired, ruleapi.WALOptions{})
+	expectRecovered(t, rs, map[string]bool{"t1_a": true, "t1_c": true, "t8_a": false})
+	if fired["join"] != 3 {
+		t.Errorf("Expected join not fired again, got %v\n", fired)
+	}
+	rs.Unregister()
+}
+
+//The log compacts itself once it holds enough records
+func Test_WAL_2(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "wal")
+	defer os.RemoveAll(dir)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	fired := map[string]int{}
+	rs := createWALSession(t, dir, start, fired, ruleapi.WALOptions{CompactAfter: 4})
+	expected := map[string]bool{}
+	for _, id := range []string{"t1_a", "t1_b", "t1_c", "t1_d", "t1_e"} {
+		t1, _ := model.NewTupleWithKeyValues("t1", id)
+		rs.Assert(context.TODO(), t1)
+		expected[id] = true
+	}
+	rs.Unregister()
+
+	snapshots, _ := filepath.Glob(filepath.Join(dir, "snapshot-*.json"))
+	segments, _ := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	if len(snapshots) != 1 || len(segments) != 1 {
+		t.Errorf("Expected a snapshot and a segment, got %v %v\n", snapshots, segments)
+	}
+	rs = createWALSession(t, dir, start, fired, ruleapi.WALOptions{})
+	expectRecovered(t, rs, expected)
+	rs.Unregister()
+}
+
+//An operation that cannot be logged fails, and does not run
+func Test_WAL_3(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "wal")
+	defer os.RemoveAll(dir)
+	rs := createWALSession(t, dir, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), map[string]int{}, ruleapi.WALOptions{})
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	rs.Assert(context.TODO(), t1)
+	//the log is closed with the session
+	rs.Unregister()
+	if err := rs.Retract(context.TODO(), t1); err == nil {
+		t.Errorf("Expected an error retracting\n")
+	}
+	if err := rs.Delete(context.TODO(), t1); err == nil {
+		t.Errorf("Expected an error deleting\n")
+	}
+	expectRecovered(t, rs, map[string]bool{"t1_a": true})
+}
+
+//Compacting keeps the operations recovered until they run again
+func Test_WAL_4(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "wal")
+	defer os.RemoveAll(dir)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	fired := map[string]int{}
+	rs := createWALSession(t, dir, start, fired, ruleapi.WALOptions{})
+	t1, _ := model.NewTupleWithKeyValues("t1", "t1_a")
+	rs.Assert(context.TODO(), t1)
+	rs.Unregister()
+	//a crash after logging the assert of t1_b
+	segments, _ := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	f, _ := os.OpenFile(segments[len(segments)-1], os.O_WRONLY|os.O_APPEND, 0644)
+	f.WriteString(`{"op":"assert","seq":100,"time":"2020-01-01T00:00:00Z","tuple":{"type":"t1","values":{"id":"t1_b"}}}` + "\n")
+	f.Close()
+
+	//compacted before the session starts and runs it
+	rs, _ = createRuleSession()
+	rs.SetClock(ruleapi.NewPseudoClock(start))
+	addSnapshotRules(rs, fired)
+	if err := ruleapi.EnableWAL(rs, dir, ruleapi.WALOptions{}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := ruleapi.CompactWAL(rs); err != nil {
+		t.Fatalf("%s", err)
+	}
+	rs.Unregister()
+
+	rs = createWALSession(t, dir, start, fired, ruleapi.WALOptions{})
+	expectRecovered(t, rs, map[string]bool{"t1_a": true, "t1_b": true})
+	rs.Unregister()
+}
+
+func createWALSession(t *testing.T, dir string, start time.Time, fired map[string]int, options ruleapi.WALOptions) model.RuleSession {
+	t.Helper()
+	rs, _ := createRuleSession()
+	rs.SetClock(ruleapi.NewPseudoClock(start))
+	addSnapshotRules(rs, fired)
+	if err := ruleapi.EnableWAL(rs, dir, options); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := rs.Start(nil); err != nil {
+		t.Fatalf("%s", err)
+	}
+	return rs
+}
+
+func expectRecovered(t *testing.T, rs model.RuleSession, expected map[string]bool) {
+	t.Helper()
+	for id, asserted := range expected {
+		key, _ := model.NewTupleKeyWithKeyValues(model.TupleType(id[:2]), id)
+		if (rs.GetAssertedTuple(key) != nil) != asserted {
+			t.Errorf("%s: expected asserted [%t]\n", id, asserted)
+		}
+	}
+}
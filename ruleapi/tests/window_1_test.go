@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/ruleapi"
+)
+
+//t5 keeps the last 2 of each p3, t6 lets go of its 2 tuples once full
+func Test_Window_1(t *testing.T) {
+
+	deleted := map[string]bool{}
+	rs, _ := createRuleSession()
+	rs.RegisterRtcTransactionHandler(tupleDeletedHandler, deleted)
+	addWindowRules(rs, "t5", "t6")
+	rs.Start(nil)
+
+	for _, id := range []string{"t5_a", "t5_b", "t5_c", "t5_d"} {
+		t5, _ := model.NewTupleWithKeyValues("t5", id)
+		if id == "t5_d" {
+			t5.SetString(context.TODO(), "p3", "other")
+		}
+		rs.Assert(context.TODO(), t5)
+	}
+	expectInWindow(t, rs, deleted, "t5", map[string]bool{"t5_a": false, "t5_b": true, "t5_c": true, "t5_d": true})
+
+	for _, id := range []string{"t6_a", "t6_b", "t6_c"} {
+		t6, _ := model.NewTupleWithKeyValues("t6", id)
+		rs.Assert(context.TODO(), t6)
+	}
+	expectInWindow(t, rs, deleted, "t6", map[string]bool{"t6_a": false, "t6_b": false, "t6_c": true})
+
+	rs.Unregister()
+}
+
+//t7 lets go of its tuples after 100ms, in an RTC of their own
+func Test_Window_2(t *testing.T) {
+
+	evicted := make(chan string, 10)
+	rs, _ := createRuleSession()
+	rs.RegisterRtcTransactionHandler(evictedHandler, evicted)
+	addWindowRules(rs, "t7")
+	rs.Start(nil)
+
+	t7, _ := model.NewTupleWithKeyValues("t7", "t7_a")
+	rs.Assert(context.TODO(), t7)
+	if rs.GetAssertedTuple(t7.GetKey()) == nil {
+		t.Errorf("Expected t7_a in the window")
+	}
+
+	select {
+	case id := <-evicted:
+		if id != "t7_a" {
+			t.Errorf("Expected t7_a evicted, got [%s]\n", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("Expected t7_a evicted")
+	}
+	if rs.GetAssertedTuple(t7.GetKey()) != nil {
+		t.Errorf("Expected t7_a out of the window")
+	}
+
+	rs.Unregister()
+}
+
+func addWindowRules(rs model.RuleSession, tupleTypes ...string) {
+	for _, tupleType := range tupleTypes {
+		r := ruleapi.NewRule(tupleType + "Rule")
+		r.AddCondition("c1", []string{tupleType}, trueCondition, nil)
+		r.SetAction(emptyAction)
+		rs.AddRule(r)
+	}
+}
+
+func expectInWindow(t *testing.T, rs model.RuleSession, deleted map[string]bool, tupleType string, expected map[string]bool) {
+	t.Helper()
+	for id, inWindow := range expected {
+		key, _ := model.NewTupleKeyWithKeyValues(model.TupleType(tupleType), id)
+		if (rs.GetAssertedTuple(key) != nil) != inWindow || deleted[id] == inWindow {
+			t.Errorf("%s: expected in the window [%t]\n", id, inWindow)
+		}
+	}
+}
+
+func evictedHandler(ctx context.Context, rs model.RuleSession, rtxn model.RtcTxn, handlerCtx interface{}) {
+	for _, tuples := range rtxn.GetRtcDeleted() {
+		for _, tuple := range tuples {
+			id, _ := tuple.GetString("id")
+			handlerCtx.(chan string) <- id
+		}
+	}
+}
+
+//tupleDeletedHandler records the ids of the tuples deleted, of any type
+func tupleDeletedHandler(ctx context.Context, rs model.RuleSession, rtxn model.RtcTxn, handlerCtx interface{}) {
+	deleted := handlerCtx.(map[string]bool)
+	for _, tuples := range rtxn.GetRtcDeleted() {
+		for _, tuple := range tuples {
+			id, _ := tuple.GetString("id")
+			deleted[id] = true
+		}
+	}
+}
@@ -0,0 +1,586 @@
+package ruleapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/project-flogo/rules/common/model"
+	"github.com/project-flogo/rules/rete"
+)
+
+//SyncPolicy is when the write-ahead log flushes its records to disk
+type SyncPolicy int
+
+const (
+	//SyncAlways flushes each record before the operation goes on
+	SyncAlways SyncPolicy = iota
+	//SyncInterval flushes the records every WALOptions.SyncInterval, a crash of the machine loses
+	//those written since
+	SyncInterval
+	//SyncNever leaves flushing to the operating system
+	SyncNever
+)
+
+//WALOptions configures the write-ahead log of a session, see EnableWAL
+type WALOptions struct {
+	Sync SyncPolicy
+	//a second by default
+	SyncInterval time.Duration
+	//compacts the log into a snapshot once it holds that many records, never if 0
+	CompactAfter int
+}
+
+//EnableWAL recovers the session from the write-ahead log in the directory, then appends to it the
+//external asserts, retracts and deletes, and the changes of each RTC. Recovery restores the latest
+//snapshot of the log and the changes logged since without firing actions, then runs again the
+//operations whose RTC was not logged once the session starts. Call it after adding the rules and
+//before asserting any tuple. Scheduled jobs are not logged, see SetJobStore
+func EnableWAL(rs model.RuleSession, dir string, options WALOptions) error {
+	rsImpl, ok := rs.(*rulesessionImpl)
+	if !ok {
+		return fmt.Errorf("Unknown rule session [%s]", rs.GetName())
+	}
+	if rsImpl.wal != nil {
+		return fmt.Errorf("Rulesession [%s] already has a write-ahead log", rs.GetName())
+	}
+	w := walImpl{}
+	err := w.initWALImpl(rsImpl, dir, options)
+	if err != nil {
+		return err
+	}
+	rsImpl.wal = &w
+	rsImpl.registerTxnHandler()
+	if rsImpl.started {
+		return w.replayPending()
+	}
+	return nil
+}
+
+//CompactWAL snapshots the session into its write-ahead log, and drops the records the snapshot covers
+func CompactWAL(rs model.RuleSession) error {
+	rsImpl, ok := rs.(*rulesessionImpl)
+	if !ok || rsImpl.wal == nil {
+		return fmt.Errorf("Rulesession [%s] has no write-ahead log", rs.GetName())
+	}
+	return rsImpl.wal.compact()
+}
+
+//writeAheadLog logs the operations of a session and the changes of its RTCs
+type writeAheadLog interface {
+	//runOp logs an external operation, then runs it with the context of its RTC. The operations run one at
+	//a time in the order logged, one that cannot be logged does not run
+	runOp(ctx context.Context, op string, tuple model.Tuple, run func(ctx context.Context)) error
+	//logCommit logs the changes of an RTC, once it is over
+	logCommit(ctx context.Context, txn model.RtcTxn)
+	//replayPending runs the operations recovered without their RTC
+	replayPending() error
+	//compact starts a new segment, and replaces the older ones by a snapshot
+	compact() error
+	close()
+}
+
+const (
+	walAssert  = "assert"
+	walRetract = "retract"
+	walDelete  = "delete"
+	walCommit  = "commit"
+
+	segmentPrefix  = "wal-"
+	segmentSuffix  = ".log"
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".json"
+)
+
+//walRecord is a line of a segment of the log
+type walRecord struct {
+	Op string `json:"op"`
+	//the number of the external operation, or of the one whose RTC is committed
+	Seq  uint64    `json:"seq,omitempty"`
+	Time time.Time `json:"time"`
+	//the tuple of an external operation
+	Tuple *snapshotTuple `json:"tuple,omitempty"`
+	//the TTL of the tuple asserted in milliseconds, -1 if it does not expire
+	TTL *int64 `json:"ttl,omitempty"`
+	//the tuples asserted once the RTC is over, and the keys of those no longer asserted
+	Put    []snapshotTuple `json:"put,omitempty"`
+	Remove []string        `json:"remove,omitempty"`
+	//why the activations of the RTC fired, for the audit, recovery ignores them
+	Explanations []model.Explanation `json:"explanations,omitempty"`
+}
+
+//walSeqKey holds the number of the external operation in the context of its RTC
+type walSeqKey struct{}
+
+//recoveredTuple is a tuple of the snapshot or the log, as of the latest record
+type recoveredTuple struct {
+	st       snapshotTuple
+	deadline time.Time
+	order    int
+}
+
+//walImpl logs to segment files numbered in order, a snapshot file covers the segments before its number
+type walImpl struct {
+	rs      *rulesessionImpl
+	dir     string
+	options WALOptions
+	//held from logging an external operation to the end of its RTC, and while compacting
+	opLock  sync.Mutex
+	lock    sync.Mutex
+	file    *os.File
+	segment int
+	//the records of the current segment
+	records int
+	seq     uint64
+	//records written since the last flush, see SyncInterval
+	dirty bool
+	//the operations recovered without their RTC
+	pending []walRecord
+	closed  bool
+	done    chan bool
+}
+
+func (w *walImpl) initWALImpl(rs *rulesessionImpl, dir string, options WALOptions) error {
+	w.rs = rs
+	w.dir = dir
+	w.options = options
+	if w.options.SyncInterval <= 0 {
+		w.options.SyncInterval = time.Second
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := w.recover(); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(w.segmentPath(w.segment), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	if w.options.Sync == SyncInterval {
+		w.done = make(chan bool)
+		go w.syncEvery()
+	}
+	return nil
+}
+
+//recover restores the latest snapshot and the changes logged since, and starts the next segment
+func (w *walImpl) recover() error {
+	snapshots, err := w.fileNumbers(snapshotPrefix, snapshotSuffix)
+	if err != nil {
+		return err
+	}
+	segments, err := w.fileNumbers(segmentPrefix, segmentSuffix)
+	if err != nil {
+		return err
+	}
+	tuples := map[string]*recoveredTuple{}
+	order := 0
+	put := func(st snapshotTuple, at time.Time) error {
+		key, err := model.NewTupleKey(st.TupleType, st.Values)
+		if err != nil {
+			return err
+		}
+		rt := &recoveredTuple{st: st, order: order}
+		if st.ExpiresIn != nil {
+			rt.deadline = at.Add(time.Duration(*st.ExpiresIn) * time.Millisecond)
+		}
+		tuples[key.String()] = rt
+		order++
+		return nil
+	}
+
+	state := rete.NetworkState{Expiries: map[string]time.Time{}}
+	if len(snapshots) > 0 {
+		w.segment = snapshots[len(snapshots)-1]
+		file, err := os.Open(w.snapshotPath(w.segment))
+		if err != nil {
+			return err
+		}
+		snap, err := readSnapshot(file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+		state.Focus = snap.Focus
+		state.Agenda = snap.Agenda
+		state.Justifications = snap.Justifications
+		for _, st := range snap.Tuples {
+			if err = put(st, snap.Time); err != nil {
+				return err
+			}
+		}
+	}
+
+	ops := []walRecord{}
+	committed := map[uint64]bool{}
+	for i, segment := range segments {
+		if segment < w.segment {
+			continue
+		}
+		records, err := w.readSegment(segment, i == len(segments)-1)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if record.Seq > w.seq {
+				w.seq = record.Seq
+			}
+			if record.Op != walCommit {
+				ops = append(ops, record)
+				continue
+			}
+			committed[record.Seq] = true
+			for _, st := range record.Put {
+				if err = put(st, record.Time); err != nil {
+					return err
+				}
+			}
+			for _, key := range record.Remove {
+				delete(tuples, key)
+			}
+		}
+		w.segment = segment
+	}
+	for _, op := range ops {
+		if !committed[op.Seq] {
+			w.pending = append(w.pending, op)
+		}
+	}
+	w.segment++
+
+	if len(tuples) == 0 {
+		return nil
+	}
+	recovered := make([]*recoveredTuple, 0, len(tuples))
+	for _, rt := range tuples {
+		recovered = append(recovered, rt)
+	}
+	sort.Slice(recovered, func(i, j int) bool {
+		return recovered[i].order < recovered[j].order
+	})
+	for _, rt := range recovered {
+		tuple, err := rt.st.newTuple()
+		if err != nil {
+			return err
+		}
+		if !rt.deadline.IsZero() {
+			state.Expiries[tuple.GetKey().String()] = rt.deadline
+		}
+		state.Tuples = append(state.Tuples, tuple)
+	}
+	return w.rs.reteNetwork.Restore(context.Background(), w.rs, state, false)
+}
+
+//readSegment reads the records of a segment. A crash may leave the last record of the last segment
+//partly written, it is dropped
+func (w *walImpl) readSegment(segment int, last bool) ([]walRecord, error) {
+	path := w.segmentPath(segment)
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	records := []walRecord{}
+	reader := bufio.NewReader(file)
+	offset := int64(0)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return records, nil
+		}
+		record := walRecord{}
+		if err == nil {
+			err = json.Unmarshal(line, &record)
+		}
+		if err != nil {
+			if !last {
+				return nil, fmt.Errorf("Corrupt write-ahead log [%s] at offset [%d]", path, offset)
+			}
+			return records, file.Truncate(offset)
+		}
+		records = append(records, record)
+		offset += int64(len(line))
+	}
+}
+
+func (w *walImpl) runOp(ctx context.Context, op string, tuple model.Tuple, run func(ctx context.Context)) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.lock.Lock()
+	w.seq++
+	now := w.rs.GetClock().Now()
+	st := newSnapshotTuple(tuple, now, time.Time{})
+	record := walRecord{Op: op, Seq: w.seq, Time: now, Tuple: &st}
+	if op == walAssert {
+		ttl := int64(-1)
+		if d := model.GetTTL(tuple); d >= 0 {
+			ttl = int64(d / time.Millisecond)
+		}
+		record.TTL = &ttl
+	}
+	err := w.write(record)
+	due := w.options.CompactAfter > 0 && w.records >= w.options.CompactAfter
+	w.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	run(context.WithValue(ctx, walSeqKey{}, record.Seq))
+	//once its RTC is logged, the snapshot covers the operation
+	if due {
+		if err := w.compactSegments(); err != nil {
+			fmt.Printf("Cannot compact the write-ahead log [%s]: %s\n", w.dir, err)
+		}
+	}
+	return nil
+}
+
+func (w *walImpl) logCommit(ctx context.Context, txn model.RtcTxn) {
+	var seq uint64
+	if ctx != nil {
+		seq, _ = ctx.Value(walSeqKey{}).(uint64)
+	}
+	keys := []string{}
+	for _, changes := range []map[string]map[string]model.Tuple{txn.GetRtcAdded(), txn.GetRtcDeleted(), txn.GetRtcRetracted()} {
+		for _, tuples := range changes {
+			for key := range tuples {
+				keys = append(keys, key)
+			}
+		}
+	}
+	for _, tuples := range txn.GetRtcModified() {
+		for key := range tuples {
+			keys = append(keys, key)
+		}
+	}
+	if seq == 0 && len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+
+	now := w.rs.GetClock().Now()
+	record := walRecord{Op: walCommit, Seq: seq, Time: now, Explanations: txn.GetExplanations()}
+	for i, key := range keys {
+		if i > 0 && keys[i-1] == key {
+			continue
+		}
+		//the changes may undo each other, the tuple as it is now is what counts
+		if tuple := w.rs.reteNetwork.GetAssertedTupleByStringKey(key); tuple != nil {
+			var deadline time.Time
+			if ttl := model.GetTTL(tuple); ttl > 0 {
+				deadline = model.GetAssertionTime(tuple).Add(ttl)
+			}
+			record.Put = append(record.Put, newSnapshotTuple(tuple, now, deadline))
+		} else {
+			record.Remove = append(record.Remove, key)
+		}
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err := w.write(record); err != nil {
+		fmt.Printf("Cannot log RTC to [%s]: %s\n", w.dir, err)
+	}
+}
+
+func (w *walImpl) write(record walRecord) error {
+	if w.closed {
+		return fmt.Errorf("Write-ahead log [%s] closed", w.dir)
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err = w.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	w.records++
+	switch w.options.Sync {
+	case SyncAlways:
+		return w.file.Sync()
+	case SyncInterval:
+		w.dirty = true
+	}
+	return nil
+}
+
+func (w *walImpl) syncEvery() {
+	ticker := time.NewTicker(w.options.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.lock.Lock()
+			if w.dirty && !w.closed {
+				w.file.Sync()
+				w.dirty = false
+			}
+			w.lock.Unlock()
+		}
+	}
+}
+
+func (w *walImpl) replayPending() error {
+	w.lock.Lock()
+	pending := w.pending
+	w.pending = nil
+	w.lock.Unlock()
+	ctx := context.Background()
+	for _, record := range pending {
+		tuple, err := record.Tuple.newTuple()
+		if err != nil {
+			return err
+		}
+		switch record.Op {
+		case walAssert:
+			if record.TTL != nil && *record.TTL >= 0 {
+				model.SetTTL(tuple, time.Duration(*record.TTL)*time.Millisecond)
+			}
+			if err = w.rs.Assert(ctx, tuple); err != nil {
+				return err
+			}
+		case walRetract, walDelete:
+			asserted := w.rs.GetAssertedTuple(tuple.GetKey())
+			if asserted == nil {
+				continue
+			}
+			if record.Op == walRetract {
+				err = w.rs.Retract(ctx, asserted)
+			} else {
+				err = w.rs.Delete(ctx, asserted)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *walImpl) compact() error {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	return w.compactSegments()
+}
+
+//compactSegments runs between external operations, so that the older segments hold none without its
+//RTC, except those recovered and not run again yet, it then keeps them. It switches to a new segment
+//first, so that the snapshot covers at least the older ones. The records of the new segment taken into
+//the snapshot too are applied again on recovery, to the same end
+func (w *walImpl) compactSegments() error {
+	w.lock.Lock()
+	if w.closed || len(w.pending) > 0 {
+		w.lock.Unlock()
+		return nil
+	}
+	file, err := os.OpenFile(w.segmentPath(w.segment+1), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		w.lock.Unlock()
+		return err
+	}
+	w.file.Sync()
+	w.file.Close()
+	w.file = file
+	w.segment++
+	w.records = 0
+	segment := w.segment
+	w.lock.Unlock()
+
+	buf := bytes.Buffer{}
+	if err = w.rs.Snapshot(&buf); err != nil {
+		return err
+	}
+	tmp := w.snapshotPath(segment) + ".tmp"
+	if err = writeFileSync(tmp, buf.Bytes()); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, w.snapshotPath(segment)); err != nil {
+		return err
+	}
+	snapshots, _ := w.fileNumbers(snapshotPrefix, snapshotSuffix)
+	for _, n := range snapshots {
+		if n < segment {
+			os.Remove(w.snapshotPath(n))
+		}
+	}
+	segments, _ := w.fileNumbers(segmentPrefix, segmentSuffix)
+	for _, n := range segments {
+		if n < segment {
+			os.Remove(w.segmentPath(n))
+		}
+	}
+	return nil
+}
+
+func (w *walImpl) close() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return
+	}
+	w.closed = true
+	if w.done != nil {
+		close(w.done)
+	}
+	w.file.Sync()
+	w.file.Close()
+}
+
+func (w *walImpl) segmentPath(segment int) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%s%08d%s", segmentPrefix, segment, segmentSuffix))
+}
+
+func (w *walImpl) snapshotPath(segment int) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%s%08d%s", snapshotPrefix, segment, snapshotSuffix))
+}
+
+//fileNumbers returns the numbers of the files of the directory named prefix, number, suffix, in order
+func (w *walImpl) fileNumbers(prefix string, suffix string) ([]int, error) {
+	files, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return nil, err
+	}
+	numbers := []int{}
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix)); err == nil {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+	return numbers, nil
+}
+
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}